@@ -0,0 +1,391 @@
+// Command go-server-gen scans Go packages for @BaseUrl/@Api annotations and
+// writes an ApiRoutes() []core.RouteConfig method for every annotated handler,
+// so RegisterHandlersWithTags does not need the sources at runtime. The
+// schemas of the types the annotations reference are written too, for the
+// OpenAPI document; a handler core.NewHandler would reject fails generation.
+//
+// Usage, next to the handlers:
+//
+//	//go:generate go run github.com/kimxuanhong/go-server/cmd/go-server-gen
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/kimxuanhong/go-server/core"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	corePath    = "github.com/kimxuanhong/go-server/core"
+	contextPath = "context"
+)
+
+func main() {
+	output := flag.String("output", "zz_generated_routes.go", "name of the generated file in each package")
+	types := flag.String("types", "", "comma-separated handler types to generate (default all)")
+	flag.Parse()
+
+	dirs := flag.Args()
+	if len(dirs) == 0 {
+		dirs = []string{"."}
+	}
+
+	only := make(map[string]bool)
+	for _, t := range strings.Split(*types, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			only[t] = true
+		}
+	}
+
+	for _, dir := range dirs {
+		if err := generate(dir, *output, only); err != nil {
+			log.Fatalf("go-server-gen: %s: %v", dir, err)
+		}
+	}
+}
+
+// handler là một kiểu có method mang annotation @Api
+type handler struct {
+	name    string
+	pointer bool
+	routes  []core.ParseRoute
+}
+
+func generate(dir, output string, only map[string]bool) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return err
+	}
+
+	pkgName := ""
+	fset := token.NewFileSet()
+	parsed := make(map[string]*ast.File)
+	types := make(map[string]*ast.TypeSpec)
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") || filepath.Base(file) == output {
+			continue
+		}
+		f, err := parser.ParseFile(fset, file, nil, 0)
+		if err != nil {
+			return err
+		}
+		if pkgName == "" {
+			pkgName = f.Name.Name
+		}
+		parsed[file] = f
+		for _, decl := range f.Decls {
+			if genDecl, ok := decl.(*ast.GenDecl); ok && genDecl.Tok == token.TYPE {
+				for _, spec := range genDecl.Specs {
+					typeSpec := spec.(*ast.TypeSpec)
+					types[typeSpec.Name.Name] = typeSpec
+				}
+			}
+		}
+	}
+
+	handlers := make(map[string]*handler)
+	var errs []error
+	for file, f := range parsed {
+		routes, err := core.ParseApiTags(file)
+		if err != nil {
+			return err
+		}
+		for _, route := range routes {
+			name := strings.TrimPrefix(route.Receiver, "*")
+			if name == "" || (len(only) > 0 && !only[name]) {
+				continue
+			}
+			// Chữ ký sai phải làm hỏng lần sinh code, không phải panic ở LoadRouter
+			if fn := findMethod(f, name, route.Name); fn != nil {
+				if err := checkSignature(fn, imports(f), types, route.Method == core.MethodWS); err != nil {
+					errs = append(errs, fmt.Errorf("%s: %s.%s: %w", fset.Position(fn.Pos()), name, route.Name, err))
+					continue
+				}
+			}
+			if route.Method != core.MethodWS {
+				route.Schemas = core.RouteSchemas(dir, route)
+			}
+			h, ok := handlers[name]
+			if !ok {
+				h = &handler{name: name}
+				handlers[name] = h
+			}
+			h.pointer = h.pointer || strings.HasPrefix(route.Receiver, "*")
+			h.routes = append(h.routes, route)
+		}
+	}
+
+	if len(errs) > 0 {
+		sort.Slice(errs, func(i, j int) bool { return errs[i].Error() < errs[j].Error() })
+		return errors.Join(errs...)
+	}
+
+	target := filepath.Join(dir, output)
+	if len(handlers) == 0 {
+		// Không còn handler nào thì xoá file sinh ra từ lần trước
+		if err := os.Remove(target); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	src, err := render(pkgName, handlers)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(target, src, 0o644)
+}
+
+func render(pkgName string, handlers map[string]*handler) ([]byte, error) {
+	names := make([]string, 0, len(handlers))
+	for name := range handlers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by go-server-gen. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&buf, "package %s\n\n", pkgName)
+	fmt.Fprintf(&buf, "import \"github.com/kimxuanhong/go-server/core\"\n")
+
+	for _, name := range names {
+		h := handlers[name]
+		sort.Slice(h.routes, func(i, j int) bool { return h.routes[i].Name < h.routes[j].Name })
+
+		receiver := name
+		if h.pointer {
+			receiver = "*" + name
+		}
+		fmt.Fprintf(&buf, "\n// ApiRoutes returns the routes declared by the @Api annotations of %s.\n", name)
+		fmt.Fprintf(&buf, "func (h %s) ApiRoutes() []core.RouteConfig {\n", receiver)
+		fmt.Fprintf(&buf, "\treturn []core.RouteConfig{\n")
+		for _, route := range h.routes {
+			fmt.Fprintf(&buf, "\t\t{\n")
+			fmt.Fprintf(&buf, "\t\t\tMethod: %q,\n", route.Method)
+			fmt.Fprintf(&buf, "\t\t\tPath: %q,\n", route.Path)
+			if route.Method == core.MethodWS {
+				fmt.Fprintf(&buf, "\t\t\tWebSocket: h.%s,\n", route.Name)
+			} else {
+				fmt.Fprintf(&buf, "\t\t\tHandler: core.MustHandler(h.%s),\n", route.Name)
+			}
+			fmt.Fprintf(&buf, "\t\t\tDoc: %s,\n", docLiteral(route))
+			fmt.Fprintf(&buf, "\t\t},\n")
+		}
+		fmt.Fprintf(&buf, "\t}\n}\n")
+	}
+
+	return format.Source(buf.Bytes())
+}
+
+// docLiteral viết annotation của route thành literal *core.ParseRoute, bỏ qua field rỗng
+func docLiteral(r core.ParseRoute) string {
+	var b strings.Builder
+	b.WriteString("&core.ParseRoute{\n")
+	field := func(name, value string) {
+		if value != "" {
+			fmt.Fprintf(&b, "%s: %q,\n", name, value)
+		}
+	}
+	field("Path", r.Path)
+	field("Method", r.Method)
+	field("Name", r.Name)
+	field("Receiver", r.Receiver)
+	field("Summary", r.Summary)
+	field("Timeout", r.Timeout)
+	field("RateLimit", r.RateLimit)
+	if len(r.Tags) > 0 {
+		fmt.Fprintf(&b, "Tags: %#v,\n", r.Tags)
+	}
+	if len(r.Params) > 0 {
+		b.WriteString("Params: []core.ParseParam{\n")
+		for _, p := range r.Params {
+			fmt.Fprintf(&b, "{Name: %q, In: %q, Type: %q, Required: %t, Description: %q},\n",
+				p.Name, p.In, p.Type, p.Required, p.Description)
+		}
+		b.WriteString("},\n")
+	}
+	if r.Body != nil {
+		fmt.Fprintf(&b, "Body: &core.ParseBody{Type: %q, Description: %q},\n", r.Body.Type, r.Body.Description)
+	}
+	if len(r.Response) > 0 {
+		b.WriteString("Response: []core.ParseResponse{\n")
+		for _, res := range r.Response {
+			fmt.Fprintf(&b, "{Code: %q, Type: %q, Description: %q},\n", res.Code, res.Type, res.Description)
+		}
+		b.WriteString("},\n")
+	}
+	if r.Schemas != nil {
+		names := make([]string, 0, len(r.Schemas))
+		for name := range r.Schemas {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		b.WriteString("Schemas: map[string]*core.Schema{\n")
+		for _, name := range names {
+			fmt.Fprintf(&b, "%q: %s,\n", name, schemaLiteral(r.Schemas[name]))
+		}
+		b.WriteString("},\n")
+	}
+	b.WriteString("}")
+	return b.String()
+}
+
+// schemaLiteral viết schema thành literal *core.Schema, bỏ qua field rỗng
+func schemaLiteral(s *core.Schema) string {
+	var b strings.Builder
+	b.WriteString("{")
+	field := func(name, value string) {
+		if value != "" {
+			fmt.Fprintf(&b, "%s: %q, ", name, value)
+		}
+	}
+	field("Ref", s.Ref)
+	field("Type", s.Type)
+	field("Format", s.Format)
+	field("Description", s.Description)
+	if len(s.Properties) > 0 {
+		names := make([]string, 0, len(s.Properties))
+		for name := range s.Properties {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		b.WriteString("Properties: map[string]*core.Schema{\n")
+		for _, name := range names {
+			fmt.Fprintf(&b, "%q: %s,\n", name, schemaLiteral(s.Properties[name]))
+		}
+		b.WriteString("}, ")
+	}
+	if len(s.Required) > 0 {
+		fmt.Fprintf(&b, "Required: %#v, ", s.Required)
+	}
+	if s.Items != nil {
+		fmt.Fprintf(&b, "Items: &core.Schema%s, ", schemaLiteral(s.Items))
+	}
+	if s.AdditionalProperties != nil {
+		fmt.Fprintf(&b, "AdditionalProperties: &core.Schema%s, ", schemaLiteral(s.AdditionalProperties))
+	}
+	return strings.TrimSuffix(b.String(), ", ") + "}"
+}
+
+// findMethod tìm khai báo method name của kiểu recv trong file
+func findMethod(f *ast.File, recv, name string) *ast.FuncDecl {
+	for _, decl := range f.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Recv == nil || len(fn.Recv.List) == 0 || fn.Name.Name != name {
+			continue
+		}
+		typ := fn.Recv.List[0].Type
+		if star, ok := typ.(*ast.StarExpr); ok {
+			typ = star.X
+		}
+		if ident, ok := typ.(*ast.Ident); ok && ident.Name == recv {
+			return fn
+		}
+	}
+	return nil
+}
+
+// imports trả về tên package dùng trong file theo import path
+func imports(f *ast.File) map[string]string {
+	names := make(map[string]string)
+	for _, spec := range f.Imports {
+		path, _ := strconv.Unquote(spec.Path.Value)
+		name := path[strings.LastIndex(path, "/")+1:]
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		names[name] = path
+	}
+	return names
+}
+
+// checkSignature checks fn against the shapes core.NewHandler accepts, or
+// func(core.WSConn) for WS routes, as far as the source tells: request
+// types from other packages are assumed to be structs.
+func checkSignature(fn *ast.FuncDecl, imports map[string]string, types map[string]*ast.TypeSpec, ws bool) error {
+	var params, results []ast.Expr
+	for _, field := range fn.Type.Params.List {
+		for i := 0; i < max(1, len(field.Names)); i++ {
+			params = append(params, field.Type)
+		}
+	}
+	if fn.Type.Results != nil {
+		for _, field := range fn.Type.Results.List {
+			for i := 0; i < max(1, len(field.Names)); i++ {
+				results = append(results, field.Type)
+			}
+		}
+	}
+	is := func(expr ast.Expr, path, name string) bool {
+		sel, ok := expr.(*ast.SelectorExpr)
+		if !ok || sel.Sel.Name != name {
+			return false
+		}
+		pkg, ok := sel.X.(*ast.Ident)
+		return ok && imports[pkg.Name] == path
+	}
+
+	if ws {
+		if len(params) != 1 || !is(params[0], corePath, "WSConn") || len(results) != 0 {
+			return errors.New("WS routes take func(core.WSConn)")
+		}
+		return nil
+	}
+	if len(params) < 1 || len(params) > 2 {
+		return errors.New("handler must take (Context[, Req])")
+	}
+	coreCtx := is(params[0], corePath, "Context")
+	if !coreCtx && !is(params[0], contextPath, "Context") {
+		return errors.New("handler first parameter must be core.Context or context.Context")
+	}
+	if len(params) == 2 && !isStruct(params[1], types) {
+		return errors.New("handler request parameter must be a struct or struct pointer")
+	}
+	switch len(results) {
+	case 0:
+		if !coreCtx || len(params) == 2 {
+			return errors.New("handler must return error or (Resp, error)")
+		}
+	case 1, 2:
+		if ident, ok := results[len(results)-1].(*ast.Ident); !ok || ident.Name != "error" {
+			return errors.New("handler last return value must be error")
+		}
+	default:
+		return errors.New("handler must return error or (Resp, error)")
+	}
+	return nil
+}
+
+// isStruct: struct, con trỏ tới struct, hoặc kiểu của package khác (không kiểm tra được)
+func isStruct(expr ast.Expr, types map[string]*ast.TypeSpec) bool {
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+	switch e := expr.(type) {
+	case *ast.StructType, *ast.SelectorExpr:
+		return true
+	case *ast.Ident:
+		spec, ok := types[e.Name]
+		if !ok {
+			return false
+		}
+		if spec.Assign.IsValid() {
+			return isStruct(spec.Type, types)
+		}
+		_, ok = spec.Type.(*ast.StructType)
+		return ok
+	}
+	return false
+}
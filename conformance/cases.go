@@ -0,0 +1,4461 @@
+package conformance
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/andybalholm/brotli"
+	"github.com/kimxuanhong/go-server/core"
+	"github.com/kimxuanhong/go-server/jwt"
+	"golang.org/x/net/http2"
+	"html/template"
+	"io"
+	"math/big"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Cases returns the conformance table. Each call returns fresh cases, so
+// state captured by closures is never shared between runs.
+func Cases() []Case {
+	var cases []Case
+	cases = append(cases, contextCases()...)
+	cases = append(cases, chainCases()...)
+	cases = append(cases, serverCases()...)
+	cases = append(cases, typedCases()...)
+	cases = append(cases, validationCases()...)
+	cases = append(cases, errorCases()...)
+	cases = append(cases, healthCases()...)
+	cases = append(cases, adminCases()...)
+	cases = append(cases, lifecycleCases()...)
+	cases = append(cases, tlsCases()...)
+	cases = append(cases, protocolCases()...)
+	cases = append(cases, listenerCases()...)
+	cases = append(cases, limitCases()...)
+	cases = append(cases, timeoutCases()...)
+	cases = append(cases, bodyCases()...)
+	cases = append(cases, multipartCases()...)
+	cases = append(cases, responseCases()...)
+	cases = append(cases, negotiateCases()...)
+	cases = append(cases, sseCases()...)
+	cases = append(cases, wsCases()...)
+	cases = append(cases, corsCases()...)
+	cases = append(cases, rateLimitCases()...)
+	cases = append(cases, compressCases()...)
+	return cases
+}
+
+func contextCases() []Case {
+	return []Case{
+		{
+			Name: "Param",
+			Setup: func(t *testing.T, s core.Server) {
+				s.Add(core.MethodGet, "/users/:id", func(c core.Context) {
+					_ = c.String(core.StatusOK, c.Param("id"))
+				})
+			},
+			Target: RootPath + "/users/42",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusOK)
+				ExpectBody(t, res, "42")
+			},
+		},
+		{
+			Name: "Query",
+			Setup: func(t *testing.T, s core.Server) {
+				s.Add(core.MethodGet, "/search", func(c core.Context) {
+					_ = c.String(core.StatusOK, c.Query("q")+"|"+c.Query("missing"))
+				})
+			},
+			Target: RootPath + "/search?q=go",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectBody(t, res, "go|")
+			},
+		},
+		{
+			Name: "Header",
+			Setup: func(t *testing.T, s core.Server) {
+				s.Add(core.MethodGet, "/header", func(c core.Context) {
+					_ = c.String(core.StatusOK, c.Header(core.HeaderXRequestID))
+				})
+			},
+			Target:  RootPath + "/header",
+			Headers: map[string]string{core.HeaderXRequestID: "req-1"},
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectBody(t, res, "req-1")
+			},
+		},
+		{
+			Name: "Bind",
+			Setup: func(t *testing.T, s core.Server) {
+				s.Add(core.MethodPost, "/bind", func(c core.Context) {
+					var req struct {
+						Name string `json:"name"`
+						Age  int    `json:"age"`
+					}
+					if err := c.Bind(&req); err != nil {
+						_ = c.String(core.StatusBadRequest, err.Error())
+						return
+					}
+					_ = c.String(core.StatusOK, req.Name+":"+strconv.Itoa(req.Age))
+				})
+			},
+			Method: core.MethodPost,
+			Target: RootPath + "/bind",
+			Body:   `{"name":"bob","age":30}`,
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusOK)
+				ExpectBody(t, res, "bob:30")
+			},
+		},
+		{
+			Name: "JSON",
+			Setup: func(t *testing.T, s core.Server) {
+				s.Add(core.MethodGet, "/json", func(c core.Context) {
+					c.JSON(core.StatusCreated, map[string]string{"message": "created"})
+				})
+			},
+			Target: RootPath + "/json",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusCreated)
+				ExpectHeader(t, res, core.HeaderContentType, core.MIMEApplicationJSON)
+				ExpectBody(t, res, `{"message":"created"}`)
+			},
+		},
+		{
+			Name: "String",
+			Setup: func(t *testing.T, s core.Server) {
+				s.Add(core.MethodGet, "/string", func(c core.Context) {
+					if err := c.String(core.StatusAccepted, "hello"); err != nil {
+						t.Errorf("String() error = %v", err)
+					}
+				})
+			},
+			Target: RootPath + "/string",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusAccepted)
+				ExpectHeader(t, res, core.HeaderContentType, core.MIMETextPlain)
+				ExpectBody(t, res, "hello")
+			},
+		},
+		{
+			Name: "Status",
+			Setup: func(t *testing.T, s core.Server) {
+				s.Add(core.MethodDelete, "/status", func(c core.Context) {
+					c.Status(core.StatusNoContent)
+				})
+			},
+			Method: core.MethodDelete,
+			Target: RootPath + "/status",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusNoContent)
+				ExpectBody(t, res, "")
+			},
+		},
+		{
+			Name: "SetHeader",
+			Setup: func(t *testing.T, s core.Server) {
+				s.Add(core.MethodGet, "/set-header", func(c core.Context) {
+					c.SetHeader(core.HeaderXRequestID, "res-1")
+					c.JSON(core.StatusOK, map[string]string{})
+				})
+			},
+			Target: RootPath + "/set-header",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectHeader(t, res, core.HeaderXRequestID, "res-1")
+			},
+		},
+		{
+			Name: "Method",
+			Setup: func(t *testing.T, s core.Server) {
+				s.Add(core.MethodPut, "/method", func(c core.Context) {
+					_ = c.String(core.StatusOK, c.Method())
+				})
+			},
+			Method: core.MethodPut,
+			Target: RootPath + "/method",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectBody(t, res, core.MethodPut)
+			},
+		},
+		{
+			Name: "Path",
+			Setup: func(t *testing.T, s core.Server) {
+				s.Add(core.MethodGet, "/users/:id", func(c core.Context) {
+					_ = c.String(core.StatusOK, c.GetString("mw-path")+"|"+c.Path())
+				}, func(c core.Context) {
+					c.Set("mw-path", c.Path())
+					c.Next()
+				})
+			},
+			Target: RootPath + "/users/7",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectBody(t, res, RootPath+"/users/:id|"+RootPath+"/users/:id")
+			},
+		},
+		{
+			Name: "SetGet",
+			Setup: func(t *testing.T, s core.Server) {
+				s.Add(core.MethodGet, "/values", func(c core.Context) {
+					ok := c.Get("user") == "bob" &&
+						c.GetString("user") == "bob" &&
+						c.GetInt("count") == 7 &&
+						c.Get("missing") == nil &&
+						c.GetString("missing") == "" &&
+						c.GetInt("missing") == 0
+					_ = c.String(core.StatusOK, strconv.FormatBool(ok))
+				}, func(c core.Context) {
+					c.Set("user", "bob")
+					c.Set("count", 7)
+					c.Next()
+				})
+			},
+			Target: RootPath + "/values",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectBody(t, res, "true")
+			},
+		},
+		{
+			Name: "Context",
+			Setup: func(t *testing.T, s core.Server) {
+				s.Add(core.MethodGet, "/context", func(c core.Context) {
+					ctx := c.Context()
+					ok := ctx != nil && ctx.Err() == nil && ctx.Value("user") == "bob"
+					_ = c.String(core.StatusOK, strconv.FormatBool(ok))
+				}, func(c core.Context) {
+					c.Set("user", "bob")
+				})
+			},
+			Target: RootPath + "/context",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectBody(t, res, "true")
+			},
+		},
+		{
+			Name: "Raw",
+			Setup: func(t *testing.T, s core.Server) {
+				s.Add(core.MethodGet, "/raw", func(c core.Context) {
+					_ = c.String(core.StatusOK, strconv.FormatBool(c.Raw() != nil))
+				})
+			},
+			Target: RootPath + "/raw",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectBody(t, res, "true")
+			},
+		},
+	}
+}
+
+// chainCases check Next/Abort ordering for global, group and route middleware.
+func chainCases() []Case {
+	var order []string
+	record := func(name string) core.Handler {
+		return func(c core.Context) {
+			order = append(order, name+":before")
+			c.Next()
+			order = append(order, name+":after")
+		}
+	}
+	expectOrder := func(t *testing.T, want ...string) {
+		t.Helper()
+		if strings.Join(order, ",") != strings.Join(want, ",") {
+			t.Fatalf("order = %v, want %v", order, want)
+		}
+	}
+	handler := func(c core.Context) {
+		order = append(order, "handler")
+		_ = c.String(core.StatusOK, "ok")
+	}
+
+	return []Case{
+		{
+			Name: "NextOrder",
+			Setup: func(t *testing.T, s core.Server) {
+				order = nil
+				s.Use(record("global"))
+				s.AddGroup("/group", func(rg core.RouterGroup) {
+					rg.Add(core.MethodGet, "/chain", handler, record("route"))
+				}, record("group"))
+			},
+			Target: RootPath + "/group/chain",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusOK)
+				expectOrder(t,
+					"global:before", "group:before", "route:before", "handler",
+					"route:after", "group:after", "global:after")
+			},
+		},
+		{
+			Name: "NextImplicit",
+			Setup: func(t *testing.T, s core.Server) {
+				order = nil
+				s.Add(core.MethodGet, "/implicit", handler, func(c core.Context) {
+					order = append(order, "mw")
+				})
+			},
+			Target: RootPath + "/implicit",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusOK)
+				expectOrder(t, "mw", "handler")
+			},
+		},
+		{
+			Name: "NextOnce",
+			Setup: func(t *testing.T, s core.Server) {
+				order = nil
+				s.Add(core.MethodGet, "/once", handler, func(c core.Context) {
+					c.Next()
+					c.Next()
+				})
+			},
+			Target: RootPath + "/once",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				expectOrder(t, "handler")
+			},
+		},
+		{
+			Name: "Abort",
+			Setup: func(t *testing.T, s core.Server) {
+				order = nil
+				s.Add(core.MethodGet, "/abort", handler, func(c core.Context) {
+					c.Abort()
+					_ = c.String(core.StatusForbidden, "forbidden")
+				}, record("after-abort"))
+			},
+			Target: RootPath + "/abort",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusForbidden)
+				ExpectBody(t, res, "forbidden")
+				expectOrder(t)
+			},
+		},
+		{
+			Name: "AbortThenNext",
+			Setup: func(t *testing.T, s core.Server) {
+				order = nil
+				s.Add(core.MethodGet, "/abort-next", handler, func(c core.Context) {
+					c.Abort()
+					c.Status(core.StatusForbidden)
+					c.Next()
+				})
+			},
+			Target: RootPath + "/abort-next",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusForbidden)
+				expectOrder(t)
+			},
+		},
+		{
+			Name: "AbortWithStatusJSON",
+			Setup: func(t *testing.T, s core.Server) {
+				order = nil
+				s.Use(func(c core.Context) {
+					c.AbortWithStatusJSON(core.StatusUnauthorized, map[string]string{"error": "missing token"})
+				})
+				s.Add(core.MethodGet, "/private", handler)
+			},
+			Target: RootPath + "/private",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusUnauthorized)
+				ExpectHeader(t, res, core.HeaderContentType, core.MIMEApplicationJSON)
+				ExpectBody(t, res, `{"error":"missing token"}`)
+				expectOrder(t)
+			},
+		},
+		{
+			Name: "RouteMiddlewareScope",
+			Setup: func(t *testing.T, s core.Server) {
+				order = nil
+				s.AddGroup("/group", func(rg core.RouterGroup) {
+					rg.Add(core.MethodGet, "/a", handler, record("a"))
+					rg.Add(core.MethodGet, "/b", handler)
+				})
+				s.Add(core.MethodGet, "/c", handler, record("c"))
+				s.Add(core.MethodGet, "/d", handler)
+			},
+			Target: RootPath + "/group/b",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusOK)
+				expectOrder(t, "handler")
+			},
+		},
+	}
+}
+
+func serverCases() []Case {
+	return []Case{
+		{
+			Name: "GroupPath",
+			Setup: func(t *testing.T, s core.Server) {
+				s.AddGroup("/v1", func(rg core.RouterGroup) {
+					rg.Add(core.MethodGet, "/items/:id", func(c core.Context) {
+						_ = c.String(core.StatusOK, c.Path()+"|"+c.Param("id"))
+					})
+				})
+			},
+			Target: RootPath + "/v1/items/9",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectBody(t, res, RootPath+"/v1/items/:id|9")
+			},
+		},
+		{
+			Name: "Routes",
+			Setup: func(t *testing.T, s core.Server) {
+				s.Routes([]core.RouteConfig{{
+					Method: core.MethodGet,
+					Path:   "/configured",
+					Handler: func(c core.Context) {
+						_ = c.String(core.StatusOK, c.GetString("mw"))
+					},
+					Middleware: []core.Handler{func(c core.Context) {
+						c.Set("mw", "route-config")
+						c.Next()
+					}},
+				}})
+			},
+			Target: RootPath + "/configured",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectBody(t, res, "route-config")
+			},
+		},
+		{
+			Name: "RegisterHandlers",
+			Setup: func(t *testing.T, s core.Server) {
+				s.RegisterHandlers(&ProviderHandler{})
+			},
+			Target: RootPath + "/provided",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectBody(t, res, "provider:"+RootPath+"/provided")
+			},
+		},
+		{
+			Name: "RegisterHandlersWithTags",
+			Setup: func(t *testing.T, s core.Server) {
+				s.RegisterHandlersWithTags(&TagHandler{})
+			},
+			Target: RootPath + "/tags/hello",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectBody(t, res, "tag:"+RootPath+"/tags/hello")
+			},
+		},
+		{
+			Name: "RegisterHandlersWithTagsDevMode",
+			Mode: "debug",
+			Setup: func(t *testing.T, s core.Server) {
+				s.RegisterHandlersWithTags(&DevTagHandler{})
+			},
+			Target: RootPath + "/dev/hello",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusOK)
+				ExpectBody(t, res, "dev:"+RootPath+"/dev/hello")
+			},
+		},
+		{
+			Name: "RegisterHandlersWithTagsNoSourceParsing",
+			Setup: func(t *testing.T, s core.Server) {
+				s.RegisterHandlersWithTags(&DevTagHandler{})
+			},
+			Target: RootPath + "/dev/hello",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusNotFound)
+			},
+		},
+		{
+			Name: "OpenAPI",
+			Setup: func(t *testing.T, s core.Server) {
+				s.RegisterHandlersWithTags(&TagHandler{})
+				s.OpenAPI(core.OpenAPIInfo{Title: "Conformance"})
+			},
+			Target: core.OpenAPIPath,
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusOK)
+				var doc core.OpenAPI
+				if err := json.Unmarshal(res.Body.Bytes(), &doc); err != nil {
+					t.Fatalf("invalid document: %v", err)
+				}
+				if doc.OpenAPI != "3.1.0" || doc.Info.Title != "Conformance" {
+					t.Fatalf("openapi = %q, title = %q", doc.OpenAPI, doc.Info.Title)
+				}
+				if doc.Paths[RootPath+"/tags/hello"]["get"] == nil {
+					t.Fatalf("missing GET %s/tags/hello in %v", RootPath, doc.Paths)
+				}
+				greet := doc.Paths[RootPath+"/tags/greet/{lang}"]["post"]
+				if greet == nil || greet.RequestBody == nil || len(greet.Parameters) != 2 {
+					t.Fatalf("invalid POST %s/tags/greet/{lang}: %+v", RootPath, greet)
+				}
+				if ref := greet.RequestBody.Content[core.MIMEApplicationJSON].Schema.Ref; ref != "#/components/schemas/Greeting" {
+					t.Fatalf("body $ref = %q", ref)
+				}
+				greeting := doc.Components.Schemas["Greeting"]
+				if greeting == nil || strings.Join(greeting.Required, ",") != "name" || greeting.Properties["tags"].Type != "array" {
+					t.Fatalf("invalid Greeting schema: %+v", greeting)
+				}
+			},
+		},
+		{
+			// Schema sinh sẵn đủ cho tài liệu, không cần đọc source lúc chạy
+			Name: "OpenAPIGeneratedSchemas",
+			Setup: func(t *testing.T, s core.Server) {
+				s.RegisterHandlersWithTags(generatedHandler{})
+				s.OpenAPI(core.OpenAPIInfo{Title: "Conformance"})
+			},
+			Target: core.OpenAPIPath,
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusOK)
+				var doc core.OpenAPI
+				if err := json.Unmarshal(res.Body.Bytes(), &doc); err != nil {
+					t.Fatalf("invalid document: %v", err)
+				}
+				op := doc.Paths[RootPath+"/ghosts"]["get"]
+				if op == nil || op.Responses["200"].Content[core.MIMEApplicationJSON].Schema.Items.Ref != "#/components/schemas/Ghost" {
+					t.Fatalf("invalid GET %s/ghosts: %s", RootPath, res.Body.String())
+				}
+				if ghost := doc.Components.Schemas["Ghost"]; ghost == nil || ghost.Properties["name"].Type != "string" {
+					t.Fatalf("invalid Ghost schema: %+v", ghost)
+				}
+			},
+		},
+		{
+			Name: "Static",
+			Setup: func(t *testing.T, s core.Server) {
+				dir := t.TempDir()
+				if err := os.WriteFile(filepath.Join(dir, "hello.txt"), []byte("static"), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				s.Static("/assets", dir)
+			},
+			Target: "/assets/hello.txt",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusOK)
+				ExpectBody(t, res, "static")
+			},
+		},
+		{
+			Name: "HealthCheckPing",
+			Setup: func(t *testing.T, s core.Server) {
+				s.HealthCheck()
+			},
+			Target: "/ping",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusOK)
+				ExpectBody(t, res, `{"message":"pong"}`)
+			},
+		},
+		{
+			Name: "HealthCheckLiveness",
+			Setup: func(t *testing.T, s core.Server) {
+				s.HealthCheck()
+			},
+			Target: "/liveness",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusOK)
+				ExpectBody(t, res, `{"status":"alive"}`)
+			},
+		},
+		{
+			Name: "HealthCheckReadiness",
+			Setup: func(t *testing.T, s core.Server) {
+				s.HealthCheck()
+			},
+			Target: "/readiness",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusOK)
+				ExpectBody(t, res, `{"status":"ready"}`)
+			},
+		},
+		{
+			Name: "NotFound",
+			Setup: func(t *testing.T, s core.Server) {
+				s.Add(core.MethodGet, "/exists", func(c core.Context) {
+					_ = c.String(core.StatusOK, "ok")
+				})
+			},
+			Target: RootPath + "/missing",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusNotFound)
+			},
+		},
+	}
+}
+
+type typedRequest struct {
+	ID      int     `path:"id"`
+	Page    *int    `query:"page"`
+	Request string  `header:"X-Request-ID"`
+	Name    string  `json:"name"`
+	Score   float64 `json:"score"`
+}
+
+type typedResponse struct {
+	ID      int    `json:"id"`
+	Page    int    `json:"page"`
+	Request string `json:"request"`
+	Name    string `json:"name"`
+}
+
+type conflictError struct{}
+
+func (conflictError) Error() string   { return "already exists" }
+func (conflictError) StatusCode() int { return core.StatusConflict }
+
+// typedCases check handlers adapted by core.NewHandler.
+func typedCases() []Case {
+	typed := func(c core.Context, req *typedRequest) (*typedResponse, error) {
+		page := 0
+		if req.Page != nil {
+			page = *req.Page
+		}
+		return &typedResponse{ID: req.ID, Page: page, Request: req.Request, Name: req.Name}, nil
+	}
+
+	return []Case{
+		{
+			Name: "TypedHandlerBind",
+			Setup: func(t *testing.T, s core.Server) {
+				s.Add(core.MethodPost, "/typed/:id", core.MustHandler(typed))
+			},
+			Method:  core.MethodPost,
+			Target:  RootPath + "/typed/5?page=2",
+			Body:    `{"name":"bob"}`,
+			Headers: map[string]string{core.HeaderXRequestID: "req-9"},
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusOK)
+				ExpectBody(t, res, `{"id":5,"page":2,"request":"req-9","name":"bob"}`)
+			},
+		},
+		{
+			// Giá trị của path và header thắng field cùng tên trong body
+			Name: "TypedHandlerBodyPathConflict",
+			Setup: func(t *testing.T, s core.Server) {
+				s.Add(core.MethodPut, "/typed/:id", core.MustHandler(typed))
+			},
+			Method:  core.MethodPut,
+			Target:  RootPath + "/typed/1",
+			Body:    `{"id":2,"request":"body","name":"bob"}`,
+			Headers: map[string]string{core.HeaderXRequestID: "req-1"},
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusOK)
+				ExpectBody(t, res, `{"id":1,"page":0,"request":"req-1","name":"bob"}`)
+			},
+		},
+		{
+			Name: "TypedHandlerNoBody",
+			Setup: func(t *testing.T, s core.Server) {
+				s.Add(core.MethodGet, "/typed/:id", core.MustHandler(typed))
+			},
+			Target: RootPath + "/typed/7",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusOK)
+				ExpectBody(t, res, `{"id":7,"page":0,"request":"","name":""}`)
+			},
+		},
+		{
+			Name: "TypedHandlerBindError",
+			Setup: func(t *testing.T, s core.Server) {
+				s.Add(core.MethodGet, "/typed/:id", core.MustHandler(typed))
+			},
+			Target: RootPath + "/typed/abc",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusBadRequest)
+				ExpectHeader(t, res, core.HeaderContentType, core.MIMEApplicationProblemJSON)
+			},
+		},
+		{
+			Name: "TypedHandlerStdContext",
+			Setup: func(t *testing.T, s core.Server) {
+				s.Add(core.MethodPut, "/typed/:id", core.MustHandler(func(ctx context.Context, req typedRequest) (typedResponse, error) {
+					if ctx == nil {
+						t.Error("nil context.Context")
+					}
+					return typedResponse{ID: req.ID}, nil
+				}))
+			},
+			Method: core.MethodPut,
+			Target: RootPath + "/typed/3",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusOK)
+				ExpectBody(t, res, `{"id":3,"page":0,"request":"","name":""}`)
+			},
+		},
+		{
+			Name: "TypedHandlerStatusError",
+			Setup: func(t *testing.T, s core.Server) {
+				s.Add(core.MethodPost, "/typed", core.MustHandler(func(c core.Context) error {
+					return conflictError{}
+				}))
+			},
+			Method: core.MethodPost,
+			Target: RootPath + "/typed",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusConflict)
+				ExpectBody(t, res, `{"detail":"already exists","status":409,"title":"Conflict","type":"about:blank"}`)
+			},
+		},
+		{
+			Name: "TypedHandlerInternalError",
+			Setup: func(t *testing.T, s core.Server) {
+				s.Add(core.MethodGet, "/typed", core.MustHandler(func(ctx context.Context) (*typedResponse, error) {
+					return nil, errors.New("database is down")
+				}))
+			},
+			Target: RootPath + "/typed",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusInternalServerError)
+				ExpectBody(t, res, `{"status":500,"title":"Internal Server Error","type":"about:blank"}`)
+			},
+		},
+		{
+			Name: "TypedHandlerNilResponse",
+			Setup: func(t *testing.T, s core.Server) {
+				s.Add(core.MethodDelete, "/typed/:id", core.MustHandler(func(c core.Context, req *typedRequest) (*typedResponse, error) {
+					return nil, nil
+				}))
+			},
+			Method: core.MethodDelete,
+			Target: RootPath + "/typed/1",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusNoContent)
+				ExpectBody(t, res, "")
+			},
+		},
+		{
+			Name: "TypedHandlerGenerated",
+			Setup: func(t *testing.T, s core.Server) {
+				s.RegisterHandlersWithTags(&TagHandler{})
+			},
+			Method: core.MethodPost,
+			Target: RootPath + "/tags/greet/vi",
+			Body:   `{"name":"an","tags":["a"]}`,
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusOK)
+				ExpectBody(t, res, `{"name":"vi:an","tags":["a"]}`)
+			},
+		},
+	}
+}
+
+type signupRequest struct {
+	Email string       `json:"email" validate:"required,email"`
+	Age   int          `json:"age" binding:"gte=18"`
+	Items []signupItem `json:"items" validate:"dive"`
+	Ref   string       `query:"ref" validate:"omitempty,len=4"`
+}
+
+type signupItem struct {
+	Name string `json:"name" validate:"required"`
+}
+
+// validationCases check that Bind validates the same way on every engine.
+func validationCases() []Case {
+	signup := func(c core.Context, req *signupRequest) error {
+		return c.String(core.StatusOK, req.Email)
+	}
+	return []Case{
+		{
+			Name: "ValidateValid",
+			Setup: func(t *testing.T, s core.Server) {
+				s.Add(core.MethodPost, "/signup", core.MustHandler(signup))
+			},
+			Method: core.MethodPost,
+			Target: RootPath + "/signup?ref=abcd",
+			Body:   `{"email":"a@b.co","age":20,"items":[{"name":"x"}]}`,
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusOK)
+				ExpectBody(t, res, "a@b.co")
+			},
+		},
+		{
+			Name: "ValidateFieldErrors",
+			Setup: func(t *testing.T, s core.Server) {
+				s.Add(core.MethodPost, "/signup", core.MustHandler(signup))
+			},
+			Method: core.MethodPost,
+			Target: RootPath + "/signup?ref=abc",
+			Body:   `{"email":"nope","age":17,"items":[{"name":""}]}`,
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusUnprocessableEntity)
+				var body struct {
+					Errors []core.FieldError `json:"errors"`
+				}
+				if err := json.Unmarshal(res.Body.Bytes(), &body); err != nil {
+					t.Fatal(err)
+				}
+				var got []string
+				for _, fe := range body.Errors {
+					got = append(got, fe.Field+":"+fe.Rule)
+				}
+				want := "email:email,items[0].name:required,ref:len,age:gte"
+				if strings.Join(got, ",") != want {
+					t.Fatalf("field errors = %v, want %s", got, want)
+				}
+			},
+		},
+		{
+			Name: "ValidateNoBody",
+			Setup: func(t *testing.T, s core.Server) {
+				s.Add(core.MethodGet, "/signup", core.MustHandler(signup))
+			},
+			Target: RootPath + "/signup",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusUnprocessableEntity)
+			},
+		},
+		{
+			Name: "BindAndValidate",
+			Setup: func(t *testing.T, s core.Server) {
+				s.Add(core.MethodPost, "/signup", func(c core.Context) {
+					var req signupRequest
+					if !core.BindAndValidate(c, &req) {
+						return
+					}
+					_ = c.String(core.StatusOK, req.Email)
+				})
+			},
+			Method: core.MethodPost,
+			Target: RootPath + "/signup",
+			Body:   `{"age":18}`,
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusUnprocessableEntity)
+				ExpectBody(t, res, `{"detail":"validation failed: email is required","errors":[{"field":"email","rule":"required","message":"email is required"}],"status":422,"title":"Unprocessable Entity","type":"about:blank"}`)
+			},
+		},
+		{
+			Name: "BindAndValidateMalformed",
+			Setup: func(t *testing.T, s core.Server) {
+				s.Add(core.MethodPost, "/signup", func(c core.Context) {
+					var req signupRequest
+					if !core.BindAndValidate(c, &req) {
+						return
+					}
+					_ = c.String(core.StatusOK, req.Email)
+				})
+			},
+			Method: core.MethodPost,
+			Target: RootPath + "/signup",
+			Body:   `{"email":`,
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusBadRequest)
+			},
+		},
+	}
+}
+
+// errorCases check that Context.Error, panics and unmatched routes render the
+// same problem+json body on every engine.
+func errorCases() []Case {
+	return []Case{
+		{
+			Name: "ErrorHTTPError",
+			Setup: func(t *testing.T, s core.Server) {
+				s.Add(core.MethodGet, "/users/:id", func(c core.Context) {
+					c.Error(core.NewHTTPError(core.StatusNotFound, "user "+c.Param("id")+" does not exist").With("userId", c.Param("id")))
+				})
+			},
+			Target: RootPath + "/users/42",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusNotFound)
+				ExpectHeader(t, res, core.HeaderContentType, core.MIMEApplicationProblemJSON)
+				ExpectBody(t, res, `{"detail":"user 42 does not exist","status":404,"title":"Not Found","type":"about:blank","userId":"42"}`)
+			},
+		},
+		{
+			Name: "ErrorAbortsChain",
+			Setup: func(t *testing.T, s core.Server) {
+				s.Add(core.MethodGet, "/guarded", func(c core.Context) {
+					t.Error("handler ran after Error")
+				}, func(c core.Context) {
+					c.Error(core.NewHTTPError(core.StatusForbidden, ""))
+				})
+			},
+			Target: RootPath + "/guarded",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusForbidden)
+				ExpectBody(t, res, `{"status":403,"title":"Forbidden","type":"about:blank"}`)
+			},
+		},
+		{
+			Name: "ErrorPanic",
+			Setup: func(t *testing.T, s core.Server) {
+				s.Add(core.MethodGet, "/panic", func(c core.Context) {
+					panic("boom")
+				})
+			},
+			Target: RootPath + "/panic",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusInternalServerError)
+				ExpectHeader(t, res, core.HeaderContentType, core.MIMEApplicationProblemJSON)
+				ExpectBody(t, res, `{"status":500,"title":"Internal Server Error","type":"about:blank"}`)
+			},
+		},
+		{
+			Name: "ErrorNotFound",
+			Setup: func(t *testing.T, s core.Server) {
+				s.Add(core.MethodGet, "/ping", func(c core.Context) {})
+			},
+			Target: RootPath + "/missing",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusNotFound)
+				ExpectHeader(t, res, core.HeaderContentType, core.MIMEApplicationProblemJSON)
+				ExpectBody(t, res, `{"status":404,"title":"Not Found","type":"about:blank"}`)
+			},
+		},
+		{
+			Name: "ErrorMethodNotAllowed",
+			Setup: func(t *testing.T, s core.Server) {
+				s.Add(core.MethodGet, "/ping", func(c core.Context) {})
+			},
+			Method: core.MethodPost,
+			Target: RootPath + "/ping",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusMethodNotAllowed)
+				ExpectHeader(t, res, core.HeaderContentType, core.MIMEApplicationProblemJSON)
+				ExpectBody(t, res, `{"status":405,"title":"Method Not Allowed","type":"about:blank"}`)
+			},
+		},
+		{
+			Name: "ErrorHandlerHook",
+			Setup: func(t *testing.T, s core.Server) {
+				s.SetErrorHandler(func(c core.Context, err error) {
+					_ = c.String(core.ToHTTPError(err).Status, "custom: "+err.Error())
+					c.Abort()
+				})
+				s.Add(core.MethodGet, "/typed/:id", core.MustHandler(func(c core.Context) error {
+					return conflictError{}
+				}))
+			},
+			Target: RootPath + "/typed/1",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusConflict)
+				ExpectBody(t, res, "custom: already exists")
+			},
+		},
+		{
+			Name: "ErrorHandlerHookNotFound",
+			Setup: func(t *testing.T, s core.Server) {
+				s.SetErrorHandler(func(c core.Context, err error) {
+					_ = c.String(core.ToHTTPError(err).Status, "custom")
+					c.Abort()
+				})
+				s.Add(core.MethodGet, "/ping", func(c core.Context) {})
+			},
+			Target: RootPath + "/missing",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusNotFound)
+				ExpectBody(t, res, "custom")
+			},
+		},
+	}
+}
+
+func decodeHealth(t *testing.T, res *httptest.ResponseRecorder) core.HealthReport {
+	t.Helper()
+	var report core.HealthReport
+	if err := json.Unmarshal(res.Body.Bytes(), &report); err != nil {
+		t.Fatalf("invalid health report %q: %v", res.Body.String(), err)
+	}
+	return report
+}
+
+// healthCases check the checks registered with RegisterHealthCheck.
+func healthCases() []Case {
+	ok := core.HealthCheckerFunc(func(ctx context.Context) error { return nil })
+	failing := core.HealthCheckerFunc(func(ctx context.Context) error { return errors.New("connection refused") })
+	return []Case{
+		{
+			Name: "HealthCheckCriticalDown",
+			Setup: func(t *testing.T, s core.Server) {
+				s.HealthCheck()
+				s.RegisterHealthCheck("cache", ok)
+				s.RegisterHealthCheck("db", failing, core.HealthCheckOptions{Critical: true})
+			},
+			Target: "/readiness",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusServiceUnavailable)
+				report := decodeHealth(t, res)
+				db := report.Components["db"]
+				if report.Status != core.HealthStatusUnavailable || db.Status != core.HealthStatusDown || db.Error != "connection refused" || !db.Critical {
+					t.Fatalf("report = %+v", report)
+				}
+				if report.Components["cache"].Status != core.HealthStatusUp {
+					t.Fatalf("cache = %+v", report.Components["cache"])
+				}
+			},
+		},
+		{
+			Name: "HealthCheckNonCriticalDown",
+			Setup: func(t *testing.T, s core.Server) {
+				s.HealthCheck()
+				s.RegisterHealthCheck("search", failing)
+			},
+			Target: "/readiness",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusOK)
+				if report := decodeHealth(t, res); report.Status != core.HealthStatusDegraded {
+					t.Fatalf("status = %q", report.Status)
+				}
+			},
+		},
+		{
+			Name: "HealthCheckTimeout",
+			Setup: func(t *testing.T, s core.Server) {
+				s.HealthCheck()
+				s.RegisterHealthCheck("slow", core.HealthCheckerFunc(func(ctx context.Context) error {
+					time.Sleep(time.Second)
+					return nil
+				}), core.HealthCheckOptions{Critical: true, Timeout: 10 * time.Millisecond})
+			},
+			Target: "/readiness",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusServiceUnavailable)
+				if slow := decodeHealth(t, res).Components["slow"]; !strings.HasPrefix(slow.Error, "timed out") {
+					t.Fatalf("slow = %+v", slow)
+				}
+			},
+		},
+		{
+			Name: "HealthCheckCached",
+			Setup: func(t *testing.T, s core.Server) {
+				calls := 0
+				s.HealthCheck()
+				s.RegisterHealthCheck("db", core.HealthCheckerFunc(func(ctx context.Context) error {
+					calls++
+					if calls > 1 {
+						return errors.New("checked twice")
+					}
+					return nil
+				}), core.HealthCheckOptions{Critical: true, CacheTTL: time.Minute})
+				for i := 0; i < 2; i++ {
+					if res := Do(s, NewRequest(core.MethodGet, "/readiness", "", nil)); res.Code != core.StatusOK {
+						t.Fatalf("warm-up status = %d", res.Code)
+					}
+				}
+			},
+			Target: "/readiness",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusOK)
+			},
+		},
+		{
+			Name: "HealthCheckLivenessScope",
+			Setup: func(t *testing.T, s core.Server) {
+				s.HealthCheck()
+				s.RegisterHealthCheck("db", failing, core.HealthCheckOptions{Critical: true})
+				s.RegisterHealthCheck("deadlock", ok, core.HealthCheckOptions{Critical: true, Liveness: true})
+			},
+			Target: "/liveness",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusOK)
+				report := decodeHealth(t, res)
+				if report.Status != core.HealthStatusAlive || len(report.Components) != 1 {
+					t.Fatalf("report = %+v", report)
+				}
+			},
+		},
+	}
+}
+
+// adminCases check the admin control plane on the public and admin listeners.
+func adminCases() []Case {
+	const loopback = "127.0.0.1:40000"
+	adminListener := func(cfg *core.Config) {
+		cfg.Admin = core.AdminConfig{Host: "localhost", Port: "0", Token: "s3cret"}
+	}
+	return []Case{
+		{
+			Name: "AdminPublicControlNeedsCredentials",
+			Setup: func(t *testing.T, s core.Server) {
+				s.HealthCheck()
+			},
+			Method:     core.MethodPost,
+			Target:     "/terminate",
+			RemoteAddr: loopback,
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusNotFound)
+			},
+		},
+		{
+			Name:   "AdminPublicTokenRequired",
+			Config: func(cfg *core.Config) { cfg.Admin.Token = "s3cret" },
+			Setup: func(t *testing.T, s core.Server) {
+				s.HealthCheck()
+			},
+			Method:     core.MethodPost,
+			Target:     "/terminate",
+			RemoteAddr: loopback,
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusUnauthorized)
+			},
+		},
+		{
+			Name:   "AdminPublicRoutesToken",
+			Config: func(cfg *core.Config) { cfg.Admin.Token = "s3cret" },
+			Setup: func(t *testing.T, s core.Server) {
+				s.HealthCheck()
+				s.Add(core.MethodGet, "/users/:id", func(c core.Context) {})
+			},
+			Target:  "/routes",
+			Headers: map[string]string{core.HeaderAdminToken: "s3cret"},
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusOK)
+				if !strings.Contains(res.Body.String(), `{"method":"GET","path":"/api/users/:id"}`) {
+					t.Fatalf("routes = %s", res.Body.String())
+				}
+			},
+		},
+		{
+			Name: "AdminPublicUseAdmin",
+			Setup: func(t *testing.T, s core.Server) {
+				s.UseAdmin(func(c core.Context) {
+					if c.Header("X-Role") != "ops" {
+						c.Error(core.NewHTTPError(core.StatusForbidden, ""))
+						return
+					}
+					c.Next()
+				})
+				s.HealthCheck()
+			},
+			Target:     "/log-level",
+			Headers:    map[string]string{"X-Role": "ops"},
+			RemoteAddr: "10.0.0.1:40000",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusOK)
+				ExpectBody(t, res, `{"level":"INFO"}`)
+			},
+		},
+		{
+			Name:   "AdminPublicDisabled",
+			Config: func(cfg *core.Config) { cfg.Admin.DisablePublic = true },
+			Setup: func(t *testing.T, s core.Server) {
+				s.HealthCheck()
+				s.Add(core.MethodGet, "/exists", func(c core.Context) {})
+			},
+			Target:     "/readiness",
+			RemoteAddr: loopback,
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusNotFound)
+			},
+		},
+		{
+			Name:   "AdminListenerMovesControlRoutes",
+			Config: adminListener,
+			Setup: func(t *testing.T, s core.Server) {
+				s.HealthCheck()
+			},
+			Method:     core.MethodPost,
+			Target:     "/terminate",
+			RemoteAddr: loopback,
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusNotFound)
+			},
+		},
+		{
+			Name:   "AdminListenerProbe",
+			Config: adminListener,
+			Target: "/readiness",
+			Admin:  true,
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusOK)
+				ExpectBody(t, res, `{"status":"ready"}`)
+			},
+		},
+		{
+			Name:   "AdminListenerTokenRequired",
+			Config: adminListener,
+			Target: "/log-level",
+			Admin:  true,
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusUnauthorized)
+				ExpectHeader(t, res, core.HeaderContentType, core.MIMEApplicationProblemJSON)
+			},
+		},
+		{
+			Name:    "AdminListenerToken",
+			Config:  adminListener,
+			Target:  "/log-level",
+			Headers: map[string]string{core.HeaderAuthorization: "Bearer s3cret"},
+			Admin:   true,
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusOK)
+				ExpectBody(t, res, `{"level":"INFO"}`)
+			},
+		},
+		{
+			Name:   "AdminListenerUseAdmin",
+			Config: adminListener,
+			Setup: func(t *testing.T, s core.Server) {
+				s.UseAdmin(func(c core.Context) {
+					if c.Header("X-Role") != "ops" {
+						c.Error(core.NewHTTPError(core.StatusForbidden, ""))
+					}
+				})
+			},
+			Target:  "/routes",
+			Headers: map[string]string{core.HeaderAdminToken: "s3cret"},
+			Admin:   true,
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusForbidden)
+			},
+		},
+	}
+}
+
+// lifecycleCases check hooks and core.Run on a real listener.
+func lifecycleCases() []Case {
+	return []Case{
+		{
+			Name: "LifecycleNotReady",
+			Setup: func(t *testing.T, s core.Server) {
+				s.HealthCheck()
+				s.RegisterHealthCheck("db", core.HealthCheckerFunc(func(ctx context.Context) error {
+					t.Error("checks must not run while draining")
+					return nil
+				}))
+				s.SetReady(false)
+			},
+			Target: "/readiness",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusServiceUnavailable)
+				ExpectBody(t, res, `{"status":"draining"}`)
+			},
+		},
+		{
+			Name: "LifecycleStartHookError",
+			Setup: func(t *testing.T, s core.Server) {
+				s.OnStart(func(ctx context.Context) error { return errors.New("no database") })
+				if err := s.Start(); err == nil || !strings.Contains(err.Error(), "no database") {
+					t.Fatalf("Start() = %v", err)
+				}
+			},
+			Target: "/missing",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusNotFound)
+			},
+		},
+		{
+			Name: "LifecycleRun",
+			Setup: func(t *testing.T, s core.Server) {
+				s.HealthCheck()
+				started := make(chan struct{})
+				var order []string
+				s.OnStart(func(ctx context.Context) error {
+					close(started)
+					return nil
+				})
+				s.OnShutdown(func(ctx context.Context) error {
+					order = append(order, "db")
+					return nil
+				}, func(ctx context.Context) error {
+					order = append(order, "cache")
+					return errors.New("cache flush failed")
+				})
+
+				done := make(chan error, 1)
+				go func() {
+					done <- core.Run(s, core.RunOptions{Signals: []os.Signal{os.Interrupt}, ShutdownTimeout: 5 * time.Second})
+				}()
+				<-started
+				time.Sleep(100 * time.Millisecond)
+				p, _ := os.FindProcess(os.Getpid())
+				if err := p.Signal(os.Interrupt); err != nil {
+					t.Skipf("cannot signal the test process: %v", err)
+				}
+				select {
+				case err := <-done:
+					if err == nil || !strings.Contains(err.Error(), "cache flush failed") {
+						t.Fatalf("Run() = %v, want the hook error", err)
+					}
+				case <-time.After(10 * time.Second):
+					t.Fatal("Run did not return after the signal")
+				}
+				if strings.Join(order, ",") != "cache,db" {
+					t.Fatalf("shutdown hooks ran as %v, want reverse order", order)
+				}
+			},
+			Target: "/readiness",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusServiceUnavailable)
+			},
+		},
+	}
+}
+
+// startServer starts s in the background and waits until it accepts
+// connections on addr; the server is shut down when the test ends. It returns
+// the error of Start when the server stops before listening.
+func startServer(t *testing.T, s core.Server, addr string) error {
+	t.Helper()
+	done := make(chan error, 1)
+	go func() { done <- s.Start() }()
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = s.Shutdown(ctx)
+	})
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		select {
+		case err := <-done:
+			return err
+		default:
+		}
+		conn, err := net.DialTimeout("tcp", addr, 100*time.Millisecond)
+		if err == nil {
+			conn.Close()
+			return nil
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("server did not listen on %s: %v", addr, err)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// getTLS sends GET url on a new connection and returns the body and the
+// serial number of the server certificate.
+func getTLS(conf *tls.Config, url string) (string, *big.Int, error) {
+	client := &http.Client{
+		Timeout:   5 * time.Second,
+		Transport: &http.Transport{TLSClientConfig: conf, DisableKeepAlives: true},
+	}
+	res, err := client.Get(url)
+	if err != nil {
+		return "", nil, err
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return "", nil, err
+	}
+	return string(body), res.TLS.PeerCertificates[0].SerialNumber, nil
+}
+
+func tlsCases() []Case {
+	var dir, port string
+	mtls := func(cfg *core.Config) {
+		var err error
+		if dir, err = os.MkdirTemp("", "conformance-tls"); err != nil {
+			panic(err)
+		}
+		if port, err = FreePort(); err != nil {
+			panic(err)
+		}
+		cfg.Host, cfg.Port = "127.0.0.1", port
+		cfg.TLS = core.TLSConfig{
+			CertFile:       filepath.Join(dir, "server.pem"),
+			KeyFile:        filepath.Join(dir, "server-key.pem"),
+			ClientCAFile:   filepath.Join(dir, "ca.pem"),
+			ReloadInterval: 50 * time.Millisecond,
+		}
+	}
+	whoami := func(c core.Context) {
+		if cert := c.ClientCertificate(); cert != nil {
+			c.String(core.StatusOK, cert.Subject.CommonName)
+			return
+		}
+		c.String(core.StatusOK, "anonymous")
+	}
+
+	return []Case{
+		{
+			Name:   "TLSMutualAndReload",
+			Config: mtls,
+			Setup: func(t *testing.T, s core.Server) {
+				t.Cleanup(func() { os.RemoveAll(dir) })
+				pki, err := NewPKI(dir)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if _, _, err := pki.Issue("server", "localhost", true); err != nil {
+					t.Fatal(err)
+				}
+				clientCert, clientKey, err := pki.Issue("client", "client-a", false)
+				if err != nil {
+					t.Fatal(err)
+				}
+				s.Add(core.MethodGet, "/whoami", whoami)
+				addr := "127.0.0.1:" + port
+				if err := startServer(t, s, addr); err != nil {
+					t.Fatalf("Start() = %v", err)
+				}
+				url := "https://" + addr + RootPath + "/whoami"
+
+				client, err := pki.ClientConfig(clientCert, clientKey)
+				if err != nil {
+					t.Fatal(err)
+				}
+				body, serial, err := getTLS(client, url)
+				if err != nil || body != "client-a" {
+					t.Fatalf("mTLS GET = %q, %v; want client-a", body, err)
+				}
+
+				anonymous, _ := pki.ClientConfig("", "")
+				if body, _, err := getTLS(anonymous, url); err == nil {
+					t.Fatalf("GET without a client certificate = %q, want a handshake error", body)
+				}
+
+				// Certificate mới phải được phục vụ mà không cần restart
+				if _, _, err := pki.Issue("server", "localhost", true); err != nil {
+					t.Fatal(err)
+				}
+				later := time.Now().Add(time.Minute)
+				for _, f := range []string{"server.pem", "server-key.pem"} {
+					if err := os.Chtimes(filepath.Join(dir, f), later, later); err != nil {
+						t.Fatal(err)
+					}
+				}
+				time.Sleep(100 * time.Millisecond)
+				body, rotated, err := getTLS(client, url)
+				if err != nil || body != "client-a" {
+					t.Fatalf("GET after rotation = %q, %v", body, err)
+				}
+				if rotated.Cmp(serial) == 0 {
+					t.Fatal("server still presents the old certificate after rotation")
+				}
+			},
+			Target: "/api/whoami",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusOK)
+				ExpectBody(t, res, "anonymous")
+			},
+		},
+		{
+			Name: "TLSInvalidConfig",
+			Config: func(cfg *core.Config) {
+				cfg.TLS = core.TLSConfig{
+					CertFile:     "server.pem",
+					KeyFile:      "server-key.pem",
+					CipherSuites: []string{"TLS_RSA_WITH_RC4_128_SHA"},
+				}
+			},
+			Setup: func(t *testing.T, s core.Server) {
+				if err := s.Start(); err == nil || !strings.Contains(err.Error(), "cipher suite") {
+					t.Fatalf("Start() = %v, want a cipher suite error", err)
+				}
+			},
+			Target: "/missing",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusNotFound)
+			},
+		},
+		{
+			// Cấu hình từ env bật được reload certificate như cấu hình từ viper
+			Name: "TLSReloadIntervalFromEnv",
+			Setup: func(t *testing.T, s core.Server) {
+				t.Setenv("SERVER_TLS_CERT_FILE", "server.pem")
+				t.Setenv("SERVER_TLS_RELOAD_INTERVAL", "30s")
+				if got := core.NewConfig().TLS; got.CertFile != "server.pem" || got.ReloadInterval != 30*time.Second {
+					t.Fatalf("NewConfig().TLS = %+v, want a 30s reload interval", got)
+				}
+			},
+			Target: "/missing",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusNotFound)
+			},
+		},
+	}
+}
+
+func protocolCases() []Case {
+	var port string
+	return []Case{
+		{
+			Name: "ProtocolHTTP1",
+			Setup: func(t *testing.T, s core.Server) {
+				s.Add(core.MethodGet, "/proto", func(c core.Context) {
+					c.String(core.StatusOK, c.Protocol())
+				})
+			},
+			Target: "/api/proto",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusOK)
+				ExpectBody(t, res, "HTTP/1.1")
+			},
+		},
+		{
+			// h2c chạy trên engine net/http, engine khác phải báo lỗi rõ ràng
+			Name: "ProtocolH2C",
+			Config: func(cfg *core.Config) {
+				var err error
+				if port, err = FreePort(); err != nil {
+					panic(err)
+				}
+				cfg.Host, cfg.Port = "127.0.0.1", port
+				cfg.Protocols = []string{core.ProtocolHTTP1, core.ProtocolH2C}
+				cfg.Timeouts.Idle = 200 * time.Millisecond
+			},
+			Setup: func(t *testing.T, s core.Server) {
+				s.Add(core.MethodGet, "/proto", func(c core.Context) {
+					c.String(core.StatusOK, c.Protocol())
+				})
+				addr := "127.0.0.1:" + port
+				if err := startServer(t, s, addr); err != nil {
+					var unsupported *core.UnsupportedProtocolError
+					if !errors.As(err, &unsupported) || unsupported.Protocol != core.ProtocolH2C {
+						t.Fatalf("Start() = %v, want h2c served or UnsupportedProtocolError", err)
+					}
+					return
+				}
+
+				// Client HTTP/2 prior knowledge trên TCP thường
+				client := &http.Client{Timeout: 5 * time.Second, Transport: &http2.Transport{
+					AllowHTTP: true,
+					DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+						return (&net.Dialer{}).DialContext(ctx, network, addr)
+					},
+				}}
+				res, err := client.Get("http://" + addr + RootPath + "/proto")
+				if err != nil {
+					t.Fatalf("h2c GET: %v", err)
+				}
+				defer res.Body.Close()
+				body, _ := io.ReadAll(res.Body)
+				if res.ProtoMajor != 2 || string(body) != "HTTP/2.0" {
+					t.Fatalf("h2c GET = %s %q, want HTTP/2.0", res.Proto, body)
+				}
+
+				// Timeouts.Idle cũng áp dụng cho kết nối h2c: server gửi GOAWAY rồi đóng
+				conn, err := net.Dial("tcp", addr)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer conn.Close()
+				_ = conn.SetDeadline(time.Now().Add(3 * time.Second))
+				_, _ = io.WriteString(conn, http2.ClientPreface)
+				framer := http2.NewFramer(conn, conn)
+				_ = framer.WriteSettings()
+				for {
+					frame, err := framer.ReadFrame()
+					if errors.Is(err, io.EOF) {
+						break
+					}
+					if err != nil {
+						t.Fatalf("idle h2c connection not closed: %v", err)
+					}
+					if _, ok := frame.(*http2.GoAwayFrame); ok {
+						break
+					}
+				}
+			},
+			Target: "/api/proto",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusOK)
+			},
+		},
+		{
+			Name: "ProtocolUnsupported",
+			Config: func(cfg *core.Config) {
+				cfg.Protocols = []string{core.ProtocolHTTP3}
+			},
+			Setup: func(t *testing.T, s core.Server) {
+				var unsupported *core.UnsupportedProtocolError
+				if err := s.Start(); !errors.As(err, &unsupported) || unsupported.Protocol != core.ProtocolHTTP3 {
+					t.Fatalf("Start() = %v, want UnsupportedProtocolError for h3", err)
+				}
+			},
+			Target: "/missing",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusNotFound)
+			},
+		},
+	}
+}
+
+// getBody sends GET url with client and returns the body.
+func getBody(client *http.Client, url string) (string, error) {
+	res, err := client.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	return string(body), err
+}
+
+func listenerCases() []Case {
+	var dir, port string
+	pong := func(c core.Context) {
+		c.String(core.StatusOK, "pong")
+	}
+	return []Case{
+		{
+			Name: "ListenerTCPAndUnix",
+			Config: func(cfg *core.Config) {
+				var err error
+				if dir, err = os.MkdirTemp("", "conformance"); err != nil {
+					panic(err)
+				}
+				if port, err = FreePort(); err != nil {
+					panic(err)
+				}
+				cfg.Listeners = []string{
+					"tcp://127.0.0.1:" + port,
+					"unix://" + filepath.Join(dir, "app.sock") + "?mode=0660",
+				}
+			},
+			Setup: func(t *testing.T, s core.Server) {
+				t.Cleanup(func() { os.RemoveAll(dir) })
+				s.Add(core.MethodGet, "/pong", pong)
+				if err := startServer(t, s, "127.0.0.1:"+port); err != nil {
+					t.Fatalf("Start() = %v", err)
+				}
+				client := &http.Client{Timeout: 5 * time.Second}
+				if body, err := getBody(client, "http://127.0.0.1:"+port+RootPath+"/pong"); err != nil || body != "pong" {
+					t.Fatalf("tcp GET = %q, %v", body, err)
+				}
+
+				sock := filepath.Join(dir, "app.sock")
+				info, err := os.Stat(sock)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if info.Mode().Perm() != 0o660 {
+					t.Fatalf("socket mode = %v, want 0660", info.Mode().Perm())
+				}
+				unixClient := &http.Client{Timeout: 5 * time.Second, Transport: &http.Transport{
+					DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+						return (&net.Dialer{}).DialContext(ctx, "unix", sock)
+					},
+				}}
+				if body, err := getBody(unixClient, "http://unix"+RootPath+"/pong"); err != nil || body != "pong" {
+					t.Fatalf("unix GET = %q, %v", body, err)
+				}
+			},
+			Target: "/api/pong",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusOK)
+			},
+		},
+		{
+			// Listener kế thừa qua fd, như systemd socket activation
+			Name: "ListenerFD",
+			Config: func(cfg *core.Config) {
+				ln, err := net.Listen("tcp", "127.0.0.1:0")
+				if err != nil {
+					panic(err)
+				}
+				defer ln.Close()
+				_, port, _ = net.SplitHostPort(ln.Addr().String())
+				fd, err := InheritFD(ln.(*net.TCPListener))
+				if err != nil {
+					port = ""
+					return
+				}
+				cfg.Listeners = []string{"fd://" + strconv.Itoa(fd)}
+			},
+			Setup: func(t *testing.T, s core.Server) {
+				if port == "" {
+					t.Skip("inherited file descriptors are not supported")
+				}
+				s.Add(core.MethodGet, "/pong", pong)
+				if err := startServer(t, s, "127.0.0.1:"+port); err != nil {
+					t.Fatalf("Start() = %v", err)
+				}
+				client := &http.Client{Timeout: 5 * time.Second}
+				if body, err := getBody(client, "http://127.0.0.1:"+port+RootPath+"/pong"); err != nil || body != "pong" {
+					t.Fatalf("fd GET = %q, %v", body, err)
+				}
+			},
+			Target: "/api/pong",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusOK)
+			},
+		},
+		{
+			Name: "ListenerServe",
+			Setup: func(t *testing.T, s core.Server) {
+				s.Add(core.MethodGet, "/pong", pong)
+				ln, err := net.Listen("tcp", "127.0.0.1:0")
+				if err != nil {
+					t.Fatal(err)
+				}
+				go s.Serve(ln)
+				t.Cleanup(func() {
+					ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+					defer cancel()
+					_ = s.Shutdown(ctx)
+				})
+				client := &http.Client{Timeout: 5 * time.Second}
+				if body, err := getBody(client, "http://"+ln.Addr().String()+RootPath+"/pong"); err != nil || body != "pong" {
+					t.Fatalf("Serve GET = %q, %v", body, err)
+				}
+			},
+			Target: "/api/pong",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusOK)
+			},
+		},
+		{
+			Name: "ListenerInvalid",
+			Config: func(cfg *core.Config) {
+				cfg.Listeners = []string{"udp://127.0.0.1:0"}
+			},
+			Setup: func(t *testing.T, s core.Server) {
+				if err := s.Start(); err == nil || !strings.Contains(err.Error(), "unsupported scheme") {
+					t.Fatalf("Start() = %v, want an unsupported scheme error", err)
+				}
+			},
+			Target: "/missing",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusNotFound)
+			},
+		},
+	}
+}
+
+func limitCases() []Case {
+	var port string
+	listen := func(adjust func(cfg *core.Config)) func(cfg *core.Config) {
+		return func(cfg *core.Config) {
+			var err error
+			if port, err = FreePort(); err != nil {
+				panic(err)
+			}
+			cfg.Host, cfg.Port = "127.0.0.1", port
+			adjust(cfg)
+		}
+	}
+	pong := func(c core.Context) {
+		c.String(core.StatusOK, "pong")
+	}
+	request := "GET " + RootPath + "/pong HTTP/1.1\r\nHost: localhost\r\n\r\n"
+
+	return []Case{
+		{
+			Name: "LimitReadHeaderTimeout",
+			Config: listen(func(cfg *core.Config) {
+				cfg.Timeouts.ReadHeader = 200 * time.Millisecond
+			}),
+			Setup: func(t *testing.T, s core.Server) {
+				addr := "127.0.0.1:" + port
+				if err := startServer(t, s, addr); err != nil {
+					t.Fatalf("Start() = %v", err)
+				}
+				// Slowloris: header không bao giờ gửi xong
+				conn, err := net.Dial("tcp", addr)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer conn.Close()
+				if _, err := conn.Write([]byte("GET " + RootPath + "/pong HTTP/1.1\r\nHost: localhost\r\n")); err != nil {
+					t.Fatal(err)
+				}
+				_ = conn.SetReadDeadline(time.Now().Add(3 * time.Second))
+				if _, err := io.ReadAll(conn); err != nil {
+					t.Fatalf("connection with a stalled header still open: %v", err)
+				}
+			},
+			Target: "/missing",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusNotFound)
+			},
+		},
+		{
+			Name: "LimitMaxConnections",
+			Config: listen(func(cfg *core.Config) {
+				cfg.Limits.MaxConnections = 1
+			}),
+			Setup: func(t *testing.T, s core.Server) {
+				s.Add(core.MethodGet, "/pong", pong)
+				addr := "127.0.0.1:" + port
+				if err := startServer(t, s, addr); err != nil {
+					t.Fatalf("Start() = %v", err)
+				}
+				// startServer đã mở rồi đóng một connection thử, chờ slot được trả
+				time.Sleep(100 * time.Millisecond)
+				first, err := net.Dial("tcp", addr)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer first.Close()
+				if _, err := first.Write([]byte(request)); err != nil {
+					t.Fatal(err)
+				}
+				_ = first.SetReadDeadline(time.Now().Add(3 * time.Second))
+				if _, err := first.Read(make([]byte, 512)); err != nil {
+					t.Fatalf("first connection: %v", err)
+				}
+
+				second, err := net.Dial("tcp", addr)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer second.Close()
+				if _, err := second.Write([]byte(request)); err != nil {
+					t.Fatal(err)
+				}
+				// Engine net/http để connection chờ, fiber trả 503; không engine nào được trả 200
+				_ = second.SetReadDeadline(time.Now().Add(300 * time.Millisecond))
+				buf := make([]byte, 512)
+				if n, _ := second.Read(buf); strings.Contains(string(buf[:n]), " 200 ") {
+					t.Fatalf("second connection served over the limit: %q", buf[:n])
+				}
+			},
+			Target: "/api/pong",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusOK)
+			},
+		},
+		{
+			Name: "LimitMaxHeaderBytes",
+			Config: listen(func(cfg *core.Config) {
+				cfg.Limits.MaxHeaderBytes = 1024
+			}),
+			Setup: func(t *testing.T, s core.Server) {
+				s.Add(core.MethodGet, "/pong", pong)
+				addr := "127.0.0.1:" + port
+				if err := startServer(t, s, addr); err != nil {
+					t.Fatalf("Start() = %v", err)
+				}
+				req, _ := http.NewRequest(http.MethodGet, "http://"+addr+RootPath+"/pong", nil)
+				req.Header.Set("X-Large", strings.Repeat("a", 16<<10))
+				res, err := (&http.Client{Timeout: 5 * time.Second}).Do(req)
+				if err != nil {
+					// Một số engine đóng connection thay vì trả lỗi
+					return
+				}
+				res.Body.Close()
+				if res.StatusCode < 400 {
+					t.Fatalf("status = %d for a 16 KB header, want an error", res.StatusCode)
+				}
+			},
+			Target: "/api/pong",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusOK)
+			},
+		},
+	}
+}
+
+type ctxKey struct{}
+
+func timeoutCases() []Case {
+	expectTimeout := func(t *testing.T, res *httptest.ResponseRecorder) {
+		ExpectStatus(t, res, core.StatusServiceUnavailable)
+		ExpectHeader(t, res, core.HeaderContentType, core.MIMEApplicationProblemJSON)
+		var problem core.HTTPError
+		if err := json.Unmarshal(res.Body.Bytes(), &problem); err != nil || problem.Detail != "request timed out" {
+			t.Fatalf("body = %q, want the timeout problem", res.Body.String())
+		}
+	}
+	return []Case{
+		{
+			Name: "TimeoutAnnotation",
+			Setup: func(t *testing.T, s core.Server) {
+				s.RegisterHandlersWithTags(&TagHandler{})
+			},
+			Target: RootPath + "/tags/wait",
+			Check:  expectTimeout,
+		},
+		{
+			Name: "TimeoutAnnotationDevMode",
+			Mode: "debug",
+			Setup: func(t *testing.T, s core.Server) {
+				s.RegisterHandlersWithTags(&DevTagHandler{})
+			},
+			Target: RootPath + "/dev/wait",
+			Check:  expectTimeout,
+		},
+		{
+			Name: "TimeoutHandlerError",
+			Setup: func(t *testing.T, s core.Server) {
+				s.Add(core.MethodGet, "/err", func(c core.Context) {
+					<-c.Context().Done()
+					c.Error(c.Context().Err())
+				}, core.Timeout(20*time.Millisecond))
+			},
+			Target: RootPath + "/err",
+			Check:  expectTimeout,
+		},
+		{
+			// Set sau Timeout không được làm mất deadline của context
+			Name: "TimeoutSurvivesSet",
+			Setup: func(t *testing.T, s core.Server) {
+				s.Add(core.MethodGet, "/set", func(c core.Context) {
+					c.Set("user", "bob")
+					<-c.Context().Done()
+					c.Error(c.Context().Err())
+				}, core.Timeout(20*time.Millisecond))
+			},
+			Target: RootPath + "/set",
+			Check:  expectTimeout,
+		},
+		{
+			Name: "TimeoutAfterWrite",
+			Setup: func(t *testing.T, s core.Server) {
+				s.Add(core.MethodGet, "/written", func(c core.Context) {
+					_ = c.String(core.StatusOK, "done")
+					<-c.Context().Done()
+				}, core.Timeout(20*time.Millisecond))
+			},
+			Target: RootPath + "/written",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusOK)
+				ExpectBody(t, res, "done")
+			},
+		},
+		{
+			// Status chưa ghi response nên vẫn nhận 503
+			Name: "TimeoutAfterStatus",
+			Setup: func(t *testing.T, s core.Server) {
+				s.Add(core.MethodGet, "/status", func(c core.Context) {
+					c.Status(core.StatusCreated)
+					<-c.Context().Done()
+				}, core.Timeout(20*time.Millisecond))
+			},
+			Target: RootPath + "/status",
+			Check:  expectTimeout,
+		},
+		{
+			// Timeout của route thay thế timeout global, kể cả khi dài hơn
+			Name: "TimeoutRouteOverridesGlobal",
+			Setup: func(t *testing.T, s core.Server) {
+				s.Use(core.Timeout(10 * time.Millisecond))
+				s.Add(core.MethodGet, "/slow", func(c core.Context) {
+					time.Sleep(50 * time.Millisecond)
+					if err := c.Context().Err(); err != nil {
+						c.Error(err)
+						return
+					}
+					_ = c.String(core.StatusOK, "ok")
+				}, core.Timeout(time.Second))
+			},
+			Target: RootPath + "/slow",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusOK)
+				ExpectBody(t, res, "ok")
+			},
+		},
+		{
+			// Giá trị middleware đặt giữa Timeout global và Timeout của route
+			// vẫn còn trong context của handler
+			Name: "TimeoutNestedKeepsValues",
+			Setup: func(t *testing.T, s core.Server) {
+				s.Use(core.Timeout(10 * time.Millisecond))
+				setUser := func(c core.Context) {
+					c.Set("user", "bob")
+					c.Next()
+				}
+				s.Add(core.MethodGet, "/user", func(c core.Context) {
+					time.Sleep(50 * time.Millisecond)
+					if err := c.Context().Err(); err != nil {
+						c.Error(err)
+						return
+					}
+					user, _ := c.Context().Value("user").(string)
+					_ = c.String(core.StatusOK, user)
+				}, setUser, core.Timeout(time.Second))
+			},
+			Target: RootPath + "/user",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusOK)
+				ExpectBody(t, res, "bob")
+			},
+		},
+		{
+			Name: "ContextPropagation",
+			Setup: func(t *testing.T, s core.Server) {
+				s.Use(func(c core.Context) {
+					c.SetContext(context.WithValue(c.Context(), ctxKey{}, "from-middleware"))
+					c.Next()
+				})
+				s.Add(core.MethodGet, "/ctx", func(c core.Context) {
+					_, deadline := c.Context().Deadline()
+					value, _ := c.Context().Value(ctxKey{}).(string)
+					_ = c.String(core.StatusOK, value+" "+strconv.FormatBool(deadline))
+				}, core.Timeout(time.Second))
+			},
+			Target: RootPath + "/ctx",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusOK)
+				ExpectBody(t, res, "from-middleware true")
+			},
+		},
+	}
+}
+
+func bodyCases() []Case {
+	echoBody := func(c core.Context) {
+		body, err := c.BodyBytes()
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		_ = c.String(core.StatusOK, string(body))
+	}
+	expectTooLarge := func(limit int64) func(t *testing.T, res *httptest.ResponseRecorder) {
+		return func(t *testing.T, res *httptest.ResponseRecorder) {
+			ExpectStatus(t, res, core.StatusRequestEntityTooLarge)
+			ExpectHeader(t, res, core.HeaderContentType, core.MIMEApplicationProblemJSON)
+			var problem struct {
+				Limit int64 `json:"limit"`
+			}
+			if err := json.Unmarshal(res.Body.Bytes(), &problem); err != nil || problem.Limit != limit {
+				t.Fatalf("body = %q, want a problem with limit %d", res.Body.String(), limit)
+			}
+		}
+	}
+	maxBody := func(n int64) func(cfg *core.Config) {
+		return func(cfg *core.Config) {
+			cfg.Limits.MaxBodyBytes = n
+		}
+	}
+	var port string
+	return []Case{
+		{
+			Name: "BodyBytes",
+			Setup: func(t *testing.T, s core.Server) {
+				s.Add(core.MethodPost, "/body", echoBody)
+			},
+			Method: core.MethodPost,
+			Target: RootPath + "/body",
+			Body:   `{"name":"bob"}`,
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusOK)
+				ExpectBody(t, res, `{"name":"bob"}`)
+			},
+		},
+		{
+			Name:   "BodyGlobalLimit",
+			Config: maxBody(8),
+			Setup: func(t *testing.T, s core.Server) {
+				s.Add(core.MethodPost, "/body", echoBody)
+			},
+			Method: core.MethodPost,
+			Target: RootPath + "/body",
+			Body:   `{"name":"bob"}`,
+			Check:  expectTooLarge(8),
+		},
+		{
+			Name:   "BodyLimitRouteOverridesGlobal",
+			Config: maxBody(8),
+			Setup: func(t *testing.T, s core.Server) {
+				s.Add(core.MethodPost, "/body", echoBody, core.BodyLimit(64))
+			},
+			Method: core.MethodPost,
+			Target: RootPath + "/body",
+			Body:   `{"name":"bob"}`,
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusOK)
+				ExpectBody(t, res, `{"name":"bob"}`)
+			},
+		},
+		{
+			// Body đã giới hạn, vượt giới hạn hay không, vẫn đóng được body gốc
+			Name: "BodyLimitReaderClose",
+			Setup: func(t *testing.T, s core.Server) {
+				s.Add(core.MethodPost, "/reader", func(c core.Context) {
+					for _, length := range []int64{4, 14} {
+						body := &closeTracker{Reader: strings.NewReader(`{"name":"bob"}`[:length])}
+						r, ok := core.LimitReader(c, body, length).(io.ReadCloser)
+						if !ok {
+							c.Error(fmt.Errorf("LimitReader of %d bytes is not an io.ReadCloser", length))
+							return
+						}
+						if err := r.Close(); err != nil || !body.closed {
+							c.Error(fmt.Errorf("Close of %d bytes = %v, body closed %t", length, err, body.closed))
+							return
+						}
+					}
+					_ = c.String(core.StatusOK, "closed")
+				}, core.BodyLimit(4))
+			},
+			Method: core.MethodPost,
+			Target: RootPath + "/reader",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusOK)
+				ExpectBody(t, res, "closed")
+			},
+		},
+		{
+			Name: "BodyLimitGroup",
+			Setup: func(t *testing.T, s core.Server) {
+				s.AddGroup("/uploads", func(rg core.RouterGroup) {
+					rg.Add(core.MethodPost, "/small", echoBody)
+				}, core.BodyLimit(4))
+			},
+			Method: core.MethodPost,
+			Target: RootPath + "/uploads/small",
+			Body:   `{"name":"bob"}`,
+			Check:  expectTooLarge(4),
+		},
+		{
+			// GET không có body và Content-Type: Bind chỉ lấy query rồi validate
+			Name: "BindGetWithoutBody",
+			Setup: func(t *testing.T, s core.Server) {
+				s.Add(core.MethodGet, "/search", func(c core.Context) {
+					var req struct {
+						Q string `form:"q" query:"q" json:"q"`
+					}
+					if err := c.Bind(&req); err != nil {
+						c.Error(err)
+						return
+					}
+					_ = c.String(core.StatusOK, "q="+req.Q)
+				})
+			},
+			Target: RootPath + "/search?q=go",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusOK)
+				ExpectBody(t, res, "q=go")
+			},
+		},
+		{
+			Name:   "BodyLimitBind",
+			Config: maxBody(8),
+			Setup: func(t *testing.T, s core.Server) {
+				s.Add(core.MethodPost, "/bind", func(c core.Context) {
+					var req struct {
+						Name string `json:"name"`
+					}
+					if err := c.Bind(&req); err != nil {
+						c.Error(err)
+						return
+					}
+					_ = c.String(core.StatusOK, req.Name)
+				})
+			},
+			Method: core.MethodPost,
+			Target: RootPath + "/bind",
+			Body:   `{"name":"bob"}`,
+			Check:  expectTooLarge(8),
+		},
+		{
+			// Body chunked (không có Content-Length) qua listener thật: body lớn
+			// được stream, body vượt giới hạn bị chặn khi đọc tới giới hạn
+			Name: "BodyStreamChunked",
+			Config: func(cfg *core.Config) {
+				var err error
+				if port, err = FreePort(); err != nil {
+					panic(err)
+				}
+				cfg.Host, cfg.Port = "127.0.0.1", port
+				cfg.Limits.MaxBodyBytes = 1 << 10
+			},
+			Setup: func(t *testing.T, s core.Server) {
+				count := func(c core.Context) {
+					n, err := io.Copy(io.Discard, c.Body())
+					if err != nil {
+						c.Error(err)
+						return
+					}
+					_ = c.String(core.StatusOK, strconv.FormatInt(n, 10))
+				}
+				s.Add(core.MethodPost, "/upload", count, core.BodyLimit(8<<20))
+				s.Add(core.MethodPost, "/small", count)
+				if err := startServer(t, s, "127.0.0.1:"+port); err != nil {
+					t.Fatalf("Start() = %v", err)
+				}
+
+				post := func(path string, size int) (int, string) {
+					// struct ẩn độ dài để client gửi chunked
+					body := struct{ io.Reader }{io.LimitReader(zeroReader{}, int64(size))}
+					res, err := http.Post("http://127.0.0.1:"+port+RootPath+path, "application/octet-stream", body)
+					if err != nil {
+						t.Fatalf("POST %s: %v", path, err)
+					}
+					defer res.Body.Close()
+					data, _ := io.ReadAll(res.Body)
+					return res.StatusCode, string(data)
+				}
+				if code, body := post("/upload", 4<<20); code != core.StatusOK || body != strconv.Itoa(4<<20) {
+					t.Fatalf("upload = %d %q, want 200 %d", code, body, 4<<20)
+				}
+				if code, body := post("/small", 4<<10); code != core.StatusRequestEntityTooLarge {
+					t.Fatalf("oversized chunked body = %d %q, want 413", code, body)
+				}
+			},
+			Target: "/missing",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusNotFound)
+			},
+		},
+	}
+}
+
+// closeTracker ghi nhận việc Close được gọi.
+type closeTracker struct {
+	io.Reader
+	closed bool
+}
+
+func (c *closeTracker) Close() error {
+	c.closed = true
+	return nil
+}
+
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
+
+// countingReader đếm số byte đã đọc từ r.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func multipartCases() []Case {
+	// pngHeader đủ để http.DetectContentType nhận ra image/png
+	pngHeader := "\x89PNG\r\n\x1a\n" + strings.Repeat("\x00", 24)
+	form := func(files map[string]string) (string, map[string]string) {
+		var buf bytes.Buffer
+		w := multipart.NewWriter(&buf)
+		_ = w.WriteField("name", "bob")
+		for name, content := range files {
+			fw, _ := w.CreateFormFile(name, name+".bin")
+			_, _ = fw.Write([]byte(content))
+		}
+		_ = w.Close()
+		return buf.String(), map[string]string{core.HeaderContentType: w.FormDataContentType()}
+	}
+	upload := func(c core.Context) {
+		file, err := c.FormFile("avatar")
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		_ = c.String(core.StatusOK, c.FormValue("name")+":"+file.Filename+":"+strconv.FormatInt(file.Size, 10))
+	}
+	allowPNG := func(cfg *core.Config) {
+		cfg.Multipart.AllowedTypes = []string{"image/*"}
+	}
+	pngBody, pngHeaders := form(map[string]string{"avatar": pngHeader})
+	textBody, textHeaders := form(map[string]string{"avatar": "plain text"})
+	emptyBody, emptyHeaders := form(nil)
+	twoBody, twoHeaders := form(map[string]string{"avatar": pngHeader, "banner": pngHeader})
+	var saved []byte
+	var port string
+
+	return []Case{
+		{
+			Name: "MultipartFormFile",
+			Setup: func(t *testing.T, s core.Server) {
+				s.Add(core.MethodPost, "/upload", upload)
+			},
+			Method:  core.MethodPost,
+			Target:  RootPath + "/upload",
+			Body:    pngBody,
+			Headers: pngHeaders,
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusOK)
+				ExpectBody(t, res, "bob:avatar.bin:32")
+			},
+		},
+		{
+			Name: "MultipartSaveUploadedFile",
+			Setup: func(t *testing.T, s core.Server) {
+				dir := t.TempDir()
+				s.Add(core.MethodPost, "/upload", func(c core.Context) {
+					form, err := c.MultipartForm()
+					if err != nil {
+						c.Error(err)
+						return
+					}
+					if err := c.SaveUploadedFile(form.File["avatar"][0], "avatar.png"); err != nil {
+						c.Error(err)
+						return
+					}
+					saved, _ = os.ReadFile(filepath.Join(dir, "avatar.png"))
+					c.Status(core.StatusCreated)
+				}, core.Multipart(core.MultipartConfig{Storage: core.DiskStorage{Dir: dir}}))
+			},
+			Method:  core.MethodPost,
+			Target:  RootPath + "/upload",
+			Body:    pngBody,
+			Headers: pngHeaders,
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusCreated)
+				if string(saved) != pngHeader {
+					t.Fatalf("saved file = %q, want the uploaded content", saved)
+				}
+			},
+		},
+		{
+			Name: "MultipartFormValueURLEncoded",
+			Setup: func(t *testing.T, s core.Server) {
+				s.Add(core.MethodPost, "/form", func(c core.Context) {
+					_ = c.String(core.StatusOK, c.FormValue("name")+","+c.FormValue("missing"))
+				})
+			},
+			Method:  core.MethodPost,
+			Target:  RootPath + "/form",
+			Body:    "name=bob&age=30",
+			Headers: map[string]string{core.HeaderContentType: core.MIMEApplicationForm},
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusOK)
+				ExpectBody(t, res, "bob,")
+			},
+		},
+		{
+			Name: "MultipartMissingFile",
+			Setup: func(t *testing.T, s core.Server) {
+				s.Add(core.MethodPost, "/upload", upload)
+			},
+			Method:  core.MethodPost,
+			Target:  RootPath + "/upload",
+			Body:    emptyBody,
+			Headers: emptyHeaders,
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusBadRequest)
+			},
+		},
+		{
+			Name: "MultipartFileTooLarge",
+			Config: func(cfg *core.Config) {
+				cfg.Multipart.MaxFileSize = 16
+			},
+			Setup: func(t *testing.T, s core.Server) {
+				s.Add(core.MethodPost, "/upload", upload)
+			},
+			Method:  core.MethodPost,
+			Target:  RootPath + "/upload",
+			Body:    pngBody,
+			Headers: pngHeaders,
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusRequestEntityTooLarge)
+			},
+		},
+		{
+			// Giới hạn được áp dụng trong lúc đọc: file lớn không bị đọc hết
+			// vào memory hay file tạm trước khi trả 413
+			Name: "MultipartFileTooLargeStopsReading",
+			Config: func(cfg *core.Config) {
+				var err error
+				if port, err = FreePort(); err != nil {
+					panic(err)
+				}
+				cfg.Host, cfg.Port = "127.0.0.1", port
+				cfg.Multipart.MaxFileSize = 1 << 10
+			},
+			Setup: func(t *testing.T, s core.Server) {
+				s.Add(core.MethodPost, "/upload", upload)
+				if err := startServer(t, s, "127.0.0.1:"+port); err != nil {
+					t.Fatalf("Start() = %v", err)
+				}
+				head := "--b\r\nContent-Disposition: form-data; name=\"avatar\"; filename=\"big.bin\"\r\n\r\n"
+				body := &countingReader{r: io.MultiReader(strings.NewReader(head), io.LimitReader(zeroReader{}, 256<<20), strings.NewReader("\r\n--b--\r\n"))}
+				// struct ẩn độ dài để client gửi chunked
+				res, err := http.Post("http://127.0.0.1:"+port+RootPath+"/upload", "multipart/form-data; boundary=b", struct{ io.Reader }{body})
+				if err == nil {
+					res.Body.Close()
+					if res.StatusCode != core.StatusRequestEntityTooLarge {
+						t.Fatalf("status = %d, want 413", res.StatusCode)
+					}
+				}
+				// Server có thể đóng connection trước khi client gửi xong; phần
+				// đã gửi chỉ gồm vài buffer của socket
+				if body.n > 32<<20 {
+					t.Fatalf("sent %d bytes of the body, want the server to stop near the limit", body.n)
+				}
+			},
+			Target: "/missing",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusNotFound)
+			},
+		},
+		{
+			Name:   "MultipartTypeNotAllowed",
+			Config: allowPNG,
+			Setup: func(t *testing.T, s core.Server) {
+				s.Add(core.MethodPost, "/upload", upload)
+			},
+			Method:  core.MethodPost,
+			Target:  RootPath + "/upload",
+			Body:    textBody,
+			Headers: textHeaders,
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusUnsupportedMediaType)
+				ExpectHeader(t, res, core.HeaderContentType, core.MIMEApplicationProblemJSON)
+			},
+		},
+		{
+			Name:   "MultipartTypeAllowedByRoute",
+			Config: allowPNG,
+			Setup: func(t *testing.T, s core.Server) {
+				s.Add(core.MethodPost, "/upload", upload, core.Multipart(core.MultipartConfig{
+					AllowedTypes: []string{core.MIMETextPlain},
+				}))
+			},
+			Method:  core.MethodPost,
+			Target:  RootPath + "/upload",
+			Body:    textBody,
+			Headers: textHeaders,
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusOK)
+				ExpectBody(t, res, "bob:avatar.bin:10")
+			},
+		},
+		{
+			Name: "MultipartBind",
+			Setup: func(t *testing.T, s core.Server) {
+				type person struct {
+					Name string   `form:"name" validate:"required"`
+					Age  int      `form:"age"`
+					Tags []string `form:"tag"`
+				}
+				s.Add(core.MethodPost, "/bind", func(c core.Context) {
+					var p person
+					if err := c.Bind(&p); err != nil {
+						c.Error(err)
+						return
+					}
+					_ = c.String(core.StatusOK, fmt.Sprint(p))
+				})
+				res := Do(s, NewRequest(core.MethodPost, RootPath+"/bind", "name=bob&age=30&tag=a&tag=b",
+					map[string]string{core.HeaderContentType: core.MIMEApplicationForm}))
+				ExpectStatus(t, res, core.StatusOK)
+				ExpectBody(t, res, "{bob 30 [a b]}")
+				res = Do(s, NewRequest(core.MethodPost, RootPath+"/bind", "age=30",
+					map[string]string{core.HeaderContentType: core.MIMEApplicationForm}))
+				ExpectStatus(t, res, core.StatusUnprocessableEntity)
+			},
+			Method:  core.MethodPost,
+			Target:  RootPath + "/bind",
+			Body:    pngBody,
+			Headers: pngHeaders,
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusOK)
+				ExpectBody(t, res, "{bob 0 []}")
+			},
+		},
+		{
+			Name: "MultipartStreamUploads",
+			Config: func(cfg *core.Config) {
+				cfg.Multipart.MaxTotalSize = 40
+				allowPNG(cfg)
+			},
+			Setup: func(t *testing.T, s core.Server) {
+				s.Add(core.MethodPost, "/stream", func(c core.Context) {
+					storage := &memoryStorage{}
+					values, files, err := core.StreamUploads(c, storage)
+					if err != nil {
+						c.Error(err)
+						return
+					}
+					_ = c.String(core.StatusOK, fmt.Sprintf("%s %d %s %s %d %d",
+						values.Get("name"), len(files), files[0].Field, files[0].ContentType, files[0].Size, len(storage.files[files[0].Location])))
+				})
+			},
+			Method:  core.MethodPost,
+			Target:  RootPath + "/stream",
+			Body:    pngBody,
+			Headers: pngHeaders,
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusOK)
+				ExpectBody(t, res, "bob 1 avatar image/png 32 32")
+			},
+		},
+		{
+			Name: "MultipartStreamUploadsDisk",
+			Setup: func(t *testing.T, s core.Server) {
+				dir := t.TempDir()
+				// File có sẵn trùng tên file của client không được bị ghi đè hay xoá
+				existing := filepath.Join(dir, "avatar.bin")
+				if err := os.WriteFile(existing, []byte("keep"), 0o600); err != nil {
+					t.Fatal(err)
+				}
+				s.Add(core.MethodPost, "/stream", func(c core.Context) {
+					_, files, err := core.StreamUploads(c, core.DiskStorage{Dir: dir})
+					if err != nil {
+						c.Error(err)
+						return
+					}
+					_ = c.String(core.StatusOK, files[0].Location)
+				})
+				s.Add(core.MethodPost, "/cwd", func(c core.Context) {
+					if _, _, err := core.StreamUploads(c, core.DiskStorage{}); err != nil {
+						c.Error(err)
+						return
+					}
+					c.Status(core.StatusOK)
+				})
+				locations := map[string]bool{}
+				for i := 0; i < 2; i++ {
+					res := Do(s, NewRequest(core.MethodPost, RootPath+"/stream", pngBody, pngHeaders))
+					ExpectStatus(t, res, core.StatusOK)
+					location := res.Body.String()
+					if filepath.Dir(location) != dir || filepath.Ext(location) != ".bin" || location == existing || locations[location] {
+						t.Fatalf("location = %q, want a new file in %s", location, dir)
+					}
+					if data, _ := os.ReadFile(location); string(data) != pngHeader {
+						t.Fatalf("saved file = %q, want the uploaded content", data)
+					}
+					locations[location] = true
+				}
+				if data, _ := os.ReadFile(existing); string(data) != "keep" {
+					t.Fatalf("existing file = %q, want it untouched", data)
+				}
+				if _, err := (core.DiskStorage{Dir: dir}).Save(context.Background(), "avatar.bin", strings.NewReader("new")); err == nil {
+					t.Fatal("Save overwrote an existing file")
+				}
+				if data, _ := os.ReadFile(existing); string(data) != "keep" {
+					t.Fatalf("existing file = %q after a failed Save", data)
+				}
+			},
+			Method:  core.MethodPost,
+			Target:  RootPath + "/cwd",
+			Body:    pngBody,
+			Headers: pngHeaders,
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusInternalServerError)
+			},
+		},
+		{
+			Name: "MultipartStreamTotalTooLarge",
+			Config: func(cfg *core.Config) {
+				cfg.Multipart.MaxTotalSize = 40
+			},
+			Setup: func(t *testing.T, s core.Server) {
+				s.Add(core.MethodPost, "/stream", func(c core.Context) {
+					if _, _, err := core.StreamUploads(c, &memoryStorage{}); err != nil {
+						c.Error(err)
+						return
+					}
+					c.Status(core.StatusOK)
+				})
+			},
+			Method:  core.MethodPost,
+			Target:  RootPath + "/stream",
+			Body:    twoBody,
+			Headers: twoHeaders,
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusRequestEntityTooLarge)
+			},
+		},
+	}
+}
+
+// memoryStorage là Storage giữ file trong memory, kiểm tra StreamUploads
+type memoryStorage struct {
+	files map[string][]byte
+}
+
+func (m *memoryStorage) Save(ctx context.Context, name string, r io.Reader) (string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	if m.files == nil {
+		m.files = make(map[string][]byte)
+	}
+	m.files[name] = data
+	return name, nil
+}
+
+func responseCases() []Case {
+	file := func(t *testing.T) string {
+		path := filepath.Join(t.TempDir(), "hello.txt")
+		if err := os.WriteFile(path, []byte("hello world"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	type user struct {
+		Name string `xml:"name"`
+	}
+
+	return []Case{
+		{
+			Name: "ResponseXML",
+			Setup: func(t *testing.T, s core.Server) {
+				s.Add(core.MethodGet, "/xml", func(c core.Context) {
+					_ = c.XML(core.StatusCreated, user{Name: "bob"})
+				})
+			},
+			Target: RootPath + "/xml",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusCreated)
+				ExpectHeader(t, res, core.HeaderContentType, core.MIMEApplicationXML)
+				ExpectBody(t, res, `<?xml version="1.0" encoding="UTF-8"?>`+"\n"+`<user><name>bob</name></user>`)
+			},
+		},
+		{
+			Name: "ResponseHTMLTemplate",
+			Setup: func(t *testing.T, s core.Server) {
+				s.SetHTMLTemplate(template.Must(template.New("hello").Parse(`<p>Hello {{.}}</p>`)))
+				s.Add(core.MethodGet, "/html", func(c core.Context) {
+					if err := c.HTML(core.StatusOK, "hello", "<bob>"); err != nil {
+						c.Error(err)
+					}
+				})
+			},
+			Target: RootPath + "/html",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusOK)
+				ExpectHeader(t, res, core.HeaderContentType, core.MIMETextHTMLCharsetUTF8)
+				ExpectBody(t, res, `<p>Hello &lt;bob&gt;</p>`)
+			},
+		},
+		{
+			Name: "ResponseHTMLWithoutTemplate",
+			Setup: func(t *testing.T, s core.Server) {
+				s.Add(core.MethodGet, "/html", func(c core.Context) {
+					if err := c.HTML(core.StatusOK, "hello", nil); err != nil {
+						c.Error(err)
+					}
+				})
+			},
+			Target: RootPath + "/html",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusInternalServerError)
+			},
+		},
+		{
+			Name: "ResponseBlob",
+			Setup: func(t *testing.T, s core.Server) {
+				s.Add(core.MethodGet, "/blob", func(c core.Context) {
+					_ = c.Blob(core.StatusOK, core.MIMEOctetStream, []byte{1, 2, 3})
+				})
+			},
+			Target: RootPath + "/blob",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusOK)
+				ExpectHeader(t, res, core.HeaderContentType, core.MIMEOctetStream)
+				ExpectBody(t, res, "\x01\x02\x03")
+			},
+		},
+		{
+			Name: "ResponseStream",
+			Setup: func(t *testing.T, s core.Server) {
+				s.Add(core.MethodGet, "/stream", func(c core.Context) {
+					_ = c.Stream(core.StatusAccepted, core.MIMETextPlainCharsetUTF8, strings.NewReader("streamed"))
+				})
+			},
+			Target: RootPath + "/stream",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusAccepted)
+				ExpectHeader(t, res, core.HeaderContentType, core.MIMETextPlain)
+				ExpectBody(t, res, "streamed")
+			},
+		},
+		{
+			Name: "ResponseFile",
+			Setup: func(t *testing.T, s core.Server) {
+				path := file(t)
+				s.Add(core.MethodGet, "/file", func(c core.Context) {
+					if err := c.File(path); err != nil {
+						c.Error(err)
+					}
+				})
+			},
+			Target: RootPath + "/file",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusOK)
+				ExpectHeader(t, res, core.HeaderContentType, core.MIMETextPlain)
+				ExpectBody(t, res, "hello world")
+			},
+		},
+		{
+			Name: "ResponseFileRange",
+			Setup: func(t *testing.T, s core.Server) {
+				path := file(t)
+				s.Add(core.MethodGet, "/file", func(c core.Context) {
+					if err := c.File(path); err != nil {
+						c.Error(err)
+					}
+				})
+			},
+			Target:  RootPath + "/file",
+			Headers: map[string]string{"Range": "bytes=0-4"},
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusPartialContent)
+				ExpectBody(t, res, "hello")
+			},
+		},
+		{
+			Name: "ResponseFileMissing",
+			Setup: func(t *testing.T, s core.Server) {
+				dir := t.TempDir()
+				s.Add(core.MethodGet, "/file", func(c core.Context) {
+					if err := c.File(filepath.Join(dir, "missing.txt")); err != nil {
+						c.Error(err)
+					}
+				})
+			},
+			Target: RootPath + "/file",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusNotFound)
+				ExpectHeader(t, res, core.HeaderContentType, core.MIMEApplicationProblemJSON)
+			},
+		},
+		{
+			Name: "ResponseAttachment",
+			Setup: func(t *testing.T, s core.Server) {
+				path := file(t)
+				s.Add(core.MethodGet, "/download", func(c core.Context) {
+					if err := c.Attachment(path, "résumé.txt"); err != nil {
+						c.Error(err)
+					}
+				})
+			},
+			Target: RootPath + "/download",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusOK)
+				ExpectHeader(t, res, core.HeaderContentDisposition, `attachment; filename*=utf-8''r%C3%A9sum%C3%A9.txt`)
+				ExpectBody(t, res, "hello world")
+			},
+		},
+		{
+			Name: "ResponseRedirect",
+			Setup: func(t *testing.T, s core.Server) {
+				s.Add(core.MethodGet, "/old", func(c core.Context) {
+					_ = c.Redirect(core.StatusMovedPermanently, "/new")
+				})
+			},
+			Target: RootPath + "/old",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusMovedPermanently)
+				ExpectHeader(t, res, core.HeaderLocation, "/new")
+			},
+		},
+		{
+			Name: "ResponseRedirectInvalidStatus",
+			Setup: func(t *testing.T, s core.Server) {
+				s.Add(core.MethodGet, "/old", func(c core.Context) {
+					if err := c.Redirect(core.StatusOK, "/new"); err != nil {
+						c.Error(err)
+					}
+				})
+			},
+			Target: RootPath + "/old",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusInternalServerError)
+				if got := res.Header().Get(core.HeaderLocation); got != "" {
+					t.Fatalf("Location = %q, want none", got)
+				}
+			},
+		},
+		{
+			Name: "ResponseNoContent",
+			Setup: func(t *testing.T, s core.Server) {
+				s.Add(core.MethodDelete, "/users/:id", func(c core.Context) {
+					_ = c.NoContent(core.StatusNoContent)
+				})
+			},
+			Method: core.MethodDelete,
+			Target: RootPath + "/users/1",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusNoContent)
+				ExpectBody(t, res, "")
+			},
+		},
+	}
+}
+
+type negotiateUser struct {
+	Name string `json:"name" xml:"name" yaml:"name" csv:"name" codec:"name"`
+	Age  int    `json:"age" xml:"age" yaml:"age" csv:"age" codec:"age"`
+}
+
+// generatedHandler có route như go-server-gen sinh ra, với kiểu Ghost không có trong source
+type generatedHandler struct{}
+
+func (generatedHandler) ApiRoutes() []core.RouteConfig {
+	return []core.RouteConfig{{
+		Method:  core.MethodGet,
+		Path:    "/ghosts",
+		Handler: func(c core.Context) { c.JSON(core.StatusOK, []string{}) },
+		Doc: &core.ParseRoute{
+			Path:     "/ghosts",
+			Method:   core.MethodGet,
+			Name:     "List",
+			Response: []core.ParseResponse{{Code: "200", Type: "[]Ghost"}},
+			Schemas: map[string]*core.Schema{
+				"Ghost": {Type: "object", Properties: map[string]*core.Schema{"name": {Type: "string"}}},
+			},
+		},
+	}}
+}
+
+// limitedUserHandler có route @RateLimit với middleware đặt user của riêng route
+type limitedUserHandler struct{}
+
+func (limitedUserHandler) ApiRoutes() []core.RouteConfig {
+	return []core.RouteConfig{{
+		Method: core.MethodGet,
+		Path:   "/me",
+		Handler: func(c core.Context) {
+			_ = c.String(core.StatusOK, c.GetString("user"))
+		},
+		Middleware: []core.Handler{func(c core.Context) {
+			c.Set("user", c.Header("X-User"))
+			c.Next()
+		}},
+		Doc: &core.ParseRoute{Path: "/me", Method: core.MethodGet, Name: "Me", RateLimit: "1/m"},
+	}}
+}
+
+// browserAcceptHeader là Accept mặc định của Chrome khi mở một URL
+const browserAcceptHeader = "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,*/*;q=0.8"
+
+// negotiateCases check Context.Negotiate and Bind through the codec registry.
+func negotiateCases() []Case {
+	bob := negotiateUser{Name: "bob", Age: 30}
+	offer := func(s core.Server) {
+		s.Add(core.MethodGet, "/user", func(c core.Context) {
+			if err := c.Negotiate(core.StatusOK, core.Offers([]negotiateUser{bob})); err != nil {
+				c.Error(err)
+			}
+		})
+	}
+	accept := func(name, value, contentType, body string) Case {
+		return Case{
+			Name:    name,
+			Setup:   func(t *testing.T, s core.Server) { offer(s) },
+			Target:  RootPath + "/user",
+			Headers: map[string]string{core.HeaderAccept: value},
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusOK)
+				ExpectHeader(t, res, core.HeaderContentType, contentType)
+				ExpectHeader(t, res, core.HeaderVary, core.HeaderAccept)
+				ExpectBody(t, res, body)
+			},
+		}
+	}
+	bind := func(name, contentType, body string) Case {
+		return Case{
+			Name: name,
+			Setup: func(t *testing.T, s core.Server) {
+				s.Add(core.MethodPost, "/users", func(c core.Context) {
+					var users []negotiateUser
+					if err := c.Bind(&users); err != nil {
+						c.Error(err)
+						return
+					}
+					_ = c.String(core.StatusOK, fmt.Sprint(users))
+				})
+			},
+			Method:  core.MethodPost,
+			Target:  RootPath + "/users",
+			Body:    body,
+			Headers: map[string]string{core.HeaderContentType: contentType},
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusOK)
+				ExpectBody(t, res, "[{bob 30}]")
+			},
+		}
+	}
+
+	return []Case{
+		accept("NegotiateDefaultJSON", "", core.MIMEApplicationJSONCharsetUTF8, `[{"name":"bob","age":30}]`),
+		accept("NegotiateXML", "application/xml", core.MIMEApplicationXMLCharsetUTF8,
+			`<?xml version="1.0" encoding="UTF-8"?>`+"\n"+`<negotiateUser><name>bob</name><age>30</age></negotiateUser>`),
+		accept("NegotiateYAML", "application/yaml", core.MIMEApplicationYAML, "- name: bob\n  age: 30"),
+		accept("NegotiateCSV", "text/csv", "text/csv; charset=utf-8", "name,age\nbob,30"),
+		accept("NegotiateQuality", "application/json;q=0.5, text/csv", core.MIMETextCSV, "name,age\nbob,30"),
+		accept("NegotiateWildcard", "text/*, text/xml;q=0, application/json;q=0.1", core.MIMETextCSV, "name,age\nbob,30"),
+		accept("NegotiateAny", "image/png, */*;q=0.1", core.MIMEApplicationJSON, `[{"name":"bob","age":30}]`),
+		{
+			Name:    "NegotiateMsgPack",
+			Setup:   func(t *testing.T, s core.Server) { offer(s) },
+			Target:  RootPath + "/user",
+			Headers: map[string]string{core.HeaderAccept: core.MIMEApplicationMsgPack},
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusOK)
+				ExpectHeader(t, res, core.HeaderContentType, core.MIMEApplicationMsgPack)
+				var users []negotiateUser
+				if err := (core.MsgPackCodec{}).Unmarshal(res.Body.Bytes(), &users); err != nil || len(users) != 1 || users[0] != bob {
+					t.Fatalf("body = %q (%v), want bob", res.Body.String(), err)
+				}
+			},
+		},
+		{
+			Name:    "NegotiateNotAcceptable",
+			Setup:   func(t *testing.T, s core.Server) { offer(s) },
+			Target:  RootPath + "/user",
+			Headers: map[string]string{core.HeaderAccept: "image/png, application/json;q=0"},
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusNotAcceptable)
+				ExpectHeader(t, res, core.HeaderContentType, core.MIMEApplicationProblemJSON)
+				var problem struct {
+					Available []string `json:"available"`
+				}
+				if err := json.Unmarshal(res.Body.Bytes(), &problem); err != nil || len(problem.Available) == 0 || problem.Available[0] != core.MIMEApplicationJSON {
+					t.Fatalf("body = %q, want a problem listing the available types", res.Body.String())
+				}
+			},
+		},
+		{
+			Name: "NegotiateRawOffer",
+			Setup: func(t *testing.T, s core.Server) {
+				s.Add(core.MethodGet, "/page", func(c core.Context) {
+					if err := c.Negotiate(core.StatusOK, map[string]interface{}{
+						core.MIMETextHTML:        "<p>bob</p>",
+						core.MIMEApplicationJSON: bob,
+					}); err != nil {
+						c.Error(err)
+					}
+				})
+			},
+			Target:  RootPath + "/page",
+			Headers: map[string]string{core.HeaderAccept: "text/html"},
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusOK)
+				ExpectHeader(t, res, core.HeaderContentType, core.MIMETextHTMLCharsetUTF8)
+				ExpectBody(t, res, "<p>bob</p>")
+			},
+		},
+		{
+			Name: "NegotiateTypedHandler",
+			Setup: func(t *testing.T, s core.Server) {
+				s.Add(core.MethodGet, "/typed", core.MustHandler(func(c core.Context) (*negotiateUser, error) {
+					return &bob, nil
+				}), core.Produces(core.MIMEApplicationJSON, core.MIMEApplicationYAML))
+			},
+			Target:  RootPath + "/typed",
+			Headers: map[string]string{core.HeaderAccept: "application/yaml"},
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusOK)
+				ExpectHeader(t, res, core.HeaderContentType, core.MIMEApplicationYAML)
+				ExpectBody(t, res, "name: bob\nage: 30")
+			},
+		},
+		{
+			// Không có Produces, kết quả của handler có kiểu luôn là JSON
+			Name: "NegotiateTypedHandlerJSONOnly",
+			Setup: func(t *testing.T, s core.Server) {
+				s.Add(core.MethodGet, "/typed", core.MustHandler(func(c core.Context) (map[string]interface{}, error) {
+					return map[string]interface{}{"name": "bob"}, nil
+				}), core.Produces(core.MIMEApplicationXML))
+				s.Add(core.MethodGet, "/struct", core.MustHandler(func(c core.Context) (*negotiateUser, error) {
+					return &bob, nil
+				}))
+				for _, accept := range []string{browserAcceptHeader, "application/yaml"} {
+					res := Do(s, NewRequest(core.MethodGet, RootPath+"/struct", "", map[string]string{core.HeaderAccept: accept}))
+					ExpectStatus(t, res, core.StatusOK)
+					ExpectHeader(t, res, core.HeaderContentType, core.MIMEApplicationJSON)
+					ExpectBody(t, res, `{"name":"bob","age":30}`)
+				}
+				// Map không mã hoá được sang XML nên không được offer
+				res := Do(s, NewRequest(core.MethodGet, RootPath+"/typed", "", map[string]string{core.HeaderAccept: "application/xml"}))
+				ExpectStatus(t, res, core.StatusNotAcceptable)
+			},
+			Target:  RootPath + "/struct",
+			Headers: map[string]string{core.HeaderAccept: "application/xml"},
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusOK)
+				ExpectHeader(t, res, core.HeaderContentType, core.MIMEApplicationJSON)
+				ExpectBody(t, res, `{"name":"bob","age":30}`)
+			},
+		},
+		{
+			Name: "NegotiateWildcardPrefersJSON",
+			Setup: func(t *testing.T, s core.Server) {
+				s.Add(core.MethodGet, "/user", func(c core.Context) {
+					if err := c.Negotiate(core.StatusOK, core.Offers(bob, core.MIMEApplicationYAML, core.MIMEApplicationJSON)); err != nil {
+						c.Error(err)
+					}
+				})
+				if got := core.NegotiateType("image/png, */*;q=0.5", []string{core.MIMEApplicationXML, core.MIMEApplicationJSON}); got != core.MIMEApplicationJSON {
+					t.Fatalf("NegotiateType = %q, want JSON", got)
+				}
+			},
+			Target:  RootPath + "/user",
+			Headers: map[string]string{core.HeaderAccept: "*/*"},
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusOK)
+				ExpectHeader(t, res, core.HeaderContentType, core.MIMEApplicationJSON)
+			},
+		},
+		bind("BindYAML", core.MIMEApplicationYAML, "- name: bob\n  age: 30\n"),
+		bind("BindJSON", core.MIMEApplicationJSON, `[{"name":"bob","age":30}]`),
+		bind("BindCSV", core.MIMETextCSV, "age,name,extra\n30,bob,x\n"),
+		{
+			Name: "BindMsgPack",
+			Setup: func(t *testing.T, s core.Server) {
+				s.Add(core.MethodPost, "/user", func(c core.Context) {
+					var u negotiateUser
+					if err := c.Bind(&u); err != nil {
+						c.Error(err)
+						return
+					}
+					_ = c.String(core.StatusOK, fmt.Sprint(u))
+				})
+			},
+			Method: core.MethodPost,
+			Target: RootPath + "/user",
+			Body: func() string {
+				b, _ := core.MsgPackCodec{}.Marshal(bob)
+				return string(b)
+			}(),
+			Headers: map[string]string{core.HeaderContentType: core.MIMEApplicationMsgPack},
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusOK)
+				ExpectBody(t, res, "{bob 30}")
+			},
+		},
+	}
+}
+
+// sseCases check Context.SSE, in-process and on a live connection.
+func sseCases() []Case {
+	var port string
+
+	return []Case{
+		{
+			Name: "SSEEvents",
+			Setup: func(t *testing.T, s core.Server) {
+				s.Add(core.MethodGet, "/events", func(c core.Context) {
+					err := c.SSE(func(stream core.EventStream) error {
+						if err := stream.Comment("hello"); err != nil {
+							return err
+						}
+						if err := stream.Send(core.Event{ID: "42", Event: "progress", Retry: 3 * time.Second, Data: "line 1\nline 2"}); err != nil {
+							return err
+						}
+						return stream.Send(core.Event{Data: map[string]string{"resume": stream.LastEventID()}})
+					})
+					if err != nil {
+						c.Error(err)
+					}
+				})
+			},
+			Target:  RootPath + "/events",
+			Headers: map[string]string{core.HeaderLastEventID: "41"},
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusOK)
+				ExpectHeader(t, res, core.HeaderContentType, core.MIMETextEventStream)
+				ExpectHeader(t, res, core.HeaderCacheControl, "no-cache")
+				ExpectBody(t, res, ": hello\n\n"+
+					"id: 42\nevent: progress\nretry: 3000\ndata: line 1\ndata: line 2\n\n"+
+					`data: {"resume":"41"}`)
+			},
+		},
+		{
+			Name: "SSEInvalidEvent",
+			Setup: func(t *testing.T, s core.Server) {
+				s.Add(core.MethodGet, "/events", func(c core.Context) {
+					_ = c.SSE(func(stream core.EventStream) error {
+						if err := stream.Send(core.Event{Event: "a\nb"}); err == nil {
+							return stream.Send(core.Event{Data: "accepted"})
+						}
+						return stream.Send(core.Event{Data: "rejected"})
+					})
+				})
+			},
+			Target: RootPath + "/events",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectBody(t, res, "data: rejected")
+			},
+		},
+		{
+			// CR đơn cũng kết thúc dòng với EventSource nên không được chèn field
+			Name: "SSELoneCR",
+			Setup: func(t *testing.T, s core.Server) {
+				s.Add(core.MethodGet, "/events", func(c core.Context) {
+					_ = c.SSE(func(stream core.EventStream) error {
+						if err := stream.Comment("a\rb"); err != nil {
+							return err
+						}
+						if err := stream.Send(core.Event{Data: "x\rdata: injected\r\ny"}); err != nil {
+							return err
+						}
+						rejected := 0
+						for _, e := range []core.Event{{Event: "a\rb"}, {Event: "a\x00b"}, {ID: "1\r"}} {
+							if stream.Send(e) != nil {
+								rejected++
+							}
+						}
+						return stream.Send(core.Event{Data: "rejected " + strconv.Itoa(rejected)})
+					})
+				})
+			},
+			Target: RootPath + "/events",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectBody(t, res, ": a\n: b\n\n"+
+					"data: x\ndata: data: injected\ndata: y\n\n"+
+					"data: rejected 3")
+			},
+		},
+		{
+			Name: "SSEHeartbeat",
+			Setup: func(t *testing.T, s core.Server) {
+				s.Add(core.MethodGet, "/events", func(c core.Context) {
+					_ = c.SSE(func(stream core.EventStream) error {
+						stream.SetHeartbeat(5 * time.Millisecond)
+						time.Sleep(50 * time.Millisecond)
+						stream.SetHeartbeat(0)
+						return stream.Send(core.Event{Data: "done"})
+					})
+				})
+			},
+			Target: RootPath + "/events",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				body := res.Body.String()
+				if !strings.HasPrefix(body, ": heartbeat\n\n") || !strings.HasSuffix(body, "data: done\n\n") {
+					t.Fatalf("body = %q, want heartbeats then the event", body)
+				}
+			},
+		},
+		{
+			// Mỗi event tới client ngay khi gửi, stream vượt quá Timeouts.Write
+			// và context bị huỷ khi client đóng kết nối
+			Name: "SSELive",
+			Config: func(cfg *core.Config) {
+				var err error
+				if port, err = FreePort(); err != nil {
+					panic(err)
+				}
+				cfg.Host, cfg.Port = "127.0.0.1", port
+				cfg.Timeouts.Write = 200 * time.Millisecond
+			},
+			Setup: func(t *testing.T, s core.Server) {
+				received := make(chan struct{})
+				stopped := make(chan struct{})
+				done := make(chan error, 1)
+				s.Add(core.MethodGet, "/events", func(c core.Context) {
+					// fiber chạy fn sau khi handler trả về, nên chờ stopped thay vì SSE()
+					done <- c.SSE(func(stream core.EventStream) error {
+						defer close(stopped)
+						if err := stream.Send(core.Event{ID: "1", Data: "first"}); err != nil {
+							return err
+						}
+						select {
+						case <-received:
+						case <-time.After(5 * time.Second):
+							return errors.New("first event was not flushed")
+						}
+						time.Sleep(300 * time.Millisecond)
+						for i := 2; ; i++ {
+							if err := stream.Send(core.Event{ID: strconv.Itoa(i), Data: "next"}); err != nil {
+								return err
+							}
+							select {
+							case <-stream.Context().Done():
+								return stream.Context().Err()
+							case <-time.After(10 * time.Millisecond):
+							}
+						}
+					})
+				})
+				if err := startServer(t, s, "127.0.0.1:"+port); err != nil {
+					t.Fatalf("Start() = %v", err)
+				}
+
+				res, err := http.Get("http://127.0.0.1:" + port + RootPath + "/events")
+				if err != nil {
+					t.Fatalf("GET /events: %v", err)
+				}
+				r := bufio.NewReader(res.Body)
+				readEvent := func() string {
+					var event string
+					for {
+						line, err := r.ReadString('\n')
+						if err != nil {
+							t.Fatalf("read event: %v (got %q)", err, event)
+						}
+						if line == "\n" {
+							return event
+						}
+						event += line
+					}
+				}
+				if got := readEvent(); got != "id: 1\ndata: first\n" {
+					t.Fatalf("first event = %q", got)
+				}
+				close(received)
+				if got := readEvent(); got != "id: 2\ndata: next\n" {
+					t.Fatalf("second event = %q", got)
+				}
+				res.Body.Close()
+				select {
+				case <-stopped:
+				case <-time.After(5 * time.Second):
+					t.Fatal("stream was not cancelled after the client left")
+				}
+				if err := <-done; err != nil {
+					t.Fatalf("SSE() = %v, want nil after the client left", err)
+				}
+			},
+			Target: "/missing",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusNotFound)
+			},
+		},
+	}
+}
+
+// wsClient is a minimal WebSocket client that sends raw frames, so the cases
+// can check fragmentation, control frames and close codes.
+type wsClient struct {
+	conn net.Conn
+	br   *bufio.Reader
+	// Header is the response of the handshake.
+	Header http.Header
+}
+
+// dialWS sends the upgrade request for path with the extra headers and
+// returns the client once the server switched protocols.
+func dialWS(t *testing.T, addr, path string, headers map[string]string) *wsClient {
+	t.Helper()
+	conn, err := net.DialTimeout("tcp", addr, time.Second)
+	if err != nil {
+		t.Fatalf("dial %s: %v", addr, err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	req, _ := http.NewRequest(core.MethodGet, "http://"+addr+path, nil)
+	req.Header.Set(core.HeaderConnection, "Upgrade")
+	req.Header.Set(core.HeaderUpgrade, "websocket")
+	req.Header.Set(core.HeaderSecWebSocketVersion, "13")
+	req.Header.Set(core.HeaderSecWebSocketKey, "dGhlIHNhbXBsZSBub25jZQ==")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	if err := req.Write(conn); err != nil {
+		t.Fatalf("write handshake: %v", err)
+	}
+	br := bufio.NewReader(conn)
+	res, err := http.ReadResponse(br, req)
+	if err != nil {
+		t.Fatalf("read handshake: %v", err)
+	}
+	if res.StatusCode != core.StatusSwitchingProtocols {
+		body, _ := io.ReadAll(res.Body)
+		t.Fatalf("handshake = %d %q, want 101", res.StatusCode, body)
+	}
+	// Giá trị mẫu của RFC 6455, 1.3
+	if got := res.Header.Get(core.HeaderSecWebSocketAccept); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("Sec-WebSocket-Accept = %q", got)
+	}
+	return &wsClient{conn: conn, br: br, Header: res.Header}
+}
+
+// send writes one masked frame.
+func (c *wsClient) send(t *testing.T, fin bool, op byte, payload []byte) {
+	t.Helper()
+	b0 := op
+	if fin {
+		b0 |= 0x80
+	}
+	frame := []byte{b0}
+	switch n := len(payload); {
+	case n <= 125:
+		frame = append(frame, 0x80|byte(n))
+	default:
+		frame = append(frame, 0x80|126, byte(n>>8), byte(n))
+	}
+	mask := []byte{1, 2, 3, 4}
+	frame = append(frame, mask...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	if _, err := c.conn.Write(frame); err != nil {
+		t.Fatalf("write frame: %v", err)
+	}
+}
+
+// raw writes bytes as they are, for frames send cannot build.
+func (c *wsClient) raw(t *testing.T, frame ...byte) {
+	t.Helper()
+	if _, err := c.conn.Write(frame); err != nil {
+		t.Fatalf("write frame: %v", err)
+	}
+}
+
+// read returns the opcode and payload of the next frame from the server.
+func (c *wsClient) read(t *testing.T) (byte, []byte) {
+	t.Helper()
+	var header [2]byte
+	if _, err := io.ReadFull(c.br, header[:]); err != nil {
+		t.Fatalf("read frame: %v", err)
+	}
+	if header[1]&0x80 != 0 {
+		t.Fatal("server frames must not be masked")
+	}
+	n := int(header[1] & 0x7f)
+	if n == 126 {
+		var ext [2]byte
+		_, _ = io.ReadFull(c.br, ext[:])
+		n = int(ext[0])<<8 | int(ext[1])
+	}
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(c.br, payload); err != nil {
+		t.Fatalf("read payload: %v", err)
+	}
+	return header[0] & 0x0f, payload
+}
+
+// readData skips pings and returns the next data or close frame.
+func (c *wsClient) readData(t *testing.T) (byte, []byte) {
+	t.Helper()
+	for {
+		op, payload := c.read(t)
+		if op != 0x9 && op != 0xA {
+			return op, payload
+		}
+	}
+}
+
+// expectClose reads until a close frame and checks its code.
+func (c *wsClient) expectClose(t *testing.T, code int) {
+	t.Helper()
+	op, payload := c.readData(t)
+	if op != 0x8 || len(payload) < 2 || int(payload[0])<<8|int(payload[1]) != code {
+		t.Fatalf("frame = %d %q, want close %d", op, payload, code)
+	}
+}
+
+func closePayload(code int, reason string) []byte {
+	return append([]byte{byte(code >> 8), byte(code)}, reason...)
+}
+
+// wsCases check Server.WebSocket and @Api WS routes: the handshake checks
+// in-process, the protocol on a live connection.
+func wsCases() []Case {
+	var port string
+	live := func(cfg *core.Config) {
+		var err error
+		if port, err = FreePort(); err != nil {
+			panic(err)
+		}
+		cfg.Host, cfg.Port = "127.0.0.1", port
+		cfg.Timeouts.Read = time.Second
+		cfg.Timeouts.Write = time.Second
+	}
+	notFound := func(t *testing.T, res *httptest.ResponseRecorder) {
+		ExpectStatus(t, res, core.StatusNotFound)
+	}
+	echo := func(conn core.WSConn) {
+		echoMessages(conn, "")
+	}
+	upgrade := map[string]string{
+		core.HeaderConnection:          "keep-alive, Upgrade",
+		core.HeaderUpgrade:             "websocket",
+		core.HeaderSecWebSocketVersion: "13",
+		core.HeaderSecWebSocketKey:     "dGhlIHNhbXBsZSBub25jZQ==",
+	}
+	with := func(key, value string) map[string]string {
+		headers := make(map[string]string, len(upgrade)+1)
+		for k, v := range upgrade {
+			headers[k] = v
+		}
+		headers[key] = value
+		return headers
+	}
+	handshake := func(name string, headers map[string]string, code int, check func(t *testing.T, res *httptest.ResponseRecorder)) Case {
+		return Case{
+			Name: name,
+			Setup: func(t *testing.T, s core.Server) {
+				s.WebSocket("/ws", echo, core.WSOptions{Origins: []string{"https://app.example.com"}})
+			},
+			Target:  RootPath + "/ws",
+			Headers: headers,
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, code)
+				ExpectHeader(t, res, core.HeaderContentType, core.MIMEApplicationProblemJSON)
+				if check != nil {
+					check(t, res)
+				}
+			},
+		}
+	}
+
+	return []Case{
+		handshake("WSNotUpgrade", nil, core.StatusUpgradeRequired, func(t *testing.T, res *httptest.ResponseRecorder) {
+			ExpectHeader(t, res, core.HeaderUpgrade, "websocket")
+		}),
+		handshake("WSUnsupportedVersion", with(core.HeaderSecWebSocketVersion, "8"), core.StatusUpgradeRequired, func(t *testing.T, res *httptest.ResponseRecorder) {
+			ExpectHeader(t, res, core.HeaderSecWebSocketVersion, "13")
+		}),
+		handshake("WSInvalidKey", with(core.HeaderSecWebSocketKey, "short"), core.StatusBadRequest, nil),
+		handshake("WSOriginRejected", with(core.HeaderOrigin, "https://evil.example.com"), core.StatusForbidden, nil),
+		{
+			Name:   "WSMessages",
+			Config: live,
+			Setup: func(t *testing.T, s core.Server) {
+				s.WebSocket("/rooms/:room", func(conn core.WSConn) {
+					_ = conn.WriteJSON(map[string]string{
+						"room":     conn.Param("room"),
+						"token":    conn.Query("token"),
+						"agent":    conn.Header("X-Agent"),
+						"protocol": conn.Subprotocol(),
+					})
+					for {
+						typ, data, err := conn.ReadMessage()
+						if err != nil {
+							return
+						}
+						if err := conn.WriteMessage(typ, data); err != nil {
+							return
+						}
+					}
+				}, core.WSOptions{Subprotocols: []string{"chat.v2", "chat.v1"}, Origins: []string{"https://app.example.com"}})
+				if err := startServer(t, s, "127.0.0.1:"+port); err != nil {
+					t.Fatalf("Start() = %v", err)
+				}
+
+				c := dialWS(t, "127.0.0.1:"+port, RootPath+"/rooms/lobby?token=abc", map[string]string{
+					core.HeaderSecWebSocketProtocol: "chat.v1, chat.v2",
+					core.HeaderOrigin:               "https://app.example.com",
+					"X-Agent":                       "test",
+				})
+				if got := c.Header.Get(core.HeaderSecWebSocketProtocol); got != "chat.v2" {
+					t.Fatalf("Sec-WebSocket-Protocol = %q, want chat.v2", got)
+				}
+				if op, data := c.readData(t); op != 0x1 || string(data) != `{"agent":"test","protocol":"chat.v2","room":"lobby","token":"abc"}` {
+					t.Fatalf("welcome = %d %q", op, data)
+				}
+
+				// Message phân mảnh được ghép lại, ping xen giữa được trả pong
+				c.send(t, false, 0x1, []byte("hel"))
+				c.send(t, true, 0x9, []byte("ping"))
+				if op, data := c.read(t); op != 0xA || string(data) != "ping" {
+					t.Fatalf("pong = %d %q", op, data)
+				}
+				c.send(t, true, 0x0, []byte("lo"))
+				if op, data := c.readData(t); op != 0x1 || string(data) != "hello" {
+					t.Fatalf("echo = %d %q, want text hello", op, data)
+				}
+				c.send(t, true, 0x2, []byte{0, 1, 2})
+				if op, data := c.readData(t); op != 0x2 || !bytes.Equal(data, []byte{0, 1, 2}) {
+					t.Fatalf("echo = %d %v, want binary", op, data)
+				}
+				big := bytes.Repeat([]byte("x"), 200)
+				c.send(t, true, 0x1, big)
+				if _, data := c.readData(t); !bytes.Equal(data, big) {
+					t.Fatalf("echo of 200 bytes = %d bytes", len(data))
+				}
+
+				c.send(t, true, 0x8, closePayload(core.WSCloseNormal, "bye"))
+				c.expectClose(t, core.WSCloseNormal)
+			},
+			Target: "/missing",
+			Check:  notFound,
+		},
+		{
+			Name:   "WSLimitsAndKeepalive",
+			Config: live,
+			Setup: func(t *testing.T, s core.Server) {
+				s.WebSocket("/ws", echo, core.WSOptions{MaxMessageSize: 16, PingInterval: 20 * time.Millisecond, PongTimeout: 2 * time.Second})
+				if err := startServer(t, s, "127.0.0.1:"+port); err != nil {
+					t.Fatalf("Start() = %v", err)
+				}
+
+				// Server ping theo PingInterval, kể cả khi Read/Write timeout của server đã qua
+				c := dialWS(t, "127.0.0.1:"+port, RootPath+"/ws", nil)
+				time.Sleep(1200 * time.Millisecond)
+				if op, _ := c.read(t); op != 0x9 {
+					t.Fatalf("frame = %d, want ping", op)
+				}
+				c.send(t, true, 0x1, []byte("still here"))
+				if _, data := c.readData(t); string(data) != "still here" {
+					t.Fatalf("echo = %q", data)
+				}
+				c.send(t, false, 0x1, []byte("0123456789"))
+				c.send(t, true, 0x0, []byte("0123456789"))
+				c.expectClose(t, core.WSCloseTooBig)
+
+				c = dialWS(t, "127.0.0.1:"+port, RootPath+"/ws", nil)
+				c.send(t, true, 0x1, []byte{0xff})
+				c.expectClose(t, core.WSCloseInvalidPayload)
+			},
+			Target: "/missing",
+			Check:  notFound,
+		},
+		{
+			// Theo các nhóm của bộ test autobahn: phân mảnh, control frame, close, UTF-8, độ dài
+			Name:   "WSProtocolErrors",
+			Config: live,
+			Setup: func(t *testing.T, s core.Server) {
+				s.WebSocket("/ws", echo, core.WSOptions{MaxMessageSize: -1})
+				if err := startServer(t, s, "127.0.0.1:"+port); err != nil {
+					t.Fatalf("Start() = %v", err)
+				}
+				mask := []byte{1, 2, 3, 4}
+				text := "κόσμε"
+				for _, tc := range []struct {
+					name  string
+					frame func(c *wsClient)
+					code  int
+				}{
+					{"continuation without start", func(c *wsClient) { c.send(t, true, 0x0, []byte("x")) }, core.WSCloseProtocolError},
+					{"new message inside a fragmented one", func(c *wsClient) {
+						c.send(t, false, 0x1, []byte("a"))
+						c.send(t, true, 0x1, []byte("b"))
+					}, core.WSCloseProtocolError},
+					{"fragmented ping", func(c *wsClient) { c.send(t, false, 0x9, nil) }, core.WSCloseProtocolError},
+					{"ping over 125 bytes", func(c *wsClient) { c.send(t, true, 0x9, make([]byte, 126)) }, core.WSCloseProtocolError},
+					{"reserved bits", func(c *wsClient) { c.raw(t, append([]byte{0xC1, 0x80}, mask...)...) }, core.WSCloseProtocolError},
+					{"unmasked frame", func(c *wsClient) { c.raw(t, 0x81, 0x01, 'x') }, core.WSCloseProtocolError},
+					{"unknown opcode", func(c *wsClient) { c.send(t, true, 0x3, nil) }, core.WSCloseProtocolError},
+					{"close code 1005", func(c *wsClient) { c.send(t, true, 0x8, closePayload(1005, "")) }, core.WSCloseProtocolError},
+					{"close code 999", func(c *wsClient) { c.send(t, true, 0x8, closePayload(999, "")) }, core.WSCloseProtocolError},
+					{"close of 1 byte", func(c *wsClient) { c.send(t, true, 0x8, []byte{0x03}) }, core.WSCloseProtocolError},
+					{"close reason not UTF-8", func(c *wsClient) { c.send(t, true, 0x8, closePayload(core.WSCloseNormal, "\xff")) }, core.WSCloseProtocolError},
+					{"empty close", func(c *wsClient) { c.send(t, true, 0x8, nil) }, core.WSCloseNormal},
+					{"invalid UTF-8 across fragments", func(c *wsClient) {
+						c.send(t, false, 0x1, []byte(text[:1]))
+						c.send(t, true, 0x0, []byte{0xff})
+					}, core.WSCloseInvalidPayload},
+					// Độ dài 2^63-1 trong header không được làm server cấp phát, kể cả khi MaxMessageSize âm
+					{"huge length", func(c *wsClient) {
+						c.raw(t, append([]byte{0x82, 0xFF, 0x7f, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, mask...)...)
+					}, core.WSCloseTooBig},
+					{"length over 63 bits", func(c *wsClient) {
+						c.raw(t, append([]byte{0x82, 0xFF, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, mask...)...)
+					}, core.WSCloseProtocolError},
+				} {
+					t.Run(tc.name, func(t *testing.T) {
+						c := dialWS(t, "127.0.0.1:"+port, RootPath+"/ws", nil)
+						tc.frame(c)
+						c.expectClose(t, tc.code)
+					})
+				}
+
+				// Ký tự nhiều byte bị cắt giữa hai fragment vẫn hợp lệ; binary không kiểm tra UTF-8
+				c := dialWS(t, "127.0.0.1:"+port, RootPath+"/ws", nil)
+				c.send(t, false, 0x1, []byte(text[:1]))
+				c.send(t, false, 0x0, []byte(text[1:4]))
+				c.send(t, true, 0x0, []byte(text[4:]))
+				if op, data := c.readData(t); op != 0x1 || string(data) != text {
+					t.Fatalf("echo = %d %q, want %q", op, data, text)
+				}
+				c.send(t, true, 0x2, []byte{0xff, 0xfe})
+				if op, data := c.readData(t); op == 0x8 || !bytes.Equal(data, []byte{0xff, 0xfe}) {
+					t.Fatalf("echo = %d %v, want the binary payload", op, data)
+				}
+				c.send(t, true, 0x8, closePayload(3000, "done"))
+				c.expectClose(t, 3000)
+			},
+			Target: "/missing",
+			Check:  notFound,
+		},
+		{
+			Name:   "WSPongTimeout",
+			Config: live,
+			Setup: func(t *testing.T, s core.Server) {
+				done := make(chan error, 1)
+				s.WebSocket("/ws", func(conn core.WSConn) {
+					_, _, err := conn.ReadMessage()
+					done <- err
+				}, core.WSOptions{PingInterval: 20 * time.Millisecond, PongTimeout: 50 * time.Millisecond})
+				if err := startServer(t, s, "127.0.0.1:"+port); err != nil {
+					t.Fatalf("Start() = %v", err)
+				}
+				dialWS(t, "127.0.0.1:"+port, RootPath+"/ws", nil)
+				select {
+				case err := <-done:
+					var ne net.Error
+					if !errors.As(err, &ne) || !ne.Timeout() {
+						t.Fatalf("ReadMessage() = %v, want a timeout", err)
+					}
+				case <-time.After(3 * time.Second):
+					t.Fatal("the silent client was not dropped")
+				}
+			},
+			Target: "/missing",
+			Check:  notFound,
+		},
+		{
+			Name:   "WSShutdown",
+			Config: live,
+			Setup: func(t *testing.T, s core.Server) {
+				closed := make(chan error, 1)
+				s.WebSocket("/ws", func(conn core.WSConn) {
+					<-conn.Context().Done()
+					_, _, err := conn.ReadMessage()
+					closed <- err
+				})
+				if err := startServer(t, s, "127.0.0.1:"+port); err != nil {
+					t.Fatalf("Start() = %v", err)
+				}
+				c := dialWS(t, "127.0.0.1:"+port, RootPath+"/ws", nil)
+
+				shutdown := make(chan error, 1)
+				go func() {
+					ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+					defer cancel()
+					shutdown <- s.Shutdown(ctx)
+				}()
+				c.expectClose(t, core.WSCloseGoingAway)
+				c.send(t, true, 0x8, closePayload(core.WSCloseGoingAway, ""))
+				var ce *core.WSCloseError
+				if err := <-closed; !errors.As(err, &ce) || ce.Code != core.WSCloseGoingAway {
+					t.Fatalf("ReadMessage() = %v, want close 1001", err)
+				}
+				if err := <-shutdown; err != nil {
+					t.Fatalf("Shutdown() = %v", err)
+				}
+			},
+			Target: "/missing",
+			Check:  notFound,
+		},
+		{
+			Name:   "WSApiTag",
+			Config: live,
+			Setup: func(t *testing.T, s core.Server) {
+				s.RegisterHandlersWithTags(&TagHandler{})
+				if err := startServer(t, s, "127.0.0.1:"+port); err != nil {
+					t.Fatalf("Start() = %v", err)
+				}
+				c := dialWS(t, "127.0.0.1:"+port, RootPath+"/tags/echo", nil)
+				c.send(t, true, 0x1, []byte("hi"))
+				if _, data := c.readData(t); string(data) != "tag:hi" {
+					t.Fatalf("echo = %q, want tag:hi", data)
+				}
+			},
+			Target: "/missing",
+			Check:  notFound,
+		},
+		{
+			Name:   "WSApiTagDevMode",
+			Mode:   "debug",
+			Config: live,
+			Setup: func(t *testing.T, s core.Server) {
+				s.RegisterHandlersWithTags(&DevTagHandler{})
+				if err := startServer(t, s, "127.0.0.1:"+port); err != nil {
+					t.Fatalf("Start() = %v", err)
+				}
+				c := dialWS(t, "127.0.0.1:"+port, RootPath+"/dev/echo", nil)
+				c.send(t, true, 0x1, []byte("hi"))
+				if _, data := c.readData(t); string(data) != "dev:hi" {
+					t.Fatalf("echo = %q, want dev:hi", data)
+				}
+			},
+			Target: "/missing",
+			Check:  notFound,
+		},
+	}
+}
+
+// corsCases check Config.CORS, which answers the CORS requests of every
+// path, and core.CORS used as middleware.
+func corsCases() []Case {
+	items := func(t *testing.T, s core.Server) {
+		s.Add(core.MethodGet, "/items", func(c core.Context) {
+			c.SetHeader("X-Total", "2")
+			c.JSON(core.StatusOK, []string{"a", "b"})
+		})
+	}
+	var port string
+	withCORS := func(cors core.CORSConfig) func(cfg *core.Config) {
+		return func(cfg *core.Config) { cfg.CORS = cors }
+	}
+	wildcard := withCORS(core.CORSConfig{
+		AllowOrigins:     []string{"https://*.example.com", `^https://(a|b)\.test$`},
+		AllowCredentials: true,
+		ExposeHeaders:    []string{"X-Total"},
+		MaxAge:           10 * time.Minute,
+	})
+	vary := func(res *httptest.ResponseRecorder) []string {
+		var values []string
+		for _, v := range res.Header().Values(core.HeaderVary) {
+			values = append(values, strings.Split(v, ", ")...)
+		}
+		return values
+	}
+	expectVary := func(t *testing.T, res *httptest.ResponseRecorder, headers ...string) {
+		t.Helper()
+		got := vary(res)
+		for _, h := range headers {
+			found := false
+			for _, v := range got {
+				found = found || strings.EqualFold(v, h)
+			}
+			if !found {
+				t.Fatalf("Vary = %q, want %s", got, h)
+			}
+		}
+	}
+	expectNoCORS := func(t *testing.T, res *httptest.ResponseRecorder) {
+		t.Helper()
+		if got := res.Header().Get(core.HeaderAccessControlAllowOrigin); got != "" {
+			t.Fatalf("%s = %q, want none", core.HeaderAccessControlAllowOrigin, got)
+		}
+	}
+	preflight := func(origin, method, headers string) map[string]string {
+		return map[string]string{
+			core.HeaderOrigin:                      origin,
+			core.HeaderAccessControlRequestMethod:  method,
+			core.HeaderAccessControlRequestHeaders: headers,
+		}
+	}
+
+	return []Case{
+		{
+			Name:    "CORSPreflight",
+			Config:  wildcard,
+			Setup:   items,
+			Method:  core.MethodOptions,
+			Target:  RootPath + "/items",
+			Headers: preflight("https://app.example.com", core.MethodPut, "X-Token, Content-Type"),
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusNoContent)
+				ExpectHeader(t, res, core.HeaderAccessControlAllowOrigin, "https://app.example.com")
+				ExpectHeader(t, res, core.HeaderAccessControlAllowCredentials, "true")
+				ExpectHeader(t, res, core.HeaderAccessControlAllowMethods, "GET, HEAD, POST, PUT, PATCH, DELETE")
+				ExpectHeader(t, res, core.HeaderAccessControlAllowHeaders, "X-Token, Content-Type")
+				ExpectHeader(t, res, core.HeaderAccessControlMaxAge, "600")
+				expectVary(t, res, core.HeaderOrigin, core.HeaderAccessControlRequestMethod, core.HeaderAccessControlRequestHeaders)
+			},
+		},
+		{
+			// Preflight được trả lời cả khi path không có route
+			Name:    "CORSPreflightUnmatchedPath",
+			Config:  wildcard,
+			Method:  core.MethodOptions,
+			Target:  RootPath + "/missing",
+			Headers: preflight("https://a.test", core.MethodDelete, ""),
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusNoContent)
+				ExpectHeader(t, res, core.HeaderAccessControlAllowOrigin, "https://a.test")
+				if got := res.Header().Get(core.HeaderAccessControlAllowHeaders); got != "" {
+					t.Fatalf("%s = %q, want none", core.HeaderAccessControlAllowHeaders, got)
+				}
+			},
+		},
+		{
+			Name:    "CORSPreflightOriginRejected",
+			Config:  wildcard,
+			Setup:   items,
+			Method:  core.MethodOptions,
+			Target:  RootPath + "/items",
+			Headers: preflight("https://example.com.evil.net", core.MethodGet, ""),
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusNoContent)
+				expectNoCORS(t, res)
+				expectVary(t, res, core.HeaderOrigin)
+			},
+		},
+		{
+			Name: "CORSPreflightMethodOrHeaderRejected",
+			Config: withCORS(core.CORSConfig{
+				AllowOrigins: []string{"https://app.example.com"},
+				AllowMethods: []string{"get", "post"},
+				AllowHeaders: []string{"Content-Type"},
+			}),
+			Setup: func(t *testing.T, s core.Server) {
+				for _, headers := range []map[string]string{
+					preflight("https://app.example.com", core.MethodDelete, ""),
+					preflight("https://app.example.com", core.MethodPost, "X-Token"),
+				} {
+					res := Do(s, NewRequest(core.MethodOptions, RootPath+"/items", "", headers))
+					ExpectStatus(t, res, core.StatusNoContent)
+					expectNoCORS(t, res)
+				}
+			},
+			Method:  core.MethodOptions,
+			Target:  RootPath + "/items",
+			Headers: preflight("https://APP.example.com", core.MethodPost, "content-type"),
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusNoContent)
+				ExpectHeader(t, res, core.HeaderAccessControlAllowOrigin, "https://APP.example.com")
+				ExpectHeader(t, res, core.HeaderAccessControlAllowMethods, "GET, POST")
+				ExpectHeader(t, res, core.HeaderAccessControlAllowHeaders, "Content-Type")
+			},
+		},
+		{
+			Name:    "CORSRequest",
+			Config:  wildcard,
+			Setup:   items,
+			Target:  RootPath + "/items",
+			Headers: map[string]string{core.HeaderOrigin: "https://b.test"},
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusOK)
+				ExpectHeader(t, res, core.HeaderAccessControlAllowOrigin, "https://b.test")
+				ExpectHeader(t, res, core.HeaderAccessControlAllowCredentials, "true")
+				ExpectHeader(t, res, core.HeaderAccessControlExposeHeaders, "X-Total")
+				ExpectHeader(t, res, "X-Total", "2")
+				expectVary(t, res, core.HeaderOrigin)
+			},
+		},
+		{
+			// Lỗi 404 vẫn mang header CORS để script đọc được problem
+			Name:    "CORSRequestNotFound",
+			Config:  wildcard,
+			Target:  RootPath + "/missing",
+			Headers: map[string]string{core.HeaderOrigin: "https://app.example.com"},
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusNotFound)
+				ExpectHeader(t, res, core.HeaderAccessControlAllowOrigin, "https://app.example.com")
+			},
+		},
+		{
+			Name:   "CORSRequestOriginRejected",
+			Config: wildcard,
+			Setup:  items,
+			Target: RootPath + "/items",
+			Headers: map[string]string{
+				core.HeaderOrigin: "https://c.test",
+			},
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusOK)
+				expectNoCORS(t, res)
+				expectVary(t, res, core.HeaderOrigin)
+			},
+		},
+		{
+			Name:    "CORSAnyOrigin",
+			Config:  withCORS(core.CORSConfig{AllowOrigins: []string{"*"}}),
+			Setup:   items,
+			Target:  RootPath + "/items",
+			Headers: map[string]string{core.HeaderOrigin: "https://any.test"},
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusOK)
+				ExpectHeader(t, res, core.HeaderAccessControlAllowOrigin, "*")
+				if got := vary(res); len(got) != 0 {
+					t.Fatalf("Vary = %q, want none", got)
+				}
+			},
+		},
+		{
+			// Config.CORS sai không làm NewServer panic: CORS bị tắt và Start trả lỗi
+			Name: "CORSAnyOriginWithCredentials",
+			Config: func(cfg *core.Config) {
+				var err error
+				if port, err = FreePort(); err != nil {
+					panic(err)
+				}
+				cfg.Host, cfg.Port = "127.0.0.1", port
+				cfg.CORS = core.CORSConfig{AllowOrigins: []string{"*"}, AllowCredentials: true}
+			},
+			Setup: func(t *testing.T, s core.Server) {
+				items(t, s)
+				if _, err := core.NewCORS(core.CORSConfig{AllowOrigins: []string{"^https://(a"}}); err == nil {
+					t.Fatal("NewCORS accepted an invalid origin pattern")
+				}
+				done := make(chan error, 1)
+				go func() { done <- s.Start() }()
+				t.Cleanup(func() { _ = s.Shutdown(context.Background()) })
+				select {
+				case err := <-done:
+					if err == nil || !strings.Contains(err.Error(), "AllowCredentials") {
+						t.Fatalf("Start() = %v, want the CORS error", err)
+					}
+				case <-time.After(5 * time.Second):
+					t.Fatal("Start() served with an invalid CORS config")
+				}
+			},
+			Target:  RootPath + "/items",
+			Headers: map[string]string{core.HeaderOrigin: "https://evil.example"},
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusOK)
+				expectNoCORS(t, res)
+			},
+		},
+		{
+			// Pattern ^... phải khớp cả Origin, không chỉ phần đầu
+			Name: "CORSPatternSuffixAttack",
+			Config: withCORS(core.CORSConfig{
+				AllowOrigins: []string{`^https://app\.example\.com`},
+			}),
+			Setup: func(t *testing.T, s core.Server) {
+				items(t, s)
+				res := Do(s, NewRequest(core.MethodGet, RootPath+"/items", "", map[string]string{core.HeaderOrigin: "https://app.example.com"}))
+				ExpectHeader(t, res, core.HeaderAccessControlAllowOrigin, "https://app.example.com")
+			},
+			Target:  RootPath + "/items",
+			Headers: map[string]string{core.HeaderOrigin: "https://app.example.com.evil.net"},
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusOK)
+				expectNoCORS(t, res)
+			},
+		},
+		{
+			Name: "CORSOriginFunc",
+			Config: withCORS(core.CORSConfig{
+				AllowOriginFunc: func(origin string) bool { return strings.HasSuffix(origin, ".internal") },
+			}),
+			Setup:   items,
+			Target:  RootPath + "/items",
+			Headers: map[string]string{core.HeaderOrigin: "http://tools.internal"},
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectHeader(t, res, core.HeaderAccessControlAllowOrigin, "http://tools.internal")
+			},
+		},
+		{
+			Name: "CORSMiddleware",
+			Setup: func(t *testing.T, s core.Server) {
+				s.AddGroup("/public", func(rg core.RouterGroup) {
+					rg.Add(core.MethodGet, "/items", func(c core.Context) {
+						c.JSON(core.StatusOK, []string{"a"})
+					})
+					rg.Add(core.MethodOptions, "/items", func(c core.Context) {
+						t.Error("the preflight reached the handler")
+					})
+				}, core.CORS(core.CORSConfig{AllowOrigins: []string{"https://app.example.com"}}))
+
+				res := Do(s, NewRequest(core.MethodOptions, RootPath+"/public/items", "", preflight("https://app.example.com", core.MethodGet, "")))
+				ExpectStatus(t, res, core.StatusNoContent)
+				ExpectHeader(t, res, core.HeaderAccessControlAllowOrigin, "https://app.example.com")
+			},
+			Target:  RootPath + "/public/items",
+			Headers: map[string]string{core.HeaderOrigin: "https://app.example.com"},
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusOK)
+				ExpectHeader(t, res, core.HeaderAccessControlAllowOrigin, "https://app.example.com")
+			},
+		},
+	}
+}
+
+// failingStore is a RateLimitStore whose backend is down.
+type failingStore struct{}
+
+func (failingStore) Allow(context.Context, string, core.RateLimitPolicy) (core.RateLimitResult, error) {
+	return core.RateLimitResult{}, errors.New("store unavailable")
+}
+
+// recordingStore records the keys and policies it is asked about and allows everything.
+type recordingStore struct {
+	mu       sync.Mutex
+	keys     []string
+	policies []core.RateLimitPolicy
+}
+
+func (s *recordingStore) Allow(_ context.Context, key string, policy core.RateLimitPolicy) (core.RateLimitResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys = append(s.keys, key)
+	s.policies = append(s.policies, policy)
+	return core.RateLimitResult{Allowed: true, Limit: policy.Limit, Remaining: policy.Limit - 1, Reset: policy.Window}, nil
+}
+
+// rateLimitCases check RateLimit with both algorithms, its keys and stores,
+// and @RateLimit routes.
+func rateLimitCases() []Case {
+	limited := func(opts core.RateLimitOptions) func(t *testing.T, s core.Server) {
+		return func(t *testing.T, s core.Server) {
+			s.Add(core.MethodGet, "/limited", func(c core.Context) {
+				_ = c.String(core.StatusOK, "ok")
+			}, core.RateLimit(opts))
+		}
+	}
+	// allow gửi request tới path và kiểm tra request được cho qua
+	allow := func(t *testing.T, s core.Server, path string, headers map[string]string) *httptest.ResponseRecorder {
+		t.Helper()
+		res := Do(s, NewRequest(core.MethodGet, RootPath+path, "", headers))
+		ExpectStatus(t, res, core.StatusOK)
+		return res
+	}
+	expectLimited := func(t *testing.T, res *httptest.ResponseRecorder, retryAfter string) {
+		t.Helper()
+		ExpectStatus(t, res, core.StatusTooManyRequests)
+		ExpectHeader(t, res, core.HeaderContentType, core.MIMEApplicationProblemJSON)
+		ExpectHeader(t, res, core.HeaderRateLimitRemaining, "0")
+		if retryAfter != "" {
+			ExpectHeader(t, res, core.HeaderRetryAfter, retryAfter)
+		} else if n, err := strconv.Atoi(res.Header().Get(core.HeaderRetryAfter)); err != nil || n < 1 {
+			t.Fatalf("Retry-After = %q, want seconds", res.Header().Get(core.HeaderRetryAfter))
+		}
+	}
+
+	return []Case{
+		{
+			Name: "RateLimitTokenBucket",
+			Setup: func(t *testing.T, s core.Server) {
+				limited(core.RateLimitOptions{Limit: 2, Window: time.Minute})(t, s)
+				res := allow(t, s, "/limited", nil)
+				ExpectHeader(t, res, core.HeaderRateLimitLimit, "2")
+				ExpectHeader(t, res, core.HeaderRateLimitRemaining, "1")
+				ExpectHeader(t, res, core.HeaderRateLimitReset, "30")
+				ExpectHeader(t, res, core.HeaderRateLimitPolicy, "2;w=60")
+				res = allow(t, s, "/limited", nil)
+				ExpectHeader(t, res, core.HeaderRateLimitRemaining, "0")
+			},
+			Target: RootPath + "/limited",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				expectLimited(t, res, "30")
+				if body := res.Body.String(); !strings.Contains(body, `"retryAfter":30`) || !strings.Contains(body, `"detail":"rate limit exceeded"`) {
+					t.Fatalf("body = %s", body)
+				}
+			},
+		},
+		{
+			Name: "RateLimitBurst",
+			Setup: func(t *testing.T, s core.Server) {
+				limited(core.RateLimitOptions{Limit: 60, Window: time.Minute, Burst: 3})(t, s)
+				for i := 0; i < 3; i++ {
+					res := allow(t, s, "/limited", nil)
+					ExpectHeader(t, res, core.HeaderRateLimitLimit, "3")
+					ExpectHeader(t, res, core.HeaderRateLimitPolicy, "60;w=60;burst=3")
+				}
+			},
+			Target: RootPath + "/limited",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				expectLimited(t, res, "1")
+			},
+		},
+		{
+			Name: "RateLimitSlidingWindow",
+			Setup: func(t *testing.T, s core.Server) {
+				limited(core.RateLimitOptions{Limit: 2, Window: time.Hour, Algorithm: core.SlidingWindow})(t, s)
+				res := allow(t, s, "/limited", nil)
+				ExpectHeader(t, res, core.HeaderRateLimitRemaining, "1")
+				ExpectHeader(t, res, core.HeaderRateLimitPolicy, "2;w=3600")
+				allow(t, s, "/limited", nil)
+			},
+			Target: RootPath + "/limited",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				expectLimited(t, res, "")
+				if n, _ := strconv.Atoi(res.Header().Get(core.HeaderRateLimitReset)); n < 1 || n > 3600 {
+					t.Fatalf("RateLimit-Reset = %d, want within the window", n)
+				}
+			},
+		},
+		{
+			Name: "RateLimitByClient",
+			Setup: func(t *testing.T, s core.Server) {
+				limited(core.RateLimitOptions{Limit: 1, Window: time.Minute})(t, s)
+				allow(t, s, "/limited", nil)
+				req := NewRequest(core.MethodGet, RootPath+"/limited", "", nil)
+				req.RemoteAddr = "198.51.100.7:4000"
+				ExpectStatus(t, Do(s, req), core.StatusOK)
+			},
+			Target: RootPath + "/limited",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				expectLimited(t, res, "60")
+			},
+		},
+		{
+			Name: "RateLimitByAPIKey",
+			Setup: func(t *testing.T, s core.Server) {
+				limited(core.RateLimitOptions{Limit: 1, Window: time.Minute, Key: core.RateLimitByHeader("X-API-Key")})(t, s)
+				allow(t, s, "/limited", map[string]string{"X-API-Key": "a"})
+				allow(t, s, "/limited", map[string]string{"X-API-Key": "b"})
+				// Không có API key thì đếm theo IP
+				allow(t, s, "/limited", nil)
+				ExpectStatus(t, Do(s, NewRequest(core.MethodGet, RootPath+"/limited", "", nil)), core.StatusTooManyRequests)
+			},
+			Target:  RootPath + "/limited",
+			Headers: map[string]string{"X-API-Key": "a"},
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				expectLimited(t, res, "60")
+			},
+		},
+		{
+			Name: "RateLimitStoreMaxKeys",
+			Setup: func(t *testing.T, s core.Server) {
+				store := core.NewMemoryRateLimitStore(64)
+				limited(core.RateLimitOptions{Limit: 1, Window: time.Minute, Key: core.RateLimitByHeader("X-API-Key"), Store: store})(t, s)
+				// API key ngẫu nhiên không làm store lớn quá giới hạn
+				for i := 0; i < 1000; i++ {
+					allow(t, s, "/limited", map[string]string{"X-API-Key": strconv.Itoa(i)})
+				}
+				if n := store.Len(); n > 64 {
+					t.Fatalf("store holds %d keys, want at most 64", n)
+				}
+				allow(t, s, "/limited", map[string]string{"X-API-Key": "a"})
+			},
+			Target:  RootPath + "/limited",
+			Headers: map[string]string{"X-API-Key": "a"},
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				expectLimited(t, res, "60")
+			},
+		},
+		{
+			Name: "RateLimitByUser",
+			Setup: func(t *testing.T, s core.Server) {
+				j := jwt.NewJwt(&jwt.Config{SecretKey: "secret", ExpIn: 60})
+				bearer := func(id string) map[string]string {
+					token, err := j.IssueToken(context.Background(), jwt.UserInfo{ID: id})
+					if err != nil {
+						t.Fatalf("IssueToken() = %v", err)
+					}
+					return map[string]string{core.HeaderAuthorization: "Bearer " + token}
+				}
+				s.AddGroup("/users", func(rg core.RouterGroup) {
+					rg.Add(core.MethodGet, "/me", func(c core.Context) {
+						_ = c.String(core.StatusOK, "ok")
+					})
+				}, jwt.AuthMiddleware(j), core.RateLimit(core.RateLimitOptions{Limit: 1, Window: time.Minute, Key: jwt.RateLimitByUser}))
+				allow(t, s, "/users/me", bearer("u1"))
+				allow(t, s, "/users/me", bearer("u2"))
+				ExpectStatus(t, Do(s, NewRequest(core.MethodGet, RootPath+"/users/me", "", bearer("u1"))), core.StatusTooManyRequests)
+			},
+			Target: RootPath + "/users/me",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				// AuthMiddleware chạy trước nên request không có token không bị đếm
+				ExpectStatus(t, res, core.StatusUnauthorized)
+			},
+		},
+		{
+			Name: "RateLimitStore",
+			Setup: func(t *testing.T, s core.Server) {
+				store := &recordingStore{}
+				limited(core.RateLimitOptions{Limit: 5, Window: time.Second, Store: store, Prefix: "api:", Key: func(c core.Context) string {
+					return "tenant:" + c.Query("tenant")
+				}})(t, s)
+				allow(t, s, "/limited?tenant=acme", nil)
+				want := core.RateLimitPolicy{Algorithm: core.TokenBucket, Limit: 5, Window: time.Second, Burst: 5}
+				if len(store.keys) != 1 || store.keys[0] != "api:tenant:acme" || store.policies[0] != want {
+					t.Fatalf("store got %q %+v", store.keys, store.policies)
+				}
+			},
+			Target: RootPath + "/missing",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusNotFound)
+			},
+		},
+		{
+			Name:   "RateLimitStoreFailure",
+			Setup:  limited(core.RateLimitOptions{Limit: 1, Window: time.Minute, Store: failingStore{}}),
+			Target: RootPath + "/limited",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusOK)
+				if got := res.Header().Get(core.HeaderRateLimitLimit); got != "" {
+					t.Fatalf("RateLimit-Limit = %q, want none", got)
+				}
+			},
+		},
+		{
+			Name: "RateLimitApiTag",
+			Setup: func(t *testing.T, s core.Server) {
+				s.RegisterHandlersWithTags(&TagHandler{})
+				allow(t, s, "/tags/limited", nil)
+				allow(t, s, "/tags/limited", nil)
+				// Route khác không dùng chung bộ đếm
+				allow(t, s, "/tags/hello", nil)
+			},
+			Target: RootPath + "/tags/limited",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				expectLimited(t, res, "30")
+			},
+		},
+		{
+			// Limiter của @RateLimit chạy sau middleware của route, nên Key của
+			// Config.RateLimit thấy user mà middleware đó đặt
+			Name: "RateLimitApiTagConfigKey",
+			Config: func(cfg *core.Config) {
+				cfg.RateLimit = core.RateLimitOptions{Key: func(c core.Context) string {
+					if user := c.GetString("user"); user != "" {
+						return "user:" + user
+					}
+					return ""
+				}}
+			},
+			Setup: func(t *testing.T, s core.Server) {
+				s.RegisterHandlersWithTags(limitedUserHandler{})
+				allow(t, s, "/me", map[string]string{"X-User": "a"})
+				allow(t, s, "/me", map[string]string{"X-User": "b"})
+			},
+			Target:  RootPath + "/me",
+			Headers: map[string]string{"X-User": "a"},
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				expectLimited(t, res, "60")
+			},
+		},
+		{
+			Name: "RateLimitApiTagDevMode",
+			Mode: "debug",
+			Setup: func(t *testing.T, s core.Server) {
+				s.RegisterHandlersWithTags(&DevTagHandler{})
+				res := allow(t, s, "/dev/limited", nil)
+				ExpectHeader(t, res, core.HeaderRateLimitPolicy, "1;w=3600")
+			},
+			Target: RootPath + "/dev/limited",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				expectLimited(t, res, "")
+			},
+		},
+	}
+}
+
+// decompress decodes the body of res with its Content-Encoding.
+func decompress(t *testing.T, res *httptest.ResponseRecorder) string {
+	t.Helper()
+	var r io.Reader
+	switch enc := res.Header().Get(core.HeaderContentEncoding); enc {
+	case core.StrGzip:
+		zr, err := gzip.NewReader(res.Body)
+		if err != nil {
+			t.Fatalf("gzip: %v", err)
+		}
+		r = zr
+	case core.StrDeflate:
+		r = flate.NewReader(res.Body)
+	case core.StrBr:
+		r = brotli.NewReader(res.Body)
+	default:
+		t.Fatalf("Content-Encoding = %q", enc)
+	}
+	body, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("decode %s: %v", res.Header().Get(core.HeaderContentEncoding), err)
+	}
+	return string(body)
+}
+
+// compressCases check Compress: negotiation, the rules deciding what is
+// encoded and the headers of encoded responses.
+func compressCases() []Case {
+	text := strings.TrimSpace(strings.Repeat("compress me ", 200))
+	compressed := func(opts ...core.CompressOptions) func(t *testing.T, s core.Server) {
+		return func(t *testing.T, s core.Server) {
+			s.Use(core.Compress(opts...))
+			s.Add(core.MethodGet, "/text", func(c core.Context) {
+				_ = c.String(core.StatusOK, text)
+			})
+			s.Add(core.MethodGet, "/small", func(c core.Context) {
+				_ = c.String(core.StatusOK, "small")
+			})
+		}
+	}
+	gzipped := map[string]string{core.HeaderAcceptEncoding: "gzip"}
+	expectEncoded := func(t *testing.T, res *httptest.ResponseRecorder, encoding, body string) {
+		t.Helper()
+		ExpectHeader(t, res, core.HeaderContentEncoding, encoding)
+		ExpectHeader(t, res, core.HeaderVary, core.HeaderAcceptEncoding)
+		if cl := res.Header().Get(core.HeaderContentLength); cl != "" && cl != strconv.Itoa(res.Body.Len()) {
+			t.Fatalf("Content-Length = %s, body is %d bytes", cl, res.Body.Len())
+		}
+		if got := decompress(t, res); got != body {
+			t.Fatalf("decoded body = %d bytes, want %d", len(got), len(body))
+		}
+	}
+	expectIdentity := func(t *testing.T, res *httptest.ResponseRecorder, vary bool) {
+		t.Helper()
+		if got := res.Header().Get(core.HeaderContentEncoding); got != "" {
+			t.Fatalf("Content-Encoding = %q, want none", got)
+		}
+		if got := res.Header().Get(core.HeaderVary) != ""; got != vary {
+			t.Fatalf("Vary = %q, want it %t", res.Header().Get(core.HeaderVary), vary)
+		}
+	}
+
+	return []Case{
+		{
+			Name:    "CompressGzip",
+			Setup:   compressed(),
+			Target:  RootPath + "/text",
+			Headers: gzipped,
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusOK)
+				ExpectHeader(t, res, core.HeaderContentType, core.MIMETextPlain)
+				expectEncoded(t, res, core.StrGzip, text)
+				if res.Body.Len() >= len(text) {
+					t.Fatalf("body = %d bytes, not smaller than %d", res.Body.Len(), len(text))
+				}
+			},
+		},
+		{
+			Name: "CompressNegotiation",
+			Setup: func(t *testing.T, s core.Server) {
+				compressed()(t, s)
+				for accept, want := range map[string]string{
+					"gzip, deflate, br":       core.StrBr,
+					"gzip;q=1, br;q=0.5":      core.StrGzip,
+					"deflate":                 core.StrDeflate,
+					"*":                       core.StrBr,
+					"br;q=0, *;q=0.1":         core.StrGzip,
+					"GZIP;q=0.8, deflate;q=1": core.StrDeflate,
+				} {
+					res := Do(s, NewRequest(core.MethodGet, RootPath+"/text", "", map[string]string{core.HeaderAcceptEncoding: accept}))
+					if got := res.Header().Get(core.HeaderContentEncoding); got != want {
+						t.Fatalf("Accept-Encoding %q: Content-Encoding = %q, want %q", accept, got, want)
+					}
+					expectEncoded(t, res, want, text)
+				}
+			},
+			Target:  RootPath + "/text",
+			Headers: map[string]string{core.HeaderAcceptEncoding: "identity, gzip;q=0"},
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectBody(t, res, text)
+				expectIdentity(t, res, true)
+			},
+		},
+		{
+			Name:   "CompressNoAcceptEncoding",
+			Setup:  compressed(),
+			Target: RootPath + "/text",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectBody(t, res, text)
+				expectIdentity(t, res, true)
+			},
+		},
+		{
+			Name:    "CompressSmallBody",
+			Setup:   compressed(),
+			Target:  RootPath + "/small",
+			Headers: gzipped,
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectBody(t, res, "small")
+				expectIdentity(t, res, false)
+			},
+		},
+		{
+			Name: "CompressSkipped",
+			Setup: func(t *testing.T, s core.Server) {
+				s.Use(core.Compress())
+				s.Add(core.MethodGet, "/png", func(c core.Context) {
+					_ = c.Blob(core.StatusOK, "image/png", []byte(text))
+				})
+				s.Add(core.MethodGet, "/encoded", func(c core.Context) {
+					c.SetHeader(core.HeaderContentEncoding, core.StrGzip)
+					_ = c.Blob(core.StatusOK, core.MIMETextPlain, []byte(text))
+				})
+				s.Add(core.MethodGet, "/no-transform", func(c core.Context) {
+					c.SetHeader(core.HeaderCacheControl, "public, no-transform")
+					_ = c.String(core.StatusOK, text)
+				})
+				s.Add(core.MethodGet, "/stream", func(c core.Context) {
+					_ = c.Stream(core.StatusOK, core.MIMETextPlain, strings.NewReader(text))
+				})
+				s.Add(core.MethodGet, "/empty", func(c core.Context) {
+					_ = c.NoContent(core.StatusNoContent)
+				})
+				for _, path := range []string{"/png", "/encoded", "/no-transform", "/stream"} {
+					res := Do(s, NewRequest(core.MethodGet, RootPath+path, "", gzipped))
+					if got := res.Header().Get(core.HeaderContentEncoding); path != "/encoded" && got != "" {
+						t.Fatalf("%s: Content-Encoding = %q, want none", path, got)
+					}
+					if res.Header().Get(core.HeaderVary) != "" || res.Body.String() != text {
+						t.Fatalf("%s: Vary = %q, body = %d bytes", path, res.Header().Get(core.HeaderVary), res.Body.Len())
+					}
+				}
+				res := Do(s, NewRequest(core.MethodHead, RootPath+"/png", "", gzipped))
+				expectIdentity(t, res, false)
+			},
+			Target:  RootPath + "/empty",
+			Headers: gzipped,
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusNoContent)
+				expectIdentity(t, res, false)
+			},
+		},
+		{
+			Name: "CompressFile",
+			Setup: func(t *testing.T, s core.Server) {
+				path := filepath.Join(t.TempDir(), "big.txt")
+				if err := os.WriteFile(path, []byte(text), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				s.Use(core.Compress())
+				s.Add(core.MethodGet, "/file", func(c core.Context) {
+					if err := c.File(path); err != nil {
+						c.Error(err)
+					}
+				})
+				// Range tính trên file gốc nên response 206 không bị nén
+				res := Do(s, NewRequest(core.MethodGet, RootPath+"/file", "", map[string]string{
+					core.HeaderAcceptEncoding: "gzip",
+					"Range":                   "bytes=0-6",
+				}))
+				ExpectStatus(t, res, core.StatusPartialContent)
+				ExpectBody(t, res, "compres")
+			},
+			Target:  RootPath + "/file",
+			Headers: map[string]string{core.HeaderAcceptEncoding: "br"},
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusOK)
+				expectEncoded(t, res, core.StrBr, text)
+				if got := res.Header().Get(core.HeaderAcceptRanges); got != "" {
+					t.Fatalf("Accept-Ranges = %q, want none", got)
+				}
+			},
+		},
+		{
+			// fiber không nén file lớn để khỏi giữ cả file trong bộ nhớ, engine
+			// khác nén trực tiếp; client nào cũng nhận đúng nội dung
+			Name: "CompressLargeFile",
+			Setup: func(t *testing.T, s core.Server) {
+				big := strings.Repeat(text, 9<<20/len(text)+1)
+				path := filepath.Join(t.TempDir(), "large.txt")
+				if err := os.WriteFile(path, []byte(big), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				s.Use(core.Compress())
+				s.Add(core.MethodGet, "/file", func(c core.Context) {
+					if err := c.File(path); err != nil {
+						c.Error(err)
+					}
+				})
+				res := Do(s, NewRequest(core.MethodGet, RootPath+"/file", "", gzipped))
+				ExpectStatus(t, res, core.StatusOK)
+				if res.Header().Get(core.HeaderContentEncoding) != "" {
+					expectEncoded(t, res, core.StrGzip, big)
+				} else if res.Body.String() != big {
+					t.Fatalf("body = %d bytes, want %d", res.Body.Len(), len(big))
+				}
+			},
+			Target: RootPath + "/file",
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusOK)
+				if got := res.Header().Get(core.HeaderContentEncoding); got != "" {
+					t.Fatalf("Content-Encoding = %q, want none", got)
+				}
+			},
+		},
+		{
+			Name: "CompressETagAndLength",
+			Setup: func(t *testing.T, s core.Server) {
+				s.Use(core.Compress())
+				s.Add(core.MethodGet, "/doc", func(c core.Context) {
+					c.SetHeader(core.HeaderETag, `"v1"`)
+					c.SetHeader(core.HeaderContentLength, strconv.Itoa(len(text)))
+					_ = c.Blob(core.StatusOK, core.MIMEApplicationJSON, []byte(text))
+				})
+			},
+			Target:  RootPath + "/doc",
+			Headers: gzipped,
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				expectEncoded(t, res, core.StrGzip, text)
+				ExpectHeader(t, res, core.HeaderETag, `W/"v1"`)
+			},
+		},
+		{
+			Name: "CompressError",
+			Setup: func(t *testing.T, s core.Server) {
+				s.Use(core.Compress())
+				s.Add(core.MethodGet, "/fail", func(c core.Context) {
+					c.Error(core.NewHTTPError(core.StatusBadRequest, text))
+				})
+			},
+			Target:  RootPath + "/fail",
+			Headers: gzipped,
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				ExpectStatus(t, res, core.StatusBadRequest)
+				ExpectHeader(t, res, core.HeaderContentType, core.MIMEApplicationProblemJSON)
+				ExpectHeader(t, res, core.HeaderContentEncoding, core.StrGzip)
+				if body := decompress(t, res); !strings.Contains(body, `"detail":"compress me`) {
+					t.Fatalf("body = %.60s", body)
+				}
+			},
+		},
+		{
+			Name: "CompressOptions",
+			Setup: func(t *testing.T, s core.Server) {
+				compressed(core.CompressOptions{
+					Encodings: []string{core.StrDeflate, core.StrBrotli},
+					MinSize:   4,
+					Types:     []string{"text/*"},
+					Level:     9,
+				})(t, s)
+				res := Do(s, NewRequest(core.MethodGet, RootPath+"/text", "", gzipped))
+				expectIdentity(t, res, true)
+				res = Do(s, NewRequest(core.MethodGet, RootPath+"/text", "", map[string]string{core.HeaderAcceptEncoding: "gzip, br"}))
+				expectEncoded(t, res, core.StrBr, text)
+			},
+			Target:  RootPath + "/small",
+			Headers: map[string]string{core.HeaderAcceptEncoding: "gzip, deflate, br"},
+			Check: func(t *testing.T, res *httptest.ResponseRecorder) {
+				expectEncoded(t, res, core.StrDeflate, "small")
+			},
+		},
+	}
+}
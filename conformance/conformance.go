@@ -0,0 +1,139 @@
+// Package conformance is a table-driven suite that runs every core.Context and
+// core.Server method through an in-process request harness, so all engine
+// adapters can be checked against the same expectations.
+//
+// Example
+//
+//	func TestConformance(t *testing.T) {
+//		conformance.Run(t, func(cfg *core.Config) core.Server { return gin.NewServer(cfg) })
+//	}
+package conformance
+
+import (
+	"github.com/kimxuanhong/go-server/core"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// RootPath is the root path every case runs under, so route patterns and
+// groups are always checked with a prefix.
+const RootPath = "/api"
+
+// Factory builds a fresh server for the given config.
+type Factory func(cfg *core.Config) core.Server
+
+// Case is one conformance check: register routes on a fresh server, send a
+// request and verify the recorded response.
+type Case struct {
+	Name string
+	// Mode overrides the default "test" server mode.
+	Mode string
+	// Config adjusts the server config before the server is built.
+	Config  func(cfg *core.Config)
+	Setup   func(t *testing.T, s core.Server)
+	Method  string
+	Target  string
+	Body    string
+	Headers map[string]string
+	// RemoteAddr overrides the peer address of the request (192.0.2.1:1234).
+	RemoteAddr string
+	// Admin sends the request to s.AdminHandler() instead of s.Handler().
+	Admin bool
+	Check func(t *testing.T, res *httptest.ResponseRecorder)
+}
+
+// Run runs every case in Cases against servers built by newServer.
+func Run(t *testing.T, newServer Factory) {
+	t.Helper()
+	for _, tc := range Cases() {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			mode := tc.Mode
+			if mode == "" {
+				mode = "test"
+			}
+			cfg := &core.Config{
+				Host:     "localhost",
+				Port:     "0",
+				Mode:     mode,
+				RootPath: RootPath,
+			}
+			if tc.Config != nil {
+				tc.Config(cfg)
+			}
+			s := newServer(cfg)
+			if tc.Setup != nil {
+				tc.Setup(t, s)
+			}
+			req := NewRequest(tc.Method, tc.Target, tc.Body, tc.Headers)
+			if tc.RemoteAddr != "" {
+				req.RemoteAddr = tc.RemoteAddr
+			}
+			if !tc.Admin {
+				tc.Check(t, Do(s, req))
+				return
+			}
+			h := s.AdminHandler()
+			if h == nil {
+				t.Fatal("AdminHandler() = nil")
+			}
+			res := httptest.NewRecorder()
+			h.ServeHTTP(res, req)
+			tc.Check(t, res)
+		})
+	}
+}
+
+// NewRequest builds a request for the harness; a JSON Content-Type is set when
+// a body is given and no Content-Type header is.
+func NewRequest(method, target, body string, headers map[string]string) *http.Request {
+	if method == "" {
+		method = http.MethodGet
+	}
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	req := httptest.NewRequest(method, target, reader)
+	if body != "" {
+		req.Header.Set(core.HeaderContentType, core.MIMEApplicationJSON)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	return req
+}
+
+// Do serves req in-process through s.Handler and records the response.
+func Do(s core.Server, req *http.Request) *httptest.ResponseRecorder {
+	res := httptest.NewRecorder()
+	s.Handler().ServeHTTP(res, req)
+	return res
+}
+
+// ExpectStatus fails the test when the response status is not code.
+func ExpectStatus(t *testing.T, res *httptest.ResponseRecorder, code int) {
+	t.Helper()
+	if res.Code != code {
+		t.Fatalf("status = %d, want %d (body %q)", res.Code, code, res.Body.String())
+	}
+}
+
+// ExpectBody fails the test when the trimmed response body is not body.
+func ExpectBody(t *testing.T, res *httptest.ResponseRecorder, body string) {
+	t.Helper()
+	if got := strings.TrimSpace(res.Body.String()); got != body {
+		t.Fatalf("body = %q, want %q", got, body)
+	}
+}
+
+// ExpectHeader fails the test when the response header key does not start with value.
+func ExpectHeader(t *testing.T, res *httptest.ResponseRecorder, key, value string) {
+	t.Helper()
+	if got := res.Header().Get(key); !strings.HasPrefix(got, value) {
+		t.Fatalf("header %s = %q, want prefix %q", key, got, value)
+	}
+}
@@ -0,0 +1,37 @@
+package conformance
+
+import (
+	"github.com/kimxuanhong/go-server/core"
+)
+
+// DevTagHandler has no generated routes, so its @Api annotations are only
+// parsed from source in debug mode.
+// @BaseUrl /dev/
+type DevTagHandler struct {
+}
+
+// Hello API
+// @Api GET /hello
+func (h *DevTagHandler) Hello(c core.Context) {
+	_ = c.String(core.StatusOK, "dev:"+c.Path())
+}
+
+// Wait API
+// @Api GET /wait
+// @Timeout 50ms
+func (h *DevTagHandler) Wait(c core.Context) {
+	waitDeadline(c)
+}
+
+// Limited API
+// @Api GET /limited
+// @RateLimit 1/h sliding-window
+func (h *DevTagHandler) Limited(c core.Context) {
+	_ = c.String(core.StatusOK, "dev:limited")
+}
+
+// Echo API
+// @Api WS /echo
+func (h *DevTagHandler) Echo(conn core.WSConn) {
+	echoMessages(conn, "dev:")
+}
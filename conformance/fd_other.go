@@ -0,0 +1,13 @@
+//go:build !unix
+
+package conformance
+
+import (
+	"errors"
+	"net"
+)
+
+// InheritFD is only supported on unix.
+func InheritFD(ln *net.TCPListener) (int, error) {
+	return 0, errors.New("inherited file descriptors are not supported on this platform")
+}
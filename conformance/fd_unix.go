@@ -0,0 +1,24 @@
+//go:build unix
+
+package conformance
+
+import (
+	"net"
+	"syscall"
+)
+
+// InheritFD returns a duplicate of the file descriptor of ln, as a parent
+// process would pass it to fd:// listeners. The caller owns the descriptor.
+func InheritFD(ln *net.TCPListener) (int, error) {
+	raw, err := ln.SyscallConn()
+	if err != nil {
+		return 0, err
+	}
+	fd, dupErr := -1, error(nil)
+	if err := raw.Control(func(s uintptr) {
+		fd, dupErr = syscall.Dup(int(s))
+	}); err != nil {
+		return 0, err
+	}
+	return fd, dupErr
+}
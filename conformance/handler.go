@@ -0,0 +1,110 @@
+package conformance
+
+//go:generate go run ../cmd/go-server-gen -types TagHandler
+
+import (
+	"github.com/kimxuanhong/go-server/core"
+	"time"
+)
+
+// TagHandler is registered through RegisterHandlersWithTags, using the
+// routes generated by go-server-gen.
+// @BaseUrl /tags/
+// @Tag tags
+type TagHandler struct {
+}
+
+// Greeting is the body and response of TagHandler.Greet.
+type Greeting struct {
+	// Name of the person to greet
+	Name string   `json:"name" binding:"required"`
+	Tags []string `json:"tags,omitempty"`
+}
+
+// GreetRequest binds TagHandler.Greet from the path and the JSON body.
+type GreetRequest struct {
+	Lang string `path:"lang" json:"-"`
+	Greeting
+}
+
+// Hello API
+// @Api GET /hello
+// @Summary Say hello
+func (h *TagHandler) Hello(c core.Context) {
+	_ = c.String(core.StatusOK, "tag:"+c.Path())
+}
+
+// Wait API
+// @Api GET /wait
+// @Summary Wait for the request deadline
+// @Timeout 50ms
+func (h *TagHandler) Wait(c core.Context) {
+	waitDeadline(c)
+}
+
+// waitDeadline blocks until Context() is done, or answers "no deadline"
+// after a second.
+func waitDeadline(c core.Context) {
+	select {
+	case <-c.Context().Done():
+	case <-time.After(time.Second):
+		_ = c.String(core.StatusOK, "no deadline")
+	}
+}
+
+// Greet API
+// @Api POST /greet/:lang
+// @Summary Greet someone in a language
+// @Param lang path string true "Language code"
+// @Param formal query bool false "Use the formal greeting"
+// @Body Greeting "Who to greet"
+// @Success 200 Greeting "The greeting"
+// @Failure 400 "Invalid body"
+func (h *TagHandler) Greet(c core.Context, req *GreetRequest) (*Greeting, error) {
+	return &Greeting{Name: req.Lang + ":" + req.Name, Tags: req.Tags}, nil
+}
+
+// Limited API
+// @Api GET /limited
+// @Summary Answer twice a minute per client
+// @RateLimit 2/m
+func (h *TagHandler) Limited(c core.Context) {
+	_ = c.String(core.StatusOK, "tag:limited")
+}
+
+// Echo API
+// @Api WS /echo
+// @Summary Echo text messages back with the tag prefix
+func (h *TagHandler) Echo(conn core.WSConn) {
+	echoMessages(conn, "tag:")
+}
+
+// echoMessages sends every text message back with prefix until the client
+// closes the connection.
+func echoMessages(conn core.WSConn, prefix string) {
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		if err := conn.WriteText(prefix + string(data)); err != nil {
+			return
+		}
+	}
+}
+
+// ProviderHandler is registered through RegisterHandlers.
+type ProviderHandler struct {
+}
+
+func (h *ProviderHandler) Routes() []core.RouteConfig {
+	return []core.RouteConfig{
+		{
+			Method: core.MethodGet,
+			Path:   "/provided",
+			Handler: func(c core.Context) {
+				_ = c.String(core.StatusOK, "provider:"+c.Path())
+			},
+		},
+	}
+}
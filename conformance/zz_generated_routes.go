@@ -0,0 +1,96 @@
+// Code generated by go-server-gen. DO NOT EDIT.
+
+package conformance
+
+import "github.com/kimxuanhong/go-server/core"
+
+// ApiRoutes returns the routes declared by the @Api annotations of TagHandler.
+func (h *TagHandler) ApiRoutes() []core.RouteConfig {
+	return []core.RouteConfig{
+		{
+			Method:    "WS",
+			Path:      "/tags/echo",
+			WebSocket: h.Echo,
+			Doc: &core.ParseRoute{
+				Path:     "/tags/echo",
+				Method:   "WS",
+				Name:     "Echo",
+				Receiver: "*TagHandler",
+				Summary:  "Echo text messages back with the tag prefix",
+				Tags:     []string{"tags"},
+			},
+		},
+		{
+			Method:  "POST",
+			Path:    "/tags/greet/:lang",
+			Handler: core.MustHandler(h.Greet),
+			Doc: &core.ParseRoute{
+				Path:     "/tags/greet/:lang",
+				Method:   "POST",
+				Name:     "Greet",
+				Receiver: "*TagHandler",
+				Summary:  "Greet someone in a language",
+				Tags:     []string{"tags"},
+				Params: []core.ParseParam{
+					{Name: "lang", In: "path", Type: "string", Required: true, Description: "Language code"},
+					{Name: "formal", In: "query", Type: "bool", Required: false, Description: "Use the formal greeting"},
+				},
+				Body: &core.ParseBody{Type: "Greeting", Description: "Who to greet"},
+				Response: []core.ParseResponse{
+					{Code: "200", Type: "Greeting", Description: "The greeting"},
+					{Code: "400", Type: "", Description: "Invalid body"},
+				},
+				Schemas: map[string]*core.Schema{
+					"Greeting": {Type: "object", Properties: map[string]*core.Schema{
+						"name": {Type: "string", Description: "Name of the person to greet"},
+						"tags": {Type: "array", Items: &core.Schema{Type: "string"}},
+					}, Required: []string{"name"}},
+				},
+			},
+		},
+		{
+			Method:  "GET",
+			Path:    "/tags/hello",
+			Handler: core.MustHandler(h.Hello),
+			Doc: &core.ParseRoute{
+				Path:     "/tags/hello",
+				Method:   "GET",
+				Name:     "Hello",
+				Receiver: "*TagHandler",
+				Summary:  "Say hello",
+				Tags:     []string{"tags"},
+				Schemas:  map[string]*core.Schema{},
+			},
+		},
+		{
+			Method:  "GET",
+			Path:    "/tags/limited",
+			Handler: core.MustHandler(h.Limited),
+			Doc: &core.ParseRoute{
+				Path:      "/tags/limited",
+				Method:    "GET",
+				Name:      "Limited",
+				Receiver:  "*TagHandler",
+				Summary:   "Answer twice a minute per client",
+				RateLimit: "2/m",
+				Tags:      []string{"tags"},
+				Schemas:   map[string]*core.Schema{},
+			},
+		},
+		{
+			Method:  "GET",
+			Path:    "/tags/wait",
+			Handler: core.MustHandler(h.Wait),
+			Doc: &core.ParseRoute{
+				Path:     "/tags/wait",
+				Method:   "GET",
+				Name:     "Wait",
+				Receiver: "*TagHandler",
+				Summary:  "Wait for the request deadline",
+				Timeout:  "50ms",
+				Tags:     []string{"tags"},
+				Schemas:  map[string]*core.Schema{},
+			},
+		},
+	}
+}
@@ -0,0 +1,172 @@
+package core
+
+import (
+	"crypto/subtle"
+	"log"
+	"log/slog"
+	"net"
+	"strings"
+	"time"
+)
+
+// HeaderAdminToken carries the shared admin token when Authorization is used
+// for something else.
+const HeaderAdminToken = "X-Admin-Token"
+
+// LogLevel is the level exposed by the admin /log-level endpoint. Use it as
+// the Level of the slog handlers of the service.
+var LogLevel = new(slog.LevelVar)
+
+// AdminConfig configures the admin control plane (/terminate, /routes,
+// /log-level and the health probes).
+type AdminConfig struct {
+	// Host và Port của listener admin riêng; Port rỗng thì không mở listener admin
+	Host string `mapstructure:"host" yaml:"host"`
+	Port string `mapstructure:"port" yaml:"port"`
+	// Token is a shared secret accepted as "Authorization: Bearer <token>"
+	// or in X-Admin-Token.
+	Token string `mapstructure:"token" yaml:"token"`
+	// LoopbackOnly rejects admin requests that do not come from 127.0.0.1/::1.
+	LoopbackOnly bool `mapstructure:"loopback-only" yaml:"loopback-only"`
+	// DisablePublic stops HealthCheck from registering anything on the
+	// public listener.
+	DisablePublic bool `mapstructure:"disable-public" yaml:"disable-public"`
+}
+
+// Enabled reports whether a separate admin listener is configured.
+func (c AdminConfig) Enabled() bool {
+	return c.Port != ""
+}
+
+func (c AdminConfig) GetAddr() string {
+	return c.Host + ":" + c.Port
+}
+
+// RouteInfo is a registered route, as listed by /routes.
+type RouteInfo struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+}
+
+// AdminEndpoints builds the admin control plane of a server. Adapters fill
+// it in and register its routes on the admin listener, or on the public one
+// from HealthCheck.
+type AdminEndpoints struct {
+	Health    *HealthChecks
+	Routes    func() []RouteInfo
+	Terminate func()
+	// Auth guards the control routes. Without any, only loopback requests
+	// are accepted.
+	Auth []Handler
+	// Credentials reports whether Auth checks credentials (the admin Token
+	// or UseAdmin middleware), not only where the request comes from.
+	Credentials bool
+}
+
+// ProbeRoutes returns /ping, /liveness and /readiness. Probes stay open so
+// orchestrators can call them without credentials.
+func (a *AdminEndpoints) ProbeRoutes() []RouteConfig {
+	return []RouteConfig{
+		{Method: MethodGet, Path: "/ping", Handler: func(c Context) {
+			c.JSON(StatusOK, map[string]string{"message": "pong"})
+		}},
+		{Method: MethodGet, Path: "/liveness", Handler: a.Health.LivenessHandler()},
+		{Method: MethodGet, Path: "/readiness", Handler: a.Health.ReadinessHandler()},
+	}
+}
+
+// ControlRoutes returns /terminate, /routes and /log-level, guarded by Auth.
+func (a *AdminEndpoints) ControlRoutes() []RouteConfig {
+	auth := a.Auth
+	if len(auth) == 0 {
+		auth = []Handler{LoopbackOnly()}
+	}
+	return []RouteConfig{
+		{Method: MethodPost, Path: "/terminate", Middleware: auth, Handler: func(c Context) {
+			log.Printf("terminate requested from %s", c.RemoteAddr())
+			go func() {
+				time.Sleep(1 * time.Second)
+				a.Terminate()
+			}()
+			c.JSON(StatusOK, map[string]string{"status": "terminating"})
+		}},
+		{Method: MethodGet, Path: "/routes", Middleware: auth, Handler: func(c Context) {
+			c.JSON(StatusOK, a.Routes())
+		}},
+		{Method: MethodGet, Path: "/log-level", Middleware: auth, Handler: func(c Context) {
+			c.JSON(StatusOK, map[string]string{"level": LogLevel.Level().String()})
+		}},
+		{Method: MethodPut, Path: "/log-level", Middleware: auth, Handler: func(c Context) {
+			var req struct {
+				Level string `json:"level" validate:"required"`
+			}
+			if !BindAndValidate(c, &req) {
+				return
+			}
+			var level slog.Level
+			if err := level.UnmarshalText([]byte(req.Level)); err != nil {
+				c.Error(NewHTTPError(StatusBadRequest, err.Error()))
+				return
+			}
+			LogLevel.Set(level)
+			c.JSON(StatusOK, map[string]string{"level": level.String()})
+		}},
+	}
+}
+
+// PublicControlRoutes returns the ControlRoutes for the public listener
+// when Auth checks Credentials, and none otherwise: behind a reverse proxy
+// or a sidecar every request comes from loopback.
+func (a *AdminEndpoints) PublicControlRoutes() []RouteConfig {
+	if !a.Credentials {
+		log.Printf("admin control routes not registered on the public listener: set server.admin.token or UseAdmin")
+		return nil
+	}
+	return a.ControlRoutes()
+}
+
+// AdminAuth returns the admin middleware configured by cfg followed by extra,
+// e.g. jwt.AuthMiddleware.
+func AdminAuth(cfg AdminConfig, extra ...Handler) []Handler {
+	var auth []Handler
+	if cfg.LoopbackOnly {
+		auth = append(auth, LoopbackOnly())
+	}
+	if cfg.Token != "" {
+		auth = append(auth, TokenAuth(cfg.Token))
+	}
+	return append(auth, extra...)
+}
+
+// TokenAuth accepts requests carrying token as a Bearer token or in
+// X-Admin-Token.
+func TokenAuth(token string) Handler {
+	return func(c Context) {
+		got := c.Header(HeaderAdminToken)
+		if bearer, ok := strings.CutPrefix(c.Header(HeaderAuthorization), "Bearer "); ok {
+			got = bearer
+		}
+		if got == "" || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			c.SetHeader(HeaderWWWAuthenticate, "Bearer")
+			c.Error(NewHTTPError(StatusUnauthorized, "invalid admin token"))
+			return
+		}
+		c.Next()
+	}
+}
+
+// LoopbackOnly accepts requests whose peer address is a loopback address.
+// Proxy headers are ignored.
+func LoopbackOnly() Handler {
+	return func(c Context) {
+		host, _, err := net.SplitHostPort(c.RemoteAddr())
+		if err != nil {
+			host = c.RemoteAddr()
+		}
+		if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+			c.Error(NewHTTPError(StatusForbidden, "admin endpoints only accept loopback requests"))
+			return
+		}
+		c.Next()
+	}
+}
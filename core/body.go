@@ -0,0 +1,119 @@
+package core
+
+import (
+	"io"
+	"net/http"
+)
+
+// BodyLimitKey is the Context key of the request body limit in bytes, set by
+// the adapters from Limits.MaxBodyBytes and overridden by BodyLimit.
+const BodyLimitKey = "core.bodyLimit"
+
+// BodyLimit caps the request body of the rest of the chain to n bytes. Like
+// Timeout, a BodyLimit closer to the route replaces the global or group one,
+// so an upload route can raise it:
+//
+//	server.Add("POST", "/upload", upload, core.BodyLimit(1<<30))
+//
+// The limit is checked when the body is read (Body, BodyBytes, Bind): a
+// Content-Length above it fails at once, a chunked body once it passes it.
+// The error is an *http.MaxBytesError, rendered as 413.
+func BodyLimit(n int64) Handler {
+	return func(c Context) {
+		c.Set(BodyLimitKey, n)
+		c.Next()
+	}
+}
+
+// GetBodyLimit returns the body limit of the request, or 0 for none.
+func GetBodyLimit(c Context) int64 {
+	n, _ := c.Get(BodyLimitKey).(int64)
+	return n
+}
+
+// LimitRequestBody applies the body limit of c to r.Body, for the net/http
+// adapters. Reading past the limit also closes the connection after the
+// response.
+func LimitRequestBody(c Context, w http.ResponseWriter, r *http.Request) {
+	n := GetBodyLimit(c)
+	if n <= 0 {
+		return
+	}
+	if r.ContentLength > n {
+		r.Body = errorBody{ReadCloser: r.Body, err: &http.MaxBytesError{Limit: n}}
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, n)
+}
+
+// LimitReader applies the body limit of c to r, for engines without
+// http.MaxBytesReader. contentLength < 0 means unknown (chunked). The result
+// is an io.ReadCloser whose Close closes r when r is an io.Closer.
+func LimitReader(c Context, r io.Reader, contentLength int64) io.Reader {
+	n := GetBodyLimit(c)
+	if n <= 0 {
+		return r
+	}
+	if contentLength > n {
+		rc, _ := r.(io.ReadCloser)
+		return errorBody{ReadCloser: rc, err: &http.MaxBytesError{Limit: n}}
+	}
+	return &limitedReader{r: r, remaining: n, limit: n}
+}
+
+// errorBody trả lỗi ngay từ lần đọc đầu, khi Content-Length đã vượt giới hạn
+type errorBody struct {
+	io.ReadCloser
+	err error
+}
+
+func (b errorBody) Read([]byte) (int, error) {
+	return 0, b.err
+}
+
+// Close đóng body gốc nếu có
+func (b errorBody) Close() error {
+	if b.ReadCloser == nil {
+		return nil
+	}
+	return b.ReadCloser.Close()
+}
+
+// limitedReader giống http.MaxBytesReader nhưng không cần ResponseWriter
+type limitedReader struct {
+	r         io.Reader
+	remaining int64
+	limit     int64
+	err       error
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.err != nil {
+		return 0, l.err
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	// Đọc thêm 1 byte để phân biệt body vừa đúng giới hạn với body vượt giới hạn
+	if int64(len(p))-1 > l.remaining {
+		p = p[:l.remaining+1]
+	}
+	n, err := l.r.Read(p)
+	if int64(n) <= l.remaining {
+		l.remaining -= int64(n)
+		l.err = err
+		return n, err
+	}
+	n = int(l.remaining)
+	l.remaining = 0
+	l.err = &http.MaxBytesError{Limit: l.limit}
+	return n, l.err
+}
+
+// Close đóng reader gốc khi nó là io.Closer, để handler đóng được body sớm
+func (l *limitedReader) Close() error {
+	if c, ok := l.r.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
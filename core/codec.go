@@ -0,0 +1,200 @@
+package core
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"github.com/ugorji/go/codec"
+	"google.golang.org/protobuf/proto"
+	"gopkg.in/yaml.v3"
+	"reflect"
+	"sync"
+)
+
+// Codec encodes responses (Negotiate) and decodes request bodies (Bind) of
+// a media type.
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// Supporter is implemented by codecs that only handle some values, like
+// protobuf messages or CSV tables; Offers skips them for other values.
+type Supporter interface {
+	Supports(v interface{}) bool
+}
+
+// codecs là registry media type -> Codec; order là thứ tự ưu tiên khi client
+// chấp nhận nhiều kiểu ngang nhau (ví dụ */*)
+var codecs = struct {
+	sync.RWMutex
+	byType map[string]Codec
+	order  []string
+}{byType: make(map[string]Codec)}
+
+func init() {
+	RegisterCodec(MIMEApplicationJSON, JSONCodec{})
+	RegisterCodec(MIMEApplicationXML, XMLCodec{})
+	RegisterCodec(MIMETextXML, XMLCodec{})
+	RegisterCodec(MIMEApplicationMsgPack, MsgPackCodec{})
+	RegisterCodec("application/x-msgpack", MsgPackCodec{})
+	RegisterCodec(MIMEApplicationProtobuf, ProtobufCodec{})
+	RegisterCodec("application/protobuf", ProtobufCodec{})
+	RegisterCodec(MIMEApplicationYAML, YAMLCodec{})
+	RegisterCodec("application/x-yaml", YAMLCodec{})
+	RegisterCodec(MIMETextCSV, CSVCodec{})
+}
+
+// RegisterCodec sets the codec of mediaType (without parameters), replacing
+// the built-in one if any. New media types come last in the preference order.
+func RegisterCodec(mediaType string, c Codec) {
+	codecs.Lock()
+	defer codecs.Unlock()
+	if _, ok := codecs.byType[mediaType]; !ok {
+		codecs.order = append(codecs.order, mediaType)
+	}
+	codecs.byType[mediaType] = c
+}
+
+// GetCodec returns the codec of mediaType, or nil.
+func GetCodec(mediaType string) Codec {
+	codecs.RLock()
+	defer codecs.RUnlock()
+	return codecs.byType[mediaType]
+}
+
+// CodecTypes returns the registered media types in preference order.
+func CodecTypes() []string {
+	codecs.RLock()
+	defer codecs.RUnlock()
+	return append([]string(nil), codecs.order...)
+}
+
+// codecIndex là vị trí của mediaType trong thứ tự ưu tiên, -1 nếu chưa đăng ký
+func codecIndex(mediaType string) int {
+	codecs.RLock()
+	defer codecs.RUnlock()
+	for i, t := range codecs.order {
+		if t == mediaType {
+			return i
+		}
+	}
+	return -1
+}
+
+type JSONCodec struct{}
+
+func (JSONCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (JSONCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+// XMLCodec writes the XML header, like Context.XML.
+type XMLCodec struct{}
+
+// Supports rejects the values encoding/xml cannot encode: maps, channels,
+// funcs and complex numbers, also inside slices, structs and interfaces.
+func (XMLCodec) Supports(v interface{}) bool {
+	return xmlEncodable(reflect.ValueOf(v), 0)
+}
+
+var xmlMarshalerType = reflect.TypeOf((*xml.Marshaler)(nil)).Elem()
+
+// xmlEncodable duyệt giá trị như xml.Marshal; depth chặn vòng lặp con trỏ
+func xmlEncodable(v reflect.Value, depth int) bool {
+	if !v.IsValid() || depth > 32 {
+		return true
+	}
+	if v.Type().Implements(xmlMarshalerType) {
+		return true
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil() || xmlEncodable(v.Elem(), depth+1)
+	case reflect.Map, reflect.Chan, reflect.Func, reflect.Complex64, reflect.Complex128, reflect.UnsafePointer:
+		return false
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return true
+		}
+		for i := 0; i < v.Len(); i++ {
+			if !xmlEncodable(v.Index(i), depth+1) {
+				return false
+			}
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() || f.Tag.Get("xml") == "-" {
+				continue
+			}
+			if !xmlEncodable(v.Field(i), depth+1) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (XMLCodec) Marshal(v interface{}) ([]byte, error) {
+	body, err := xml.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), body...), nil
+}
+
+func (XMLCodec) Unmarshal(data []byte, v interface{}) error {
+	return xml.Unmarshal(data, v)
+}
+
+// MsgPackCodec dùng cùng thư viện với binding msgpack của gin
+type MsgPackCodec struct{}
+
+func (MsgPackCodec) Marshal(v interface{}) ([]byte, error) {
+	var out []byte
+	err := codec.NewEncoderBytes(&out, new(codec.MsgpackHandle)).Encode(v)
+	return out, err
+}
+
+func (MsgPackCodec) Unmarshal(data []byte, v interface{}) error {
+	return codec.NewDecoderBytes(data, new(codec.MsgpackHandle)).Decode(v)
+}
+
+// ProtobufCodec handles proto.Message values only.
+type ProtobufCodec struct{}
+
+func (ProtobufCodec) Supports(v interface{}) bool {
+	_, ok := v.(proto.Message)
+	return ok
+}
+
+func (ProtobufCodec) Marshal(v interface{}) ([]byte, error) {
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("protobuf: %T is not a proto.Message", v)
+	}
+	return proto.Marshal(msg)
+}
+
+func (ProtobufCodec) Unmarshal(data []byte, v interface{}) error {
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("protobuf: %T is not a proto.Message", v)
+	}
+	return proto.Unmarshal(data, msg)
+}
+
+type YAMLCodec struct{}
+
+func (YAMLCodec) Marshal(v interface{}) ([]byte, error) {
+	return yaml.Marshal(v)
+}
+
+func (YAMLCodec) Unmarshal(data []byte, v interface{}) error {
+	return yaml.Unmarshal(data, v)
+}
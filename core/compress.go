@@ -0,0 +1,397 @@
+package core
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"fmt"
+	"github.com/andybalholm/brotli"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// DefaultCompressMinSize is the smallest body Compress encodes; smaller ones
+// are not worth the overhead.
+const DefaultCompressMinSize = 1024
+
+// DefaultCompressTypes are the content types Compress encodes when
+// CompressOptions.Types is empty.
+var DefaultCompressTypes = []string{
+	"text/*",
+	MIMEApplicationJSON,
+	MIMEApplicationProblemJSON,
+	MIMEApplicationJavaScript,
+	MIMEApplicationXML,
+	MIMEApplicationYAML,
+	"application/*+json",
+	"application/*+xml",
+	"image/svg+xml",
+}
+
+// CompressOptions configures Compress; zero values use the defaults.
+type CompressOptions struct {
+	// Encodings the server offers, preferred first, among StrBr (or
+	// StrBrotli), StrGzip and StrDeflate; empty means br, gzip, deflate.
+	Encodings []string
+	// MinSize is the smallest body encoded; 0 means DefaultCompressMinSize,
+	// a negative value encodes every body.
+	MinSize int
+	// Types are the content types encoded: exact (application/json),
+	// type/* or with a structured suffix (application/*+json). Empty means
+	// DefaultCompressTypes.
+	Types []string
+	// Level is the gzip and deflate level (1-9, -2 for Huffman only); 0
+	// means gzip.DefaultCompression.
+	Level int
+	// BrotliLevel is the brotli level (1-11); 0 means brotli.DefaultCompression.
+	BrotliLevel int
+}
+
+// ResponseHeader is the response header Compress edits: an http.Header,
+// or the engine's header behind the same methods.
+type ResponseHeader interface {
+	Get(key string) string
+	Set(key, value string)
+	Add(key, value string)
+	Del(key string)
+}
+
+// ResponseCompressor is implemented by the Contexts of the adapters, so
+// Compress can encode the body the rest of the chain writes. finish is
+// called once the chain is done; the net/http adapters put a
+// CompressWriter in front of their writer, fiber encodes the buffered body
+// in finish.
+type ResponseCompressor interface {
+	CompressResponse(z *Compression) (finish func())
+}
+
+// Compress returns the middleware encoding responses with the encoding
+// the Accept-Encoding header prefers. A response is encoded when its
+// status has a body, its Content-Type is allowed, it is not encoded yet
+// (Content-Encoding) or marked no-transform, and it has at least MinSize
+// bytes; such a response gets Vary: Accept-Encoding whether it is encoded
+// or not. Content-Length and Accept-Ranges are dropped and a strong ETag
+// is made weak when encoding. Streams are sent as is: on the net/http
+// engines a response flushed before it is encoded, on fiber a body stream
+// of unknown length (Stream, SSE). HEAD requests are not encoded.
+// It panics if an encoding or a level is invalid.
+func Compress(opts ...CompressOptions) Handler {
+	var opt CompressOptions
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+	p := newCompressPolicy(opt)
+	return func(c Context) {
+		rc, ok := c.(ResponseCompressor)
+		if !ok || c.Method() == MethodHead {
+			c.Next()
+			return
+		}
+		// Accept-Encoding được đọc trước vì fiber xoá nó khi gửi file
+		z := &Compression{policy: p, encoding: p.negotiate(c.Header(HeaderAcceptEncoding))}
+		finish := rc.CompressResponse(z)
+		defer finish()
+		c.Next()
+	}
+}
+
+// Compression is the compression of one response: the encoding the client
+// prefers and the rules of Compress deciding whether the response is encoded.
+type Compression struct {
+	policy *compressPolicy
+	// encoding là encoding client chấp nhận, "" khi không có
+	encoding string
+}
+
+// Decide returns the encoding of a response with status, header h and
+// size bytes, or "" to send it as is. It adds Vary: Accept-Encoding when the
+// response could be encoded.
+func (z *Compression) Decide(h ResponseHeader, status, size int) string {
+	if !z.policy.eligible(h, status, size) {
+		return ""
+	}
+	h.Add(HeaderVary, HeaderAcceptEncoding)
+	return z.encoding
+}
+
+// SetHeaders marks h as encoded with encoding.
+func (z *Compression) SetHeaders(h ResponseHeader, encoding string) {
+	h.Set(HeaderContentEncoding, encoding)
+	h.Del(HeaderContentLength)
+	// Range của request tính trên bản chưa nén
+	h.Del(HeaderAcceptRanges)
+	if etag := h.Get(HeaderETag); etag != "" && !strings.HasPrefix(etag, "W/") {
+		h.Set(HeaderETag, "W/"+etag)
+	}
+}
+
+// NewWriter returns the encoder of encoding writing to w; Close flushes it
+// without closing w.
+func (z *Compression) NewWriter(encoding string, w io.Writer) io.WriteCloser {
+	switch encoding {
+	case StrBr:
+		return brotli.NewWriterLevel(w, z.policy.brotliLevel)
+	case StrDeflate:
+		fw, _ := flate.NewWriter(w, z.policy.level)
+		return fw
+	default:
+		gw, _ := gzip.NewWriterLevel(w, z.policy.level)
+		return gw
+	}
+}
+
+// compressPolicy là CompressOptions đã chuẩn hoá
+type compressPolicy struct {
+	encodings   []string
+	minSize     int
+	types       []string
+	level       int
+	brotliLevel int
+}
+
+func newCompressPolicy(opts CompressOptions) *compressPolicy {
+	p := &compressPolicy{minSize: opts.MinSize, types: opts.Types, level: opts.Level, brotliLevel: opts.BrotliLevel}
+	encodings := opts.Encodings
+	if len(encodings) == 0 {
+		encodings = []string{StrBr, StrGzip, StrDeflate}
+	}
+	for _, enc := range encodings {
+		switch enc = strings.ToLower(enc); enc {
+		case StrBr, StrBrotli:
+			p.encodings = append(p.encodings, StrBr)
+		case StrGzip, StrDeflate:
+			p.encodings = append(p.encodings, enc)
+		default:
+			panic(fmt.Sprintf("compress: unknown encoding %q", enc))
+		}
+	}
+	if p.minSize == 0 {
+		p.minSize = DefaultCompressMinSize
+	}
+	if len(p.types) == 0 {
+		p.types = DefaultCompressTypes
+	}
+	if p.level == 0 {
+		p.level = gzip.DefaultCompression
+	}
+	if p.level < gzip.HuffmanOnly || p.level > gzip.BestCompression {
+		panic(fmt.Sprintf("compress: invalid level %d", p.level))
+	}
+	if p.brotliLevel == 0 {
+		p.brotliLevel = brotli.DefaultCompression
+	}
+	if p.brotliLevel < brotli.BestSpeed || p.brotliLevel > brotli.BestCompression {
+		panic(fmt.Sprintf("compress: invalid brotli level %d", p.brotliLevel))
+	}
+	return p
+}
+
+// negotiate chọn encoding có q cao nhất trong Accept-Encoding, hoà thì theo thứ tự của server
+func (p *compressPolicy) negotiate(accept string) string {
+	qualities := make(map[string]float64)
+	for _, part := range strings.Split(accept, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding == "" {
+			continue
+		}
+		q := 1.0
+		if name, value, ok := strings.Cut(strings.TrimSpace(params), "="); ok && strings.TrimSpace(name) == "q" {
+			var err error
+			if q, err = strconv.ParseFloat(strings.TrimSpace(value), 64); err != nil {
+				continue
+			}
+		}
+		qualities[coding] = q
+	}
+	best, bestQ := "", 0.0
+	for _, enc := range p.encodings {
+		q, ok := qualities[enc]
+		if !ok {
+			q = qualities["*"]
+		}
+		if q > bestQ {
+			best, bestQ = enc, q
+		}
+	}
+	return best
+}
+
+// eligible báo response có thể nén, không phụ thuộc client
+func (p *compressPolicy) eligible(h ResponseHeader, status, size int) bool {
+	if status < StatusOK || status == StatusNoContent || status == StatusPartialContent || status == StatusNotModified {
+		return false
+	}
+	if size < p.minSize {
+		return false
+	}
+	if enc := h.Get(HeaderContentEncoding); enc != "" && !strings.EqualFold(enc, "identity") {
+		return false
+	}
+	for _, directive := range strings.Split(h.Get(HeaderCacheControl), ",") {
+		if strings.EqualFold(strings.TrimSpace(directive), "no-transform") {
+			return false
+		}
+	}
+	mediaType, _, err := mime.ParseMediaType(h.Get(HeaderContentType))
+	if err != nil {
+		return false
+	}
+	typ, subtype, _ := strings.Cut(mediaType, "/")
+	for _, allowed := range p.types {
+		t, s, _ := strings.Cut(strings.ToLower(allowed), "/")
+		switch {
+		case t != typ:
+		case s == "*" || s == subtype:
+			return true
+		case strings.HasPrefix(s, "*+") && strings.HasSuffix(subtype, s[1:]):
+			return true
+		}
+	}
+	return false
+}
+
+// CompressWriter encodes the body written to an http.ResponseWriter with a
+// Compression. It holds the status and up to MinSize bytes until it can
+// decide, so Close must be called once the handler is done; Flush before
+// that sends the response as is.
+type CompressWriter struct {
+	http.ResponseWriter
+	z           *Compression
+	status      int
+	wroteHeader bool
+	decided     bool
+	buf         []byte
+	enc         io.WriteCloser
+}
+
+func NewCompressWriter(w http.ResponseWriter, z *Compression) *CompressWriter {
+	return &CompressWriter{ResponseWriter: w, z: z, status: StatusOK}
+}
+
+// WriteHeader holds the status until the body decides the encoding;
+// informational statuses are sent right away.
+func (w *CompressWriter) WriteHeader(code int) {
+	if code < StatusOK {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if w.wroteHeader {
+		return
+	}
+	w.status, w.wroteHeader = code, true
+}
+
+func (w *CompressWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(StatusOK)
+	}
+	if !w.decided {
+		// Content-Length do handler đặt cho biết kích thước ngay
+		if n, err := strconv.Atoi(w.Header().Get(HeaderContentLength)); err == nil && len(w.buf) == 0 {
+			if err := w.decide(n); err != nil {
+				return 0, err
+			}
+		} else {
+			w.buf = append(w.buf, b...)
+			if len(w.buf) < w.z.policy.minSize {
+				return len(b), nil
+			}
+			return len(b), w.decide(len(w.buf))
+		}
+	}
+	if w.enc != nil {
+		return w.enc.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// WriteString lets io.WriteString use the buffer of Write.
+func (w *CompressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Written reports whether the status or body has been written, even if
+// it is still held.
+func (w *CompressWriter) Written() bool {
+	return w.wroteHeader
+}
+
+// Status returns the status written, or 200.
+func (w *CompressWriter) Status() int {
+	return w.status
+}
+
+// decide chọn encoding theo size, gửi status và phần đã giữ
+func (w *CompressWriter) decide(size int) error {
+	w.decided = true
+	encoding := w.z.Decide(w.Header(), w.status, size)
+	if encoding != "" {
+		w.z.SetHeaders(w.Header(), encoding)
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+	if encoding != "" {
+		w.enc = w.z.NewWriter(encoding, w.ResponseWriter)
+	}
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	var err error
+	if w.enc != nil {
+		_, err = w.enc.Write(buf)
+	} else {
+		_, err = w.ResponseWriter.Write(buf)
+	}
+	return err
+}
+
+// FlushError sends what is written so far; before the encoding is
+// decided, the response is a stream and is sent as is.
+func (w *CompressWriter) FlushError() error {
+	if !w.decided {
+		w.decided, w.wroteHeader = true, true
+		w.ResponseWriter.WriteHeader(w.status)
+		if len(w.buf) > 0 {
+			if _, err := w.ResponseWriter.Write(w.buf); err != nil {
+				return err
+			}
+			w.buf = nil
+		}
+	}
+	if f, ok := w.enc.(interface{ Flush() error }); ok {
+		if err := f.Flush(); err != nil {
+			return err
+		}
+	}
+	return http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Flush implements http.Flusher.
+func (w *CompressWriter) Flush() {
+	_ = w.FlushError()
+}
+
+// Close sends the held response and flushes the encoder. Nothing is sent
+// when nothing was written, so a status set later is still honoured.
+func (w *CompressWriter) Close() error {
+	if !w.decided {
+		if !w.wroteHeader {
+			return nil
+		}
+		if err := w.decide(len(w.buf)); err != nil {
+			return err
+		}
+	}
+	if w.enc != nil {
+		return w.enc.Close()
+	}
+	return nil
+}
+
+func (w *CompressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
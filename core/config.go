@@ -3,6 +3,9 @@ package core
 import (
 	"github.com/spf13/viper"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // Config defines server configuration.
@@ -11,20 +14,89 @@ type Config struct {
 	Port     string `mapstructure:"port" yaml:"port"`
 	Mode     string `mapstructure:"mode" yaml:"mode"`
 	RootPath string `mapstructure:"root-path" yaml:"root-path"`
-	Engine   string `mapstructure:"engine" yaml:"engine"` //gin, fiber, echo
+	Engine   string `mapstructure:"engine" yaml:"engine"` //gin, fiber, echo, std
+	// Listeners thay cho Host/Port khi khác rỗng: tcp://host:port, unix:///path?mode=0660, fd://3
+	Listeners []string `mapstructure:"listeners" yaml:"listeners"`
+	// Protocols là các giao thức được phục vụ (http1, h2, h2c, h3); rỗng là http1 và h2
+	Protocols []string `mapstructure:"protocols" yaml:"protocols"`
+	// Timeouts và Limits chống slowloris, map sang tham số riêng của từng engine
+	Timeouts TimeoutsConfig `mapstructure:"timeouts" yaml:"timeouts"`
+	Limits   LimitsConfig   `mapstructure:"limits" yaml:"limits"`
+	// Multipart giới hạn upload (kích thước file, kiểu file) và nơi lưu file
+	Multipart MultipartConfig `mapstructure:"multipart" yaml:"multipart"`
+	// TLS bật HTTPS (và mTLS) khi có CertFile
+	TLS TLSConfig `mapstructure:"tls" yaml:"tls"`
+	// CORS trả lời request cross-origin (kể cả preflight) của mọi path khi có AllowOrigins
+	CORS CORSConfig `mapstructure:"cors" yaml:"cors"`
+	// Admin là control plane (terminate, routes, log level), có thể chạy trên listener riêng
+	Admin AdminConfig `mapstructure:"admin" yaml:"admin"`
+	// RateLimit là gốc của limiter các route @RateLimit: Key, Store và Burst được
+	// dùng, Limit, Window và Algorithm lấy từ annotation. Chỉ đặt được bằng code.
+	RateLimit RateLimitOptions `mapstructure:"-" yaml:"-"`
 }
 
 func (c *Config) GetAddr() string {
 	return c.Host + ":" + c.Port
 }
 
+// GetProtocols returns the configured protocols, or DefaultProtocols.
+func (c *Config) GetProtocols() []string {
+	if len(c.Protocols) == 0 {
+		return DefaultProtocols
+	}
+	return c.Protocols
+}
+
 func NewConfig() *Config {
 	return &Config{
-		Host:     getEnv("SERVER_HOST", "localhost"),
-		Port:     getEnv("SERVER_PORT", "8080"),
-		Mode:     getEnv("SERVER_MODE", "debug"),
-		RootPath: getEnv("SERVER_ROOT_PATH", ""),
-		Engine:   getEnv("SERVER_ENGINE", "gin"),
+		Host:      getEnv("SERVER_HOST", "localhost"),
+		Port:      getEnv("SERVER_PORT", "8080"),
+		Mode:      getEnv("SERVER_MODE", "debug"),
+		RootPath:  getEnv("SERVER_ROOT_PATH", ""),
+		Engine:    getEnv("SERVER_ENGINE", "gin"),
+		Listeners: splitList(getEnv("SERVER_LISTENERS", "")),
+		Protocols: splitList(getEnv("SERVER_PROTOCOLS", "")),
+		Timeouts: TimeoutsConfig{
+			ReadHeader: getEnvDuration("SERVER_TIMEOUTS_READ_HEADER"),
+			Read:       getEnvDuration("SERVER_TIMEOUTS_READ"),
+			Write:      getEnvDuration("SERVER_TIMEOUTS_WRITE"),
+			Idle:       getEnvDuration("SERVER_TIMEOUTS_IDLE"),
+		},
+		Limits: LimitsConfig{
+			MaxHeaderBytes: getEnvInt("SERVER_LIMITS_MAX_HEADER_BYTES"),
+			MaxBodyBytes:   int64(getEnvInt("SERVER_LIMITS_MAX_BODY_BYTES")),
+			MaxConnections: getEnvInt("SERVER_LIMITS_MAX_CONNECTIONS"),
+		},
+		Multipart: MultipartConfig{
+			MaxMemory:    int64(getEnvInt("SERVER_MULTIPART_MAX_MEMORY")),
+			MaxFileSize:  int64(getEnvInt("SERVER_MULTIPART_MAX_FILE_SIZE")),
+			MaxTotalSize: int64(getEnvInt("SERVER_MULTIPART_MAX_TOTAL_SIZE")),
+			AllowedTypes: splitList(getEnv("SERVER_MULTIPART_ALLOWED_TYPES", "")),
+		},
+		TLS: TLSConfig{
+			CertFile:       getEnv("SERVER_TLS_CERT_FILE", ""),
+			KeyFile:        getEnv("SERVER_TLS_KEY_FILE", ""),
+			MinVersion:     getEnv("SERVER_TLS_MIN_VERSION", ""),
+			CipherSuites:   splitList(getEnv("SERVER_TLS_CIPHER_SUITES", "")),
+			ClientCAFile:   getEnv("SERVER_TLS_CLIENT_CA_FILE", ""),
+			ClientAuth:     getEnv("SERVER_TLS_CLIENT_AUTH", ""),
+			ReloadInterval: getEnvDuration("SERVER_TLS_RELOAD_INTERVAL"),
+		},
+		CORS: CORSConfig{
+			AllowOrigins:     splitList(getEnv("SERVER_CORS_ALLOW_ORIGINS", "")),
+			AllowMethods:     splitList(getEnv("SERVER_CORS_ALLOW_METHODS", "")),
+			AllowHeaders:     splitList(getEnv("SERVER_CORS_ALLOW_HEADERS", "")),
+			ExposeHeaders:    splitList(getEnv("SERVER_CORS_EXPOSE_HEADERS", "")),
+			AllowCredentials: getEnv("SERVER_CORS_ALLOW_CREDENTIALS", "false") == "true",
+			MaxAge:           getEnvDuration("SERVER_CORS_MAX_AGE"),
+		},
+		Admin: AdminConfig{
+			Host:          getEnv("SERVER_ADMIN_HOST", "localhost"),
+			Port:          getEnv("SERVER_ADMIN_PORT", ""),
+			Token:         getEnv("SERVER_ADMIN_TOKEN", ""),
+			LoopbackOnly:  getEnv("SERVER_ADMIN_LOOPBACK_ONLY", "false") == "true",
+			DisablePublic: getEnv("SERVER_ADMIN_DISABLE_PUBLIC", "false") == "true",
+		},
 	}
 }
 
@@ -36,6 +108,28 @@ func getEnv(key, defaultValue string) string {
 	return value
 }
 
+// getEnvDuration đọc duration (10s, 1m); rỗng hoặc sai định dạng trả 0
+func getEnvDuration(key string) time.Duration {
+	d, _ := time.ParseDuration(os.Getenv(key))
+	return d
+}
+
+func getEnvInt(key string) int {
+	n, _ := strconv.Atoi(os.Getenv(key))
+	return n
+}
+
+// splitList tách danh sách phân cách bởi dấu phẩy, bỏ phần tử rỗng
+func splitList(value string) []string {
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
 func GetConfig(configs ...*Config) *Config {
 	if len(configs) > 0 && configs[0] != nil {
 		return configs[0]
@@ -45,11 +139,55 @@ func GetConfig(configs ...*Config) *Config {
 	viper.SetDefault("server.mode", "debug")
 	viper.SetDefault("server.root-path", "")
 	viper.SetDefault("server.engine", "gin")
+	viper.SetDefault("server.admin.host", "localhost")
 	return &Config{
-		Host:     viper.GetString("server.host"),
-		Port:     viper.GetString("server.port"),
-		Mode:     viper.GetString("server.mode"),
-		RootPath: viper.GetString("server.root-path"),
-		Engine:   viper.GetString("server.engine"),
+		Host:      viper.GetString("server.host"),
+		Port:      viper.GetString("server.port"),
+		Mode:      viper.GetString("server.mode"),
+		RootPath:  viper.GetString("server.root-path"),
+		Engine:    viper.GetString("server.engine"),
+		Listeners: viper.GetStringSlice("server.listeners"),
+		Protocols: viper.GetStringSlice("server.protocols"),
+		Timeouts: TimeoutsConfig{
+			ReadHeader: viper.GetDuration("server.timeouts.read-header"),
+			Read:       viper.GetDuration("server.timeouts.read"),
+			Write:      viper.GetDuration("server.timeouts.write"),
+			Idle:       viper.GetDuration("server.timeouts.idle"),
+		},
+		Limits: LimitsConfig{
+			MaxHeaderBytes: viper.GetInt("server.limits.max-header-bytes"),
+			MaxBodyBytes:   viper.GetInt64("server.limits.max-body-bytes"),
+			MaxConnections: viper.GetInt("server.limits.max-connections"),
+		},
+		Multipart: MultipartConfig{
+			MaxMemory:    viper.GetInt64("server.multipart.max-memory"),
+			MaxFileSize:  viper.GetInt64("server.multipart.max-file-size"),
+			MaxTotalSize: viper.GetInt64("server.multipart.max-total-size"),
+			AllowedTypes: viper.GetStringSlice("server.multipart.allowed-types"),
+		},
+		TLS: TLSConfig{
+			CertFile:       viper.GetString("server.tls.cert-file"),
+			KeyFile:        viper.GetString("server.tls.key-file"),
+			MinVersion:     viper.GetString("server.tls.min-version"),
+			CipherSuites:   viper.GetStringSlice("server.tls.cipher-suites"),
+			ClientCAFile:   viper.GetString("server.tls.client-ca-file"),
+			ClientAuth:     viper.GetString("server.tls.client-auth"),
+			ReloadInterval: viper.GetDuration("server.tls.reload-interval"),
+		},
+		CORS: CORSConfig{
+			AllowOrigins:     viper.GetStringSlice("server.cors.allow-origins"),
+			AllowMethods:     viper.GetStringSlice("server.cors.allow-methods"),
+			AllowHeaders:     viper.GetStringSlice("server.cors.allow-headers"),
+			ExposeHeaders:    viper.GetStringSlice("server.cors.expose-headers"),
+			AllowCredentials: viper.GetBool("server.cors.allow-credentials"),
+			MaxAge:           viper.GetDuration("server.cors.max-age"),
+		},
+		Admin: AdminConfig{
+			Host:          viper.GetString("server.admin.host"),
+			Port:          viper.GetString("server.admin.port"),
+			Token:         viper.GetString("server.admin.token"),
+			LoopbackOnly:  viper.GetBool("server.admin.loopback-only"),
+			DisablePublic: viper.GetBool("server.admin.disable-public"),
+		},
 	}
 }
@@ -1,16 +1,39 @@
 package core
 
-import "context"
+import (
+	"context"
+	"crypto/x509"
+	"io"
+	"mime/multipart"
+)
 
 type Context interface {
-	// Context Request-scoped context (for timeouts, cancellation)
+	// Context Request-scoped context (for timeouts, cancellation). On the
+	// net/http engines it is also cancelled when the client goes away.
 	Context() context.Context
+	// SetContext replaces the request context for the rest of the chain.
+	SetContext(ctx context.Context)
 
 	// Param Input
 	Param(name string) string
 	Query(name string) string
 	Header(name string) string
+	// Bind decodes the body with the Codec of its Content-Type (forms with
+	// the engine), then validates obj.
 	Bind(obj interface{}) error
+	// Body streams the request body, within the limit of BodyLimit or
+	// Limits.MaxBodyBytes; reading past it returns an *http.MaxBytesError.
+	Body() io.Reader
+	// BodyBytes reads the whole request body, within the same limit.
+	BodyBytes() ([]byte, error)
+	// FormValue returns a field of a multipart or URL-encoded body.
+	FormValue(name string) string
+	// FormFile and MultipartForm parse the multipart body once, within the
+	// limits of MultipartConfig; see StreamUploads for large files.
+	FormFile(name string) (*multipart.FileHeader, error)
+	MultipartForm() (*multipart.Form, error)
+	// SaveUploadedFile writes file to the Storage of MultipartConfig.
+	SaveUploadedFile(file *multipart.FileHeader, dst string) error
 
 	// JSON Output
 	JSON(code int, obj interface{})
@@ -18,10 +41,54 @@ type Context interface {
 	AbortWithStatusJSON(code int, obj interface{})
 	String(code int, msg string) error
 	Status(code int) Context
+	// XML writes obj as XML with the XML header.
+	XML(code int, obj interface{}) error
+	// HTML renders the template name of Server.SetHTMLTemplate with data.
+	HTML(code int, name string, data interface{}) error
+	// Blob writes data; an empty contentType sets no Content-Type (fiber
+	// still sends its text/plain default).
+	Blob(code int, contentType string, data []byte) error
+	// Stream copies r to the response, flushing as it is read, and closes r
+	// when it is an io.Closer. Fiber sends it after the handler returns.
+	Stream(code int, contentType string, r io.Reader) error
+	// File sends the file at path with the Content-Type of its extension and
+	// range requests; a missing file or a directory is a 404 error.
+	File(path string) error
+	// Attachment is File with Content-Disposition: attachment; filename
+	// defaults to the base of path.
+	Attachment(path, filename string) error
+	// Redirect sends a 3xx redirect to location.
+	Redirect(code int, location string) error
+	// NoContent writes the status without a body, e.g. 204.
+	NoContent(code int) error
+	// Negotiate writes the offer (media type -> value) that best matches
+	// the Accept header with the registered Codec, or returns a 406 error.
+	Negotiate(code int, offers map[string]interface{}) error
+	// SSE answers with a Server-Sent Events stream and runs fn to send the
+	// events; it returns fn's error, or nil once the client has gone. Fiber
+	// runs fn after the handler returns and only logs its error.
+	SSE(fn func(stream EventStream) error) error
 	SetHeader(key, value string)
+	// AddHeader adds value to the response header key, e.g. to Vary.
+	AddHeader(key, value string)
+	// Written reports whether the response status or body has been written.
+	Written() bool
+	// Error renders err with the server's ErrorHandler (problem+json by
+	// default) and aborts the chain.
+	Error(err error)
 
 	Method() string
+	// Protocol returns the protocol of the request, e.g. HTTP/1.1 or HTTP/2.0.
+	Protocol() string
+	// RemoteAddr returns the network address of the peer (ip:port), without
+	// looking at proxy headers.
+	RemoteAddr() string
+	// ClientCertificate returns the client certificate verified by mutual
+	// TLS, or nil.
+	ClientCertificate() *x509.Certificate
+	// Path returns the matched route pattern (e.g. /users/:id), including the root path.
 	Path() string
+	// Next runs the rest of the chain; Abort stops it.
 	Next()
 
 	// Raw access if needed
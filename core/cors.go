@@ -0,0 +1,209 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultCORSMethods are the methods allowed when CORSConfig.AllowMethods is empty.
+var DefaultCORSMethods = []string{MethodGet, MethodHead, MethodPost, MethodPut, MethodPatch, MethodDelete}
+
+// CORSConfig configures the CORS middleware (server.cors). Servers answer
+// the CORS requests of every path with it when Enabled, preflights included.
+type CORSConfig struct {
+	// AllowOrigins are the allowed origins: exact (https://app.example.com),
+	// "*" for any, wildcards (https://*.example.com) or, starting with ^, a
+	// regular expression matched against the whole Origin.
+	AllowOrigins []string `mapstructure:"allow-origins" yaml:"allow-origins"`
+	// AllowOriginFunc allows the origins it returns true for, besides AllowOrigins.
+	AllowOriginFunc func(origin string) bool `mapstructure:"-" yaml:"-"`
+	// AllowMethods defaults to DefaultCORSMethods.
+	AllowMethods []string `mapstructure:"allow-methods" yaml:"allow-methods"`
+	// AllowHeaders are the request headers allowed in preflights; empty
+	// allows the headers the preflight asks for.
+	AllowHeaders []string `mapstructure:"allow-headers" yaml:"allow-headers"`
+	// ExposeHeaders are the response headers scripts may read.
+	ExposeHeaders []string `mapstructure:"expose-headers" yaml:"expose-headers"`
+	// AllowCredentials lets the browser send cookies and auth. It cannot be
+	// combined with the "*" origin, which would then trust every site.
+	AllowCredentials bool `mapstructure:"allow-credentials" yaml:"allow-credentials"`
+	// MaxAge is how long the browser caches a preflight; 0 sends no Max-Age.
+	MaxAge time.Duration `mapstructure:"max-age" yaml:"max-age"`
+}
+
+// Enabled reports whether origins are configured.
+func (c CORSConfig) Enabled() bool {
+	return len(c.AllowOrigins) > 0 || c.AllowOriginFunc != nil
+}
+
+// CORS returns the middleware answering Cross-Origin requests with opts. A
+// preflight (OPTIONS with Access-Control-Request-Method) is answered with
+// 204 and stops the chain; the CORS headers are only set for an allowed
+// origin, method and headers. Used with Server.Use, it only sees the
+// preflights of routes registered for OPTIONS; Config.CORS answers all of
+// them. It panics if opts is invalid, see NewCORS.
+func CORS(opts CORSConfig) Handler {
+	h, err := NewCORS(opts)
+	if err != nil {
+		panic(err)
+	}
+	return h
+}
+
+// NewCORS is CORS returning an error when an origin pattern is not a valid
+// regular expression or AllowCredentials is set with the "*" origin. The
+// adapters build Config.CORS with it, so a bad configuration fails Start.
+func NewCORS(opts CORSConfig) (Handler, error) {
+	p, err := newCORSPolicy(opts)
+	if err != nil {
+		return nil, err
+	}
+	return func(c Context) {
+		origin := c.Header(HeaderOrigin)
+		preflight := c.Method() == MethodOptions && c.Header(HeaderAccessControlRequestMethod) != ""
+		if preflight {
+			c.AddHeader(HeaderVary, HeaderOrigin)
+			c.AddHeader(HeaderVary, HeaderAccessControlRequestMethod)
+			c.AddHeader(HeaderVary, HeaderAccessControlRequestHeaders)
+			if origin != "" && p.allowOrigin(origin) {
+				p.preflight(c, origin)
+			}
+			_ = c.NoContent(StatusNoContent)
+			c.Abort()
+			return
+		}
+		// Cache không được dùng lại response cho origin khác khi header phụ thuộc origin
+		if p.vary {
+			c.AddHeader(HeaderVary, HeaderOrigin)
+		}
+		if origin != "" && p.allowOrigin(origin) {
+			p.setOrigin(c, origin)
+			if p.expose != "" {
+				c.SetHeader(HeaderAccessControlExposeHeaders, p.expose)
+			}
+		}
+		c.Next()
+	}, nil
+}
+
+// corsPolicy là CORSConfig đã được chuẩn hoá và biên dịch sẵn
+type corsPolicy struct {
+	opts     CORSConfig
+	any      bool
+	exact    map[string]bool
+	patterns []*regexp.Regexp
+	methods  map[string]bool
+	headers  map[string]bool
+	// vary: header Allow-Origin phụ thuộc vào Origin của request
+	vary          bool
+	allowMethods  string
+	allowHeaders  string
+	expose        string
+	maxAge        string
+	reflectHeader bool
+}
+
+func newCORSPolicy(opts CORSConfig) (*corsPolicy, error) {
+	p := &corsPolicy{opts: opts, exact: make(map[string]bool), methods: make(map[string]bool), headers: make(map[string]bool)}
+	for _, origin := range opts.AllowOrigins {
+		switch {
+		case origin == "*":
+			p.any = true
+		case strings.HasPrefix(origin, "^"):
+			// Bọc lại để pattern luôn khớp cả Origin, kể cả khi thiếu $
+			re, err := regexp.Compile("^(?:" + origin + ")$")
+			if err != nil {
+				return nil, fmt.Errorf("cors: invalid origin pattern %q: %w", origin, err)
+			}
+			p.patterns = append(p.patterns, re)
+		case strings.Contains(origin, "*"):
+			// * khớp một phần của host (không gồm / và :)
+			parts := strings.Split(strings.ToLower(origin), "*")
+			for i := range parts {
+				parts[i] = regexp.QuoteMeta(parts[i])
+			}
+			p.patterns = append(p.patterns, regexp.MustCompile("^"+strings.Join(parts, "[^/:]*")+"$"))
+		default:
+			p.exact[strings.ToLower(origin)] = true
+		}
+	}
+	if p.any && opts.AllowCredentials {
+		return nil, errors.New(`cors: AllowCredentials cannot be used with the "*" origin`)
+	}
+	p.vary = !p.any || len(p.exact) > 0 || len(p.patterns) > 0 || opts.AllowOriginFunc != nil
+
+	allowed := opts.AllowMethods
+	if len(allowed) == 0 {
+		allowed = DefaultCORSMethods
+	}
+	methods := make([]string, len(allowed))
+	for i, m := range allowed {
+		methods[i] = strings.ToUpper(m)
+		p.methods[methods[i]] = true
+	}
+	p.allowMethods = strings.Join(methods, ", ")
+	p.reflectHeader = len(opts.AllowHeaders) == 0
+	for _, h := range opts.AllowHeaders {
+		p.headers[strings.ToLower(h)] = true
+	}
+	p.allowHeaders = strings.Join(opts.AllowHeaders, ", ")
+	p.expose = strings.Join(opts.ExposeHeaders, ", ")
+	if opts.MaxAge > 0 {
+		p.maxAge = strconv.Itoa(int(opts.MaxAge.Seconds()))
+	}
+	return p, nil
+}
+
+func (p *corsPolicy) allowOrigin(origin string) bool {
+	if p.any || p.exact[strings.ToLower(origin)] {
+		return true
+	}
+	for _, re := range p.patterns {
+		if re.MatchString(origin) || re.MatchString(strings.ToLower(origin)) {
+			return true
+		}
+	}
+	return p.opts.AllowOriginFunc != nil && p.opts.AllowOriginFunc(origin)
+}
+
+func (p *corsPolicy) setOrigin(c Context, origin string) {
+	if p.any && !p.vary {
+		c.SetHeader(HeaderAccessControlAllowOrigin, "*")
+	} else {
+		c.SetHeader(HeaderAccessControlAllowOrigin, origin)
+	}
+	if p.opts.AllowCredentials {
+		c.SetHeader(HeaderAccessControlAllowCredentials, "true")
+	}
+}
+
+// preflight đặt header cho preflight khi method và header được yêu cầu đều được phép
+func (p *corsPolicy) preflight(c Context, origin string) {
+	if !p.methods[strings.ToUpper(c.Header(HeaderAccessControlRequestMethod))] {
+		return
+	}
+	requested := splitList(c.Header(HeaderAccessControlRequestHeaders))
+	if !p.reflectHeader {
+		for _, h := range requested {
+			if !p.headers[strings.ToLower(h)] {
+				return
+			}
+		}
+	}
+	p.setOrigin(c, origin)
+	c.SetHeader(HeaderAccessControlAllowMethods, p.allowMethods)
+	allowHeaders := p.allowHeaders
+	if p.reflectHeader {
+		allowHeaders = strings.Join(requested, ", ")
+	}
+	if allowHeaders != "" {
+		c.SetHeader(HeaderAccessControlAllowHeaders, allowHeaders)
+	}
+	if p.maxAge != "" {
+		c.SetHeader(HeaderAccessControlMaxAge, p.maxAge)
+	}
+}
@@ -0,0 +1,156 @@
+package core
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"reflect"
+)
+
+// CSVCodec handles tables: [][]string, or slices of structs whose exported
+// fields are the columns, named by their csv tag or field name (csv:"-"
+// skips a field). The first row is the header of the columns.
+type CSVCodec struct{}
+
+func (CSVCodec) Supports(v interface{}) bool {
+	if _, ok := v.([][]string); ok {
+		return true
+	}
+	_, ok := csvRowType(reflect.TypeOf(v))
+	return ok
+}
+
+func (CSVCodec) Marshal(v interface{}) ([]byte, error) {
+	records, ok := v.([][]string)
+	if !ok {
+		var err error
+		if records, err = csvRecords(reflect.ValueOf(v)); err != nil {
+			return nil, err
+		}
+	}
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.WriteAll(records); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (CSVCodec) Unmarshal(data []byte, v interface{}) error {
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		return err
+	}
+	if out, ok := v.(*[][]string); ok {
+		*out = records
+		return nil
+	}
+	ptr := reflect.ValueOf(v)
+	if ptr.Kind() != reflect.Ptr || ptr.IsNil() {
+		return fmt.Errorf("csv: cannot decode into %T", v)
+	}
+	slice := ptr.Elem()
+	row, ok := csvRowType(slice.Type())
+	if !ok {
+		return fmt.Errorf("csv: cannot decode into %T", v)
+	}
+	if len(records) == 0 {
+		return nil
+	}
+	columns := csvColumns(row)
+	// Cột theo header của request, cột lạ bị bỏ qua
+	index := make([]int, len(records[0]))
+	for i, name := range records[0] {
+		index[i] = -1
+		for j, col := range columns {
+			if col.name == name {
+				index[i] = j
+			}
+		}
+	}
+	for _, record := range records[1:] {
+		item := reflect.New(row).Elem()
+		for i, raw := range record {
+			if i >= len(index) || index[i] < 0 || raw == "" {
+				continue
+			}
+			col := columns[index[i]]
+			if err := setField(item.Field(col.field), raw); err != nil {
+				return fmt.Errorf("csv: column %s: %w", col.name, err)
+			}
+		}
+		if slice.Type().Elem().Kind() == reflect.Ptr {
+			item = item.Addr()
+		}
+		slice.Set(reflect.Append(slice, item))
+	}
+	return nil
+}
+
+type csvColumn struct {
+	name  string
+	field int
+}
+
+// csvRowType trả về kiểu struct của một dòng khi t là slice/array of struct (hoặc *struct)
+func csvRowType(t reflect.Type) (reflect.Type, bool) {
+	if t == nil || (t.Kind() != reflect.Slice && t.Kind() != reflect.Array) {
+		return nil, false
+	}
+	row := t.Elem()
+	if row.Kind() == reflect.Ptr {
+		row = row.Elem()
+	}
+	return row, row.Kind() == reflect.Struct
+}
+
+func csvColumns(row reflect.Type) []csvColumn {
+	var columns []csvColumn
+	for i := 0; i < row.NumField(); i++ {
+		field := row.Field(i)
+		name := field.Tag.Get("csv")
+		if !field.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		columns = append(columns, csvColumn{name: name, field: i})
+	}
+	return columns
+}
+
+func csvRecords(v reflect.Value) ([][]string, error) {
+	row, ok := csvRowType(v.Type())
+	if !ok {
+		return nil, fmt.Errorf("csv: cannot encode %s, want [][]string or a slice of structs", v.Type())
+	}
+	columns := csvColumns(row)
+	header := make([]string, len(columns))
+	for i, col := range columns {
+		header[i] = col.name
+	}
+	records := [][]string{header}
+	for i := 0; i < v.Len(); i++ {
+		item := v.Index(i)
+		if item.Kind() == reflect.Ptr {
+			if item.IsNil() {
+				continue
+			}
+			item = item.Elem()
+		}
+		record := make([]string, len(columns))
+		for j, col := range columns {
+			field := item.Field(col.field)
+			if field.Kind() == reflect.Ptr {
+				if field.IsNil() {
+					continue
+				}
+				field = field.Elem()
+			}
+			record[j] = fmt.Sprint(field.Interface())
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
@@ -9,6 +9,7 @@ import (
 	"reflect"
 	"runtime"
 	"strings"
+	"time"
 )
 
 var (
@@ -16,90 +17,229 @@ var (
 )
 
 type DynamicRouter struct {
+	// DevMode cho phép parse source lúc chạy khi handler chưa có bảng route sinh bởi go-server-gen
+	DevMode bool
+	// RateLimit là Config.RateLimit, gốc của limiter các route @RateLimit
+	RateLimit   RateLimitOptions
 	apiHandlers []interface{}
 	Routes      []RouteConfig
+	docs        []apiDoc
 }
 
-func (b *DynamicRouter) add(method, path string, handler Handler) {
-	b.Routes = append(b.Routes, RouteConfig{
-		Method:  method,
-		Path:    path,
-		Handler: handler,
-	})
+// GeneratedRoutes is implemented by handlers processed with cmd/go-server-gen,
+// which emits an ApiRoutes method from their @Api annotations.
+type GeneratedRoutes interface {
+	ApiRoutes() []RouteConfig
+}
+
+// apiDoc giữ annotation của một route @Api để sinh tài liệu OpenAPI
+type apiDoc struct {
+	OperationID string
+	File        string
+	Route       ParseRoute
 }
 
 func (b *DynamicRouter) RegisterHandlersWithTags(handlers ...interface{}) {
 	b.apiHandlers = append(b.apiHandlers, handlers...)
 }
 
-// LoadRouter quét tất cả các thư mục và tìm kiếm các route từ các tệp Go
+// LoadRouter đăng ký route của các handler @Api. Bảng route sinh bởi
+// go-server-gen luôn được ưu tiên; parse source lúc chạy chỉ dùng ở DevMode.
 func (b *DynamicRouter) LoadRouter() {
 	if len(b.apiHandlers) == 0 {
 		return
 	}
 
 	for _, apiHandler := range b.apiHandlers {
-		val := reflect.ValueOf(apiHandler)
-
-		filePath, err := getFilePathOfStruct(apiHandler)
-		if err != nil {
-			log.Printf("failed to get file path of struct %T: %v", apiHandler, err)
+		if generated, ok := apiHandler.(GeneratedRoutes); ok {
+			b.loadGenerated(apiHandler, generated.ApiRoutes())
+			continue
+		}
+		if !b.DevMode {
+			log.Printf("handler %T has no generated routes, run go-server-gen (runtime parsing only runs in debug mode)", apiHandler)
 			continue
 		}
+		if err := b.loadFromSource(apiHandler); err != nil {
+			log.Printf("failed to load routes of %T: %v", apiHandler, err)
+		}
+	}
+}
 
-		methodApiMap := parseApiTags(filePath)
-		for methodName, route := range methodApiMap {
-			method := val.MethodByName(methodName)
-			if !method.IsValid() {
-				log.Printf("method %s not found in handler %T", methodName, apiHandler)
-				continue
-			}
+func (b *DynamicRouter) loadGenerated(apiHandler interface{}, routes []RouteConfig) {
+	// Source chỉ cần cho schema OpenAPI, nên không có file cũng không sao
+	filePath, _ := getFilePathOfStruct(apiHandler)
+	for _, route := range routes {
+		b.Routes = append(b.Routes, b.withAnnotations(route))
+		if route.Doc != nil {
+			b.docs = append(b.docs, apiDoc{
+				OperationID: typeName(reflect.TypeOf(apiHandler)) + "." + route.Doc.Name,
+				File:        filePath,
+				Route:       *route.Doc,
+			})
+		}
+	}
+}
 
-			// Ex: (h *MyApiHandler) SayHello(c core.Context) {}
-			// Kiểm tra kiểu signature method
-			methodType := method.Type()
-			// method là bound method, nên đầu vào phải có 1 tham số (ctx)
-			if methodType.NumIn() != 1 {
-				log.Printf("method %s in %T must have exactly one input parameter (Context)", methodName, apiHandler)
-				continue
-			}
-			// Kiểm tra kiểu Context
-			if methodType.In(0) != ctxType {
-				log.Printf("method %s in %T input parameter must be Context", methodName, apiHandler)
-				continue
-			}
+// loadFromSource tìm file source của handler và parse các annotation @Api
+func (b *DynamicRouter) loadFromSource(apiHandler interface{}) error {
+	val := reflect.ValueOf(apiHandler)
 
-			// Đảm bảo function không có giá trị trả về
-			if methodType.NumOut() != 0 {
-				log.Printf("method %s in %T must have no return value", methodName, apiHandler)
+	filePath, err := getFilePathOfStruct(apiHandler)
+	if err != nil {
+		return err
+	}
+
+	methodApiMap, err := ParseApiTags(filePath)
+	if err != nil {
+		return err
+	}
+	for methodName, route := range methodApiMap {
+		method := val.MethodByName(methodName)
+		if !method.IsValid() {
+			log.Printf("method %s not found in handler %T", methodName, apiHandler)
+			continue
+		}
+
+		route := route
+		if route.Method == MethodWS {
+			// Ex: (h *MyApiHandler) Chat(conn core.WSConn) {}
+			ws, ok := method.Interface().(func(WSConn))
+			if !ok {
+				log.Printf("method %s in %T: WS routes take func(core.WSConn)", methodName, apiHandler)
 				continue
 			}
+			b.Routes = append(b.Routes, RouteConfig{Method: MethodWS, Path: route.Path, WebSocket: ws, Doc: &route})
+			continue
+		}
 
-			h := Handler(func(ctx Context) {
-				method.Call([]reflect.Value{reflect.ValueOf(ctx)})
-			})
-
-			b.add(route.Method, route.Path, h)
+		// Ex: (h *MyApiHandler) SayHello(c core.Context) {}
+		// hoặc (h *MyApiHandler) Create(ctx core.Context, req *CreateReq) (*Resp, error)
+		h, err := NewHandler(method.Interface())
+		if err != nil {
+			log.Printf("method %s in %T: %v", methodName, apiHandler, err)
+			continue
 		}
+
+		b.Routes = append(b.Routes, b.withAnnotations(RouteConfig{
+			Method:  route.Method,
+			Path:    route.Path,
+			Handler: h,
+			Doc:     &route,
+		}))
+		b.docs = append(b.docs, apiDoc{
+			OperationID: typeName(val.Type()) + "." + methodName,
+			File:        filePath,
+			Route:       route,
+		})
 	}
+	return nil
 }
 
 type ParseRoute struct {
 	Path   string
 	Method string
+	// Name và Receiver là tên method và kiểu nhận của handler, ví dụ SayHi và *MyApiHandler
+	Name     string
+	Receiver string
+
+	// Thông tin tài liệu cho OpenAPI
+	Summary  string
+	Tags     []string
+	Params   []ParseParam
+	Body     *ParseBody
+	Response []ParseResponse
+
+	// Timeout là giá trị @Timeout (ví dụ 2s), áp dụng bằng middleware Timeout
+	Timeout string
+	// RateLimit là giá trị @RateLimit (ví dụ 100/m), áp dụng bằng middleware RateLimit
+	RateLimit string
+
+	// Schemas are the component schemas of the types the route references,
+	// emitted by go-server-gen so BuildOpenAPI needs no sources at runtime.
+	Schemas map[string]*Schema
+}
+
+// ParseParam is an @Param annotation: @Param name in type required "description"
+type ParseParam struct {
+	Name        string
+	In          string
+	Type        string
+	Required    bool
+	Description string
+}
+
+// ParseBody is an @Body annotation: @Body Type "description"
+type ParseBody struct {
+	Type        string
+	Description string
+}
+
+// ParseResponse is an @Success or @Failure annotation: @Success code [Type] ["description"]
+type ParseResponse struct {
+	Code        string
+	Type        string
+	Description string
+}
+
+// withAnnotations thêm middleware của @Timeout trước middleware của route và
+// của @RateLimit sau chúng, để key của limiter (ví dụ jwt.RateLimitByUser)
+// thấy được user mà middleware xác thực của route đã đặt
+func (b *DynamicRouter) withAnnotations(route RouteConfig) RouteConfig {
+	return withRateLimit(withTimeout(route), b.RateLimit)
 }
 
-func parseApiTags(filename string) map[string]ParseRoute {
+// withRateLimit thêm middleware RateLimit dựa trên base khi có @RateLimit
+func withRateLimit(route RouteConfig, base RateLimitOptions) RouteConfig {
+	if route.Doc == nil || route.Doc.RateLimit == "" {
+		return route
+	}
+	rate, err := ParseRate(route.Doc.RateLimit)
+	if err != nil {
+		log.Printf("Invalid @RateLimit %q on %s %s", route.Doc.RateLimit, route.Method, route.Path)
+		return route
+	}
+	opts := base
+	opts.Limit, opts.Window = rate.Limit, rate.Window
+	if rate.Algorithm != "" {
+		opts.Algorithm = rate.Algorithm
+	}
+	// Mỗi route có bộ đếm riêng kể cả khi dùng chung Store
+	opts.Prefix += route.Method + " " + route.Path + " "
+	// Chép slice để không ghi vào mảng của bảng route sinh bởi go-server-gen
+	middleware := append([]Handler{}, route.Middleware...)
+	route.Middleware = append(middleware, RateLimit(opts))
+	return route
+}
+
+// withTimeout thêm middleware Timeout trước middleware của route khi có @Timeout
+func withTimeout(route RouteConfig) RouteConfig {
+	if route.Doc == nil || route.Doc.Timeout == "" {
+		return route
+	}
+	d, err := time.ParseDuration(route.Doc.Timeout)
+	if err != nil || d <= 0 {
+		log.Printf("Invalid @Timeout %q on %s %s", route.Doc.Timeout, route.Method, route.Path)
+		return route
+	}
+	route.Middleware = append([]Handler{Timeout(d)}, route.Middleware...)
+	return route
+}
+
+// ParseApiTags parses the @BaseUrl and @Api annotations of a Go file and
+// returns the routes keyed by method name. @Api WS /path declares a
+// WebSocket route (MethodWS), whose method takes a core.WSConn.
+func ParseApiTags(filename string) (map[string]ParseRoute, error) {
 	set := token.NewFileSet()
 	node, err := parser.ParseFile(set, filename, nil, parser.ParseComments)
 	if err != nil {
-		log.Fatalf("failed to parse file: %v", err)
+		return nil, fmt.Errorf("failed to parse file: %w", err)
 	}
 
 	result := make(map[string]ParseRoute)
 	baseUrl := ""
+	var baseTags []string
 
-	// Tìm @BaseUrl
+	// Tìm @BaseUrl và @Tag của struct
 	for _, decl := range node.Decls {
 		genDecl, ok := decl.(*ast.GenDecl)
 		if !ok || genDecl.Tok != token.TYPE {
@@ -113,16 +253,25 @@ func parseApiTags(filename string) map[string]ParseRoute {
 						baseUrl = parts[2]
 					}
 				}
+				if strings.HasPrefix(comment.Text, "// @Tag ") {
+					baseTags = append(baseTags, strings.Fields(comment.Text)[2:]...)
+				}
 			}
 		}
 	}
 
-	// Tìm @Api
+	// Tìm @Api và các annotation tài liệu đi kèm
 	for _, decl := range node.Decls {
 		fn, ok := decl.(*ast.FuncDecl)
 		if !ok || fn.Doc == nil {
 			continue
 		}
+		route := ParseRoute{
+			Name:     fn.Name.Name,
+			Receiver: receiverType(fn),
+			Tags:     append([]string(nil), baseTags...),
+		}
+		hasApi := false
 		for _, comment := range fn.Doc.List {
 			if strings.HasPrefix(comment.Text, "// @Api") {
 				parts := strings.Fields(comment.Text)
@@ -136,15 +285,137 @@ func parseApiTags(filename string) map[string]ParseRoute {
 				if baseUrl != "" {
 					fullPath = strings.TrimRight(baseUrl, "/") + "/" + strings.TrimLeft(path, "/")
 				}
-				result[fn.Name.Name] = ParseRoute{
-					Method: method,
-					Path:   fullPath,
-				}
+				route.Method = method
+				route.Path = fullPath
+				hasApi = true
+				continue
+			}
+			parseDocTag(&route, comment.Text)
+		}
+		if hasApi {
+			result[fn.Name.Name] = route
+		}
+	}
+
+	return result, nil
+}
+
+// receiverType trả về kiểu nhận của method dạng source, ví dụ *MyApiHandler
+func receiverType(fn *ast.FuncDecl) string {
+	if fn.Recv == nil || len(fn.Recv.List) == 0 {
+		return ""
+	}
+	switch t := fn.Recv.List[0].Type.(type) {
+	case *ast.StarExpr:
+		if ident, ok := t.X.(*ast.Ident); ok {
+			return "*" + ident.Name
+		}
+	case *ast.Ident:
+		return t.Name
+	}
+	return ""
+}
+
+// parseDocTag đọc các annotation @Summary, @Timeout, @RateLimit, @Tag, @Param, @Body, @Success, @Failure
+func parseDocTag(route *ParseRoute, text string) {
+	text = strings.TrimSpace(strings.TrimPrefix(text, "//"))
+	tag, rest, _ := strings.Cut(text, " ")
+	rest = strings.TrimSpace(rest)
+	parts := splitQuoted(rest)
+
+	switch tag {
+	case "@Summary":
+		route.Summary = rest
+	case "@Timeout":
+		if d, err := time.ParseDuration(rest); err != nil || d <= 0 {
+			log.Printf("Invalid @Timeout comment format: %s", text)
+			return
+		}
+		route.Timeout = rest
+	case "@RateLimit":
+		if _, err := ParseRate(rest); err != nil {
+			log.Printf("Invalid @RateLimit comment format: %s", text)
+			return
+		}
+		route.RateLimit = rest
+	case "@Tag":
+		route.Tags = append(route.Tags, parts...)
+	case "@Param":
+		if len(parts) < 3 {
+			log.Printf("Invalid @Param comment format: %s", text)
+			return
+		}
+		param := ParseParam{Name: parts[0], In: parts[1], Type: parts[2], Required: parts[1] == "path"}
+		if len(parts) > 3 {
+			param.Required = param.Required || parts[3] == "true"
+		}
+		if len(parts) > 4 {
+			param.Description = parts[4]
+		}
+		route.Params = append(route.Params, param)
+	case "@Body":
+		if rest == "" {
+			log.Printf("Invalid @Body comment format: %s", text)
+			return
+		}
+		typ, desc := cutType(rest)
+		route.Body = &ParseBody{Type: typ, Description: desc}
+	case "@Success", "@Failure":
+		code, tail, _ := strings.Cut(rest, " ")
+		if code == "" {
+			log.Printf("Invalid %s comment format: %s", tag, text)
+			return
+		}
+		res := ParseResponse{Code: code}
+		tail = strings.TrimSpace(tail)
+		if strings.HasPrefix(tail, `"`) {
+			res.Description = strings.Trim(tail, `"`)
+		} else {
+			res.Type, res.Description = cutType(tail)
+		}
+		route.Response = append(route.Response, res)
+	}
+}
+
+// cutType tách `Type "mô tả"` thành kiểu Go và mô tả
+func cutType(s string) (string, string) {
+	typ, desc, _ := strings.Cut(strings.TrimSpace(s), " ")
+	return typ, strings.Trim(strings.TrimSpace(desc), `"`)
+}
+
+// splitQuoted tách chuỗi theo khoảng trắng, giữ nguyên phần trong dấu ngoặc kép
+func splitQuoted(s string) []string {
+	var parts []string
+	var current strings.Builder
+	inQuote := false
+	for _, r := range s {
+		switch {
+		case r == '"':
+			if inQuote {
+				parts = append(parts, current.String())
+				current.Reset()
+			}
+			inQuote = !inQuote
+		case r == ' ' && !inQuote:
+			if current.Len() > 0 {
+				parts = append(parts, current.String())
+				current.Reset()
 			}
+		default:
+			current.WriteRune(r)
 		}
 	}
+	if current.Len() > 0 {
+		parts = append(parts, current.String())
+	}
+	return parts
+}
 
-	return result
+func typeName(typ reflect.Type) string {
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	return typ.Name()
 }
 
 func getFilePathOfStruct(i interface{}) (string, error) {
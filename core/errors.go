@@ -0,0 +1,161 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+)
+
+// MIMEApplicationProblemJSON is the media type of RFC 9457 problem details.
+const MIMEApplicationProblemJSON = "application/problem+json"
+
+// ErrorHandlerKey is the Context key under which adapters store the server's
+// ErrorHandler for Context.Error.
+const ErrorHandlerKey = "core.errorHandler"
+
+// ErrorHandler renders an error as the response. It is called by
+// Context.Error and for engine panics and 404/405 responses.
+type ErrorHandler func(c Context, err error)
+
+// HTTPError is an RFC 9457 problem detail. Extensions are written as
+// top-level members next to the standard ones; Err is the cause and is
+// only logged.
+//
+// Example
+//
+//	c.Error(core.NewHTTPError(core.StatusNotFound, "user 42 does not exist").
+//		With("userId", 42))
+type HTTPError struct {
+	Type       string
+	Title      string
+	Status     int
+	Detail     string
+	Instance   string
+	Extensions map[string]interface{}
+	Err        error
+}
+
+// NewHTTPError returns a problem with the given status; Title defaults to
+// the status text.
+func NewHTTPError(status int, detail string) *HTTPError {
+	return &HTTPError{Status: status, Detail: detail}
+}
+
+// With sets an extension member and returns e.
+func (e *HTTPError) With(key string, value interface{}) *HTTPError {
+	if e.Extensions == nil {
+		e.Extensions = make(map[string]interface{})
+	}
+	e.Extensions[key] = value
+	return e
+}
+
+// Wrap sets the cause of e and returns e.
+func (e *HTTPError) Wrap(err error) *HTTPError {
+	e.Err = err
+	return e
+}
+
+func (e *HTTPError) Error() string {
+	msg := fmt.Sprintf("%d %s", e.Status, e.title())
+	if e.Detail != "" {
+		msg += ": " + e.Detail
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *HTTPError) Unwrap() error {
+	return e.Err
+}
+
+func (e *HTTPError) StatusCode() int {
+	return e.Status
+}
+
+func (e *HTTPError) title() string {
+	if e.Title != "" {
+		return e.Title
+	}
+	return http.StatusText(e.Status)
+}
+
+func (e *HTTPError) MarshalJSON() ([]byte, error) {
+	body := make(map[string]interface{}, len(e.Extensions)+5)
+	for k, v := range e.Extensions {
+		body[k] = v
+	}
+	body["type"] = "about:blank"
+	if e.Type != "" {
+		body["type"] = e.Type
+	}
+	body["title"] = e.title()
+	body["status"] = e.Status
+	if e.Detail != "" {
+		body["detail"] = e.Detail
+	}
+	if e.Instance != "" {
+		body["instance"] = e.Instance
+	}
+	return json.Marshal(body)
+}
+
+// ToHTTPError converts err to a problem:
+//   - an *HTTPError in the chain is returned as is
+//   - a ValidationError becomes 422 with its field errors under "errors"
+//   - an *http.MaxBytesError (see BodyLimit) becomes 413
+//   - a StatusCoder keeps its status, with err.Error() as the detail
+//   - anything else is a 500 whose detail is not exposed
+func ToHTTPError(err error) *HTTPError {
+	var he *HTTPError
+	if errors.As(err, &he) {
+		return he
+	}
+	var verr *ValidationError
+	if errors.As(err, &verr) {
+		return NewHTTPError(verr.StatusCode(), verr.Error()).With("errors", verr.Errors).Wrap(err)
+	}
+	var mbe *http.MaxBytesError
+	if errors.As(err, &mbe) {
+		return NewHTTPError(StatusRequestEntityTooLarge, "request body too large").With("limit", mbe.Limit).Wrap(err)
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		// Deadline của Timeout hết trước khi handler trả lời
+		return NewHTTPError(StatusServiceUnavailable, "request timed out").Wrap(err)
+	}
+	var sc StatusCoder
+	if errors.As(err, &sc) {
+		return NewHTTPError(sc.StatusCode(), err.Error()).Wrap(err)
+	}
+	return NewHTTPError(StatusInternalServerError, "").Wrap(err)
+}
+
+// DefaultErrorHandler writes err as application/problem+json and aborts the
+// chain. 5xx errors are logged with their cause.
+func DefaultErrorHandler(c Context, err error) {
+	problem := ToHTTPError(err)
+	if problem.Status >= StatusInternalServerError {
+		log.Printf("%s %s: %v", c.Method(), c.Path(), err)
+	}
+	c.SetHeader(HeaderContentType, MIMEApplicationProblemJSON)
+	c.AbortWithStatusJSON(problem.Status, problem)
+}
+
+// HandleError passes err to the ErrorHandler stored in c under
+// ErrorHandlerKey, or to DefaultErrorHandler. Adapters implement
+// Context.Error with it.
+func HandleError(c Context, err error) {
+	if err == nil {
+		return
+	}
+	if h, ok := c.Get(ErrorHandlerKey).(ErrorHandler); ok && h != nil {
+		h(c, err)
+		return
+	}
+	DefaultErrorHandler(c, err)
+}
@@ -0,0 +1,346 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var (
+	stdCtxType = reflect.TypeOf((*context.Context)(nil)).Elem()
+	errorType  = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// StatusCoder is implemented by errors and responses that carry their own HTTP status.
+type StatusCoder interface {
+	StatusCode() int
+}
+
+// BindError is returned when the request cannot be bound; it maps to 400.
+type BindError struct {
+	Field string
+	Err   error
+}
+
+func (e *BindError) Error() string {
+	if e.Field == "" {
+		return fmt.Sprintf("invalid request: %v", e.Err)
+	}
+	return fmt.Sprintf("invalid request field %s: %v", e.Field, e.Err)
+}
+
+func (e *BindError) Unwrap() error {
+	return e.Err
+}
+
+func (e *BindError) StatusCode() int {
+	return StatusBadRequest
+}
+
+// NewHandler adapts a typed function to a Handler. Supported shapes, where
+// Ctx is Context or context.Context and Req is a struct or struct pointer:
+//
+//	func(Context)
+//	func(Ctx) error
+//	func(Ctx, Req) error
+//	func(Ctx) (Resp, error)
+//	func(Ctx, Req) (Resp, error)
+//
+// Req is bound from the body (Context.Bind) and from fields tagged path,
+// query or header, which win over the body, then validated. Resp is written
+// as JSON with 200, or with its StatusCode() when it implements StatusCoder;
+// routes using Produces negotiate it between the listed types instead. A
+// nil Resp writes 204.
+// Errors are passed to Context.Error.
+func NewHandler(fn interface{}) (Handler, error) {
+	if h, ok := fn.(func(Context)); ok {
+		return h, nil
+	}
+	if h, ok := fn.(Handler); ok {
+		return h, nil
+	}
+
+	val := reflect.ValueOf(fn)
+	typ := val.Type()
+	if typ.Kind() != reflect.Func {
+		return nil, fmt.Errorf("handler must be a function, got %s", typ)
+	}
+
+	// Tham số đầu tiên: core.Context hoặc context.Context
+	if typ.NumIn() < 1 || typ.NumIn() > 2 {
+		return nil, fmt.Errorf("handler %s must take (Context[, Req])", typ)
+	}
+	useStdCtx := typ.In(0) == stdCtxType
+	if typ.In(0) != ctxType && !useStdCtx {
+		return nil, fmt.Errorf("handler %s first parameter must be core.Context or context.Context", typ)
+	}
+
+	// Tham số thứ hai (nếu có): struct request
+	var reqType reflect.Type
+	if typ.NumIn() == 2 {
+		reqType = typ.In(1)
+		elem := reqType
+		if elem.Kind() == reflect.Ptr {
+			elem = elem.Elem()
+		}
+		if elem.Kind() != reflect.Struct {
+			return nil, fmt.Errorf("handler %s request parameter must be a struct or struct pointer", typ)
+		}
+	}
+
+	// Kết quả: (), (error) hoặc (Resp, error)
+	switch typ.NumOut() {
+	case 0:
+		if useStdCtx || reqType != nil {
+			return nil, fmt.Errorf("handler %s must return error or (Resp, error)", typ)
+		}
+	case 1, 2:
+		if typ.Out(typ.NumOut()-1) != errorType {
+			return nil, fmt.Errorf("handler %s last return value must be error", typ)
+		}
+	default:
+		return nil, fmt.Errorf("handler %s must return error or (Resp, error)", typ)
+	}
+
+	return func(c Context) {
+		args := make([]reflect.Value, 0, 2)
+		if useStdCtx {
+			args = append(args, reflect.ValueOf(c.Context()))
+		} else {
+			args = append(args, reflect.ValueOf(c))
+		}
+		if reqType != nil {
+			req, err := bindRequest(c, reqType)
+			if err != nil {
+				c.Error(err)
+				return
+			}
+			args = append(args, req)
+		}
+
+		out := val.Call(args)
+		if len(out) == 0 {
+			return
+		}
+		if err, _ := out[len(out)-1].Interface().(error); err != nil {
+			c.Error(err)
+			return
+		}
+		if len(out) == 2 {
+			writeResult(c, out[0])
+		}
+	}, nil
+}
+
+// MustHandler is like NewHandler but panics on an unsupported signature.
+// Routes generated by go-server-gen use it.
+func MustHandler(fn interface{}) Handler {
+	h, err := NewHandler(fn)
+	if err != nil {
+		panic(err)
+	}
+	return h
+}
+
+func writeResult(c Context, result reflect.Value) {
+	if isNil(result) {
+		c.Status(StatusNoContent)
+		return
+	}
+	code := StatusOK
+	if sc, ok := result.Interface().(StatusCoder); ok {
+		code = sc.StatusCode()
+	}
+	types, _ := c.Get(ProducesKey).([]string)
+	if len(types) == 0 {
+		c.JSON(code, result.Interface())
+		return
+	}
+	if err := Negotiate(c, code, Offers(result.Interface(), types...)); err != nil {
+		c.Error(err)
+	}
+}
+
+func isNil(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice:
+		return v.IsNil()
+	}
+	return false
+}
+
+// bindRequest tạo struct request mới, bind body trước rồi mới bind các field
+// path/query/header, để body không ghi đè được giá trị của URL (ví dụ id của
+// PUT /users/{id}); struct được validate một lần sau cùng
+func bindRequest(c Context, reqType reflect.Type) (reflect.Value, error) {
+	elem := reqType
+	if elem.Kind() == reflect.Ptr {
+		elem = elem.Elem()
+	}
+	ptr := reflect.New(elem)
+
+	// Chỉ bind body khi request có body, vì fiber báo lỗi với body rỗng
+	if c.Header(HeaderContentType) != "" {
+		// Bind validate khi các field path/query/header chưa có giá trị, nên
+		// bỏ qua lỗi validate của nó
+		var verr *ValidationError
+		if err := c.Bind(ptr.Interface()); err != nil && !errors.As(err, &verr) {
+			return reflect.Value{}, &BindError{Err: err}
+		}
+	}
+	if err := bindTags(c, ptr.Elem()); err != nil {
+		return reflect.Value{}, err
+	}
+	if err := Validate(ptr.Interface()); err != nil {
+		return reflect.Value{}, err
+	}
+
+	if reqType.Kind() == reflect.Ptr {
+		return ptr, nil
+	}
+	return ptr.Elem(), nil
+}
+
+var tagSources = []struct {
+	tag    string
+	lookup func(c Context, name string) string
+}{
+	{"path", Context.Param},
+	{"query", Context.Query},
+	{"header", Context.Header},
+}
+
+func bindTags(c Context, v reflect.Value) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			if err := bindTags(c, v.Field(i)); err != nil {
+				return err
+			}
+			continue
+		}
+		for _, src := range tagSources {
+			name := field.Tag.Get(src.tag)
+			if name == "" || name == "-" {
+				continue
+			}
+			raw := src.lookup(c, name)
+			if raw == "" {
+				continue
+			}
+			if err := setField(v.Field(i), raw); err != nil {
+				return &BindError{Field: name, Err: err}
+			}
+		}
+	}
+	return nil
+}
+
+// BindForm sets the fields of the struct obj points to from form values,
+// by their form tag or else their name, like gin's form binding. Slices
+// take every value of their key. It does not validate obj.
+func BindForm(obj interface{}, values map[string][]string) error {
+	v := reflect.ValueOf(obj)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return fmt.Errorf("bind form: %T is not a pointer", obj)
+	}
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return fmt.Errorf("bind form: %s is not a struct", v.Type())
+	}
+	return bindFormFields(v, values)
+}
+
+func bindFormFields(v reflect.Value, values map[string][]string) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("form"), ",")
+		if name == "-" {
+			continue
+		}
+		if field.Anonymous && field.Type.Kind() == reflect.Struct && name == "" {
+			if err := bindFormFields(v.Field(i), values); err != nil {
+				return err
+			}
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		raw, ok := values[name]
+		if !ok || len(raw) == 0 {
+			continue
+		}
+		fv := v.Field(i)
+		if fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() != reflect.Uint8 {
+			slice := reflect.MakeSlice(fv.Type(), len(raw), len(raw))
+			for j, r := range raw {
+				if err := setField(slice.Index(j), r); err != nil {
+					return &BindError{Field: name, Err: err}
+				}
+			}
+			fv.Set(slice)
+			continue
+		}
+		if err := setField(fv, raw[0]); err != nil {
+			return &BindError{Field: name, Err: err}
+		}
+	}
+	return nil
+}
+
+func setField(v reflect.Value, raw string) error {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return setField(v.Elem(), raw)
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(raw, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(raw, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported field type %s", v.Type())
+	}
+	return nil
+}
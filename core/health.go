@@ -0,0 +1,236 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultHealthCheckTimeout bounds a check registered without a Timeout.
+const DefaultHealthCheckTimeout = 2 * time.Second
+
+// Trạng thái trả về bởi /readiness và /liveness
+const (
+	HealthStatusReady       = "ready"
+	HealthStatusAlive       = "alive"
+	HealthStatusDegraded    = "degraded"
+	HealthStatusUnavailable = "unavailable"
+	HealthStatusDraining    = "draining"
+
+	// Trạng thái của từng check
+	HealthStatusUp   = "up"
+	HealthStatusDown = "down"
+)
+
+// HealthChecker reports whether a dependency (DB, Redis, ...) is usable.
+type HealthChecker interface {
+	Check(ctx context.Context) error
+}
+
+// HealthCheckerFunc adapts a function to HealthChecker.
+type HealthCheckerFunc func(ctx context.Context) error
+
+func (f HealthCheckerFunc) Check(ctx context.Context) error {
+	return f(ctx)
+}
+
+// HealthCheckOptions configures a registered check.
+type HealthCheckOptions struct {
+	// Timeout bounds one run of the check; 0 means DefaultHealthCheckTimeout.
+	Timeout time.Duration
+	// Critical checks turn the endpoint into 503 when they fail; other
+	// failures only mark the report degraded.
+	Critical bool
+	// CacheTTL reuses the last result for this long; 0 runs the check on
+	// every request.
+	CacheTTL time.Duration
+	// Liveness runs the check for /liveness too; by default checks only
+	// affect /readiness.
+	Liveness bool
+}
+
+// HealthReport is the body of /readiness and /liveness.
+type HealthReport struct {
+	Status     string                     `json:"status"`
+	Components map[string]ComponentHealth `json:"components,omitempty"`
+}
+
+// ComponentHealth is the result of one check.
+type ComponentHealth struct {
+	Status    string    `json:"status"`
+	Critical  bool      `json:"critical"`
+	Error     string    `json:"error,omitempty"`
+	Duration  string    `json:"duration"`
+	CheckedAt time.Time `json:"checkedAt"`
+}
+
+// HealthChecks holds the checks behind /readiness and /liveness. Servers
+// embed it, so RegisterHealthCheck is available on every engine.
+type HealthChecks struct {
+	mu       sync.RWMutex
+	checks   map[string]*healthCheck
+	notReady atomic.Bool
+}
+
+type healthCheck struct {
+	checker HealthChecker
+	opts    HealthCheckOptions
+
+	mu     sync.Mutex
+	result ComponentHealth
+	cached bool
+}
+
+// RegisterHealthCheck adds or replaces the check called name.
+//
+// Example
+//
+//	server.RegisterHealthCheck("db", core.HealthCheckerFunc(db.PingContext),
+//		core.HealthCheckOptions{Critical: true, Timeout: time.Second})
+func (h *HealthChecks) RegisterHealthCheck(name string, checker HealthChecker, opts ...HealthCheckOptions) {
+	check := &healthCheck{checker: checker}
+	if len(opts) > 0 {
+		check.opts = opts[0]
+	}
+	if check.opts.Timeout <= 0 {
+		check.opts.Timeout = DefaultHealthCheckTimeout
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.checks == nil {
+		h.checks = make(map[string]*healthCheck)
+	}
+	h.checks[name] = check
+}
+
+// SetReady(false) makes readiness fail with 503 "draining" without running
+// the checks, e.g. while the server shuts down.
+func (h *HealthChecks) SetReady(ready bool) {
+	h.notReady.Store(!ready)
+}
+
+// Readiness runs every check and returns the status code and report.
+func (h *HealthChecks) Readiness(ctx context.Context) (int, HealthReport) {
+	if h.notReady.Load() {
+		return StatusServiceUnavailable, HealthReport{Status: HealthStatusDraining}
+	}
+	return h.run(ctx, false, HealthStatusReady)
+}
+
+// Liveness runs the checks registered with Liveness.
+func (h *HealthChecks) Liveness(ctx context.Context) (int, HealthReport) {
+	return h.run(ctx, true, HealthStatusAlive)
+}
+
+// ReadinessHandler serves Readiness as JSON.
+func (h *HealthChecks) ReadinessHandler() Handler {
+	return func(c Context) {
+		c.JSON(h.Readiness(c.Context()))
+	}
+}
+
+// LivenessHandler serves Liveness as JSON.
+func (h *HealthChecks) LivenessHandler() Handler {
+	return func(c Context) {
+		c.JSON(h.Liveness(c.Context()))
+	}
+}
+
+// run chạy song song các check và gộp kết quả; check critical lỗi thì trả 503
+func (h *HealthChecks) run(ctx context.Context, liveness bool, okStatus string) (int, HealthReport) {
+	h.mu.RLock()
+	names := make([]string, 0, len(h.checks))
+	for name, check := range h.checks {
+		if !liveness || check.opts.Liveness {
+			names = append(names, name)
+		}
+	}
+	checks := make([]*healthCheck, len(names))
+	sort.Strings(names)
+	for i, name := range names {
+		checks[i] = h.checks[name]
+	}
+	h.mu.RUnlock()
+
+	results := make([]ComponentHealth, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func(i int, check *healthCheck) {
+			defer wg.Done()
+			results[i] = check.run(ctx)
+		}(i, check)
+	}
+	wg.Wait()
+
+	code := StatusOK
+	report := HealthReport{Status: okStatus}
+	if len(names) > 0 {
+		report.Components = make(map[string]ComponentHealth, len(names))
+	}
+	for i, name := range names {
+		res := results[i]
+		report.Components[name] = res
+		if res.Status == HealthStatusUp {
+			continue
+		}
+		if res.Critical {
+			code = StatusServiceUnavailable
+			report.Status = HealthStatusUnavailable
+		} else if report.Status == okStatus {
+			report.Status = HealthStatusDegraded
+		}
+	}
+	return code, report
+}
+
+func (c *healthCheck) run(ctx context.Context) ComponentHealth {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.cached && time.Since(c.result.CheckedAt) < c.opts.CacheTTL {
+		return c.result
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.opts.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	// Chạy trong goroutine để check không tôn trọng ctx vẫn bị ngắt khi hết timeout
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("panic: %v", r)
+			}
+		}()
+		done <- c.checker.Check(ctx)
+	}()
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+		if errors.Is(err, context.DeadlineExceeded) {
+			err = fmt.Errorf("timed out after %s", c.opts.Timeout)
+		}
+	}
+
+	result := ComponentHealth{
+		Status:    HealthStatusUp,
+		Critical:  c.opts.Critical,
+		Duration:  time.Since(start).String(),
+		CheckedAt: start,
+	}
+	if err != nil {
+		result.Status = HealthStatusDown
+		result.Error = err.Error()
+	}
+	// Request bị huỷ không nói gì về dependency, nên không cache
+	c.result, c.cached = result, c.opts.CacheTTL > 0 && !errors.Is(err, context.Canceled)
+	return result
+}
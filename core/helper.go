@@ -26,6 +26,11 @@ const (
 	MIMEApplicationForm       = "application/x-www-form-urlencoded"
 	MIMEOctetStream           = "application/octet-stream"
 	MIMEMultipartForm         = "multipart/form-data"
+	MIMEApplicationMsgPack    = "application/msgpack"
+	MIMEApplicationProtobuf   = "application/x-protobuf"
+	MIMEApplicationYAML       = "application/yaml"
+	MIMETextCSV               = "text/csv"
+	MIMETextEventStream       = "text/event-stream"
 
 	MIMETextXMLCharsetUTF8         = "text/xml; charset=utf-8"
 	MIMETextHTMLCharsetUTF8        = "text/html; charset=utf-8"
@@ -223,6 +228,10 @@ const (
 	HeaderLink                    = "Link"
 	HeaderPushPolicy              = "Push-Policy"
 	HeaderRetryAfter              = "Retry-After"
+	HeaderRateLimitLimit          = "RateLimit-Limit"
+	HeaderRateLimitRemaining      = "RateLimit-Remaining"
+	HeaderRateLimitReset          = "RateLimit-Reset"
+	HeaderRateLimitPolicy         = "RateLimit-Policy"
 	HeaderServerTiming            = "Server-Timing"
 	HeaderSignature               = "Signature"
 	HeaderSignedHeaders           = "Signed-Headers"
@@ -0,0 +1,185 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// DefaultShutdownTimeout bounds the drain and OnShutdown hooks of Run.
+const DefaultShutdownTimeout = 30 * time.Second
+
+// Hook is a start or shutdown step registered with OnStart/OnShutdown.
+type Hook func(ctx context.Context) error
+
+// Lifecycle holds the OnStart and OnShutdown hooks of a server. Servers embed
+// it: Start runs the start hooks before listening and Shutdown runs the
+// shutdown hooks after draining.
+type Lifecycle struct {
+	mu           sync.Mutex
+	onStart      []Hook
+	onShutdown   []Hook
+	shutdownOnce sync.Once
+	shutdownErr  error
+}
+
+// OnStart registers hooks run in order by Start before the server listens;
+// the first error aborts Start.
+func (l *Lifecycle) OnStart(hooks ...Hook) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.onStart = append(l.onStart, hooks...)
+}
+
+// OnShutdown registers hooks run in reverse order by Shutdown once in-flight
+// requests are drained, like deferred calls.
+func (l *Lifecycle) OnShutdown(hooks ...Hook) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.onShutdown = append(l.onShutdown, hooks...)
+}
+
+// RunStartHooks runs the OnStart hooks; adapters call it from Start.
+func (l *Lifecycle) RunStartHooks(ctx context.Context) error {
+	l.mu.Lock()
+	hooks := append([]Hook(nil), l.onStart...)
+	l.mu.Unlock()
+	for i, hook := range hooks {
+		if err := hook(ctx); err != nil {
+			return fmt.Errorf("start hook %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// RunShutdownHooks runs the OnShutdown hooks once, in reverse order, and
+// joins their errors; adapters call it from Shutdown.
+func (l *Lifecycle) RunShutdownHooks(ctx context.Context) error {
+	l.shutdownOnce.Do(func() {
+		l.mu.Lock()
+		hooks := append([]Hook(nil), l.onShutdown...)
+		l.mu.Unlock()
+		var errs []error
+		for i := len(hooks) - 1; i >= 0; i-- {
+			if err := hooks[i](ctx); err != nil {
+				errs = append(errs, fmt.Errorf("shutdown hook %d: %w", i, err))
+			}
+		}
+		l.shutdownErr = errors.Join(errs...)
+	})
+	return l.shutdownErr
+}
+
+// RunOptions configures Run.
+type RunOptions struct {
+	// Signals that start the shutdown; default SIGINT and SIGTERM.
+	Signals []os.Signal
+	// PreStopDelay is how long readiness fails before draining starts, so
+	// load balancers stop sending traffic first.
+	PreStopDelay time.Duration
+	// ShutdownTimeout bounds the drain and the OnShutdown hooks; 0 means
+	// DefaultShutdownTimeout.
+	ShutdownTimeout time.Duration
+	// Upgrade re-executes the binary on UpgradeSignals and hands it the
+	// listening sockets (see Upgrade); this process then drains and exits.
+	Upgrade bool
+	// UpgradeSignals default to SIGHUP and SIGUSR2.
+	UpgradeSignals []os.Signal
+	// UpgradeTimeout bounds the start of the new process; 0 means
+	// DefaultUpgradeTimeout.
+	UpgradeTimeout time.Duration
+}
+
+// Run starts server and blocks until it stops. On SIGINT/SIGTERM it fails
+// readiness, waits PreStopDelay, then shuts the server down (drain and
+// OnShutdown hooks) within ShutdownTimeout. With Upgrade, SIGHUP/SIGUSR2
+// start a new process of the binary on the same sockets and this one drains
+// once the new one is started, for zero-downtime deploys.
+//
+// Example
+//
+//	server.OnShutdown(func(ctx context.Context) error { return db.Close() })
+//	if err := core.Run(server, core.RunOptions{PreStopDelay: 5 * time.Second}); err != nil {
+//		log.Fatal(err)
+//	}
+func Run(server Server, opts ...RunOptions) error {
+	var opt RunOptions
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+	if len(opt.Signals) == 0 {
+		opt.Signals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+	}
+	if opt.ShutdownTimeout <= 0 {
+		opt.ShutdownTimeout = DefaultShutdownTimeout
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), opt.Signals...)
+	defer stop()
+
+	var upgrade chan os.Signal
+	if opt.Upgrade {
+		if len(opt.UpgradeSignals) == 0 {
+			opt.UpgradeSignals = defaultUpgradeSignals
+		}
+		upgrade = make(chan os.Signal, 1)
+		signal.Notify(upgrade, opt.UpgradeSignals...)
+		defer signal.Stop(upgrade)
+		// Chạy sau các start hook của service: process cũ chỉ drain khi process này đã sẵn sàng
+		server.OnStart(func(ctx context.Context) error { return notifyUpgraded() })
+	}
+
+	startErr := make(chan error, 1)
+	go func() {
+		startErr <- server.Start()
+	}()
+
+	upgraded := false
+wait:
+	for {
+		select {
+		case err := <-startErr:
+			// Server dừng trước khi có signal, ví dụ không listen được hoặc /terminate
+			if errors.Is(err, http.ErrServerClosed) {
+				return nil
+			}
+			return err
+		case <-ctx.Done():
+			break wait
+		case <-upgrade:
+			if err := Upgrade(opt.UpgradeTimeout); err != nil {
+				log.Printf("Upgrade failed, still serving: %v", err)
+				continue
+			}
+			upgraded = true
+			break wait
+		}
+	}
+	// Signal thứ hai sẽ kết thúc process ngay như mặc định
+	stop()
+
+	if upgraded {
+		// Process mới đã nhận socket nên không cần chờ load balancer
+		log.Println("Upgraded, draining")
+	} else {
+		log.Printf("Shutdown signal received, draining (pre-stop delay %s)", opt.PreStopDelay)
+		server.SetReady(false)
+		time.Sleep(opt.PreStopDelay)
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), opt.ShutdownTimeout)
+	defer cancel()
+	err := server.Shutdown(shutdownCtx)
+
+	if startErr := <-startErr; startErr != nil && !errors.Is(startErr, http.ErrServerClosed) {
+		err = errors.Join(err, startErr)
+	}
+	return err
+}
@@ -0,0 +1,125 @@
+package core
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Giá trị mặc định chống slowloris khi Timeouts để trống
+const (
+	DefaultReadHeaderTimeout = 10 * time.Second
+	DefaultIdleTimeout       = 120 * time.Second
+)
+
+// TimeoutsConfig bounds the phases of a connection; 0 means the default for
+// ReadHeader and Idle, and no timeout for Read and Write.
+//
+// Engine mapping: gin, echo and std set the http.Server fields of the same
+// name. fasthttp (fiber) has no header-only timeout, so ReadHeader bounds the
+// whole request there when Read is not set.
+type TimeoutsConfig struct {
+	// ReadHeader bounds reading the request headers.
+	ReadHeader time.Duration `mapstructure:"read-header" yaml:"read-header"`
+	// Read bounds reading the whole request, body included.
+	Read time.Duration `mapstructure:"read" yaml:"read"`
+	// Write bounds writing the response; keep it 0 for Stream handlers (SSE
+	// lifts it per event).
+	Write time.Duration `mapstructure:"write" yaml:"write"`
+	// Idle bounds the wait for the next request on a keep-alive connection.
+	Idle time.Duration `mapstructure:"idle" yaml:"idle"`
+}
+
+func (t TimeoutsConfig) GetReadHeader() time.Duration {
+	if t.ReadHeader <= 0 {
+		return DefaultReadHeaderTimeout
+	}
+	return t.ReadHeader
+}
+
+func (t TimeoutsConfig) GetIdle() time.Duration {
+	if t.Idle <= 0 {
+		return DefaultIdleTimeout
+	}
+	return t.Idle
+}
+
+// LimitsConfig caps the resources of a connection; 0 keeps the engine default.
+//
+// Engine mapping: MaxHeaderBytes is http.Server.MaxHeaderBytes (default 1 MB)
+// on gin, echo and std, and the read buffer size on fiber (default 4 KB).
+// MaxBodyBytes is checked by the adapters when the body is read, see
+// BodyLimit; fiber streams the request body for it, so its own BodyLimit is
+// not used. MaxConnections makes net/http listeners stop accepting once the limit is
+// reached, so new connections wait in the backlog; fiber uses fasthttp's
+// Concurrency, which answers 503 and closes them instead.
+type LimitsConfig struct {
+	MaxHeaderBytes int `mapstructure:"max-header-bytes" yaml:"max-header-bytes"`
+	// MaxBodyBytes caps the request body, per group or route with BodyLimit.
+	MaxBodyBytes int64 `mapstructure:"max-body-bytes" yaml:"max-body-bytes"`
+	// MaxConnections caps the concurrent connections over all listeners.
+	MaxConnections int `mapstructure:"max-connections" yaml:"max-connections"`
+}
+
+// ApplyLimits sets the timeouts and limits of cfg on a net/http server.
+func ApplyLimits(srv *http.Server, cfg *Config) {
+	srv.ReadHeaderTimeout = cfg.Timeouts.GetReadHeader()
+	srv.ReadTimeout = cfg.Timeouts.Read
+	srv.WriteTimeout = cfg.Timeouts.Write
+	srv.IdleTimeout = cfg.Timeouts.GetIdle()
+	srv.MaxHeaderBytes = cfg.Limits.MaxHeaderBytes
+}
+
+// LimitListeners caps the connections accepted over all listeners to n; n <= 0
+// returns them unchanged.
+func LimitListeners(listeners []net.Listener, n int) []net.Listener {
+	if n <= 0 {
+		return listeners
+	}
+	sem := make(chan struct{}, n)
+	limited := make([]net.Listener, len(listeners))
+	for i, ln := range listeners {
+		limited[i] = &limitListener{Listener: ln, sem: sem, done: make(chan struct{})}
+	}
+	return limited
+}
+
+// limitListener giữ một slot của sem cho mỗi connection đang mở
+type limitListener struct {
+	net.Listener
+	sem       chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func (l *limitListener) Accept() (net.Conn, error) {
+	select {
+	case l.sem <- struct{}{}:
+	case <-l.done:
+		return nil, net.ErrClosed
+	}
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		<-l.sem
+		return nil, err
+	}
+	return &limitConn{Conn: conn, release: func() { <-l.sem }}, nil
+}
+
+func (l *limitListener) Close() error {
+	l.closeOnce.Do(func() { close(l.done) })
+	return l.Listener.Close()
+}
+
+type limitConn struct {
+	net.Conn
+	releaseOnce sync.Once
+	release     func()
+}
+
+func (c *limitConn) Close() error {
+	err := c.Conn.Close()
+	c.releaseOnce.Do(c.release)
+	return err
+}
@@ -0,0 +1,163 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Listen opens the listeners of the config: every entry of Listeners, or a
+// TCP listener on GetAddr when Listeners is empty.
+func (c *Config) Listen() ([]net.Listener, error) {
+	specs := c.Listeners
+	if len(specs) == 0 {
+		specs = []string{"tcp://" + c.GetAddr()}
+	}
+	listeners := make([]net.Listener, 0, len(specs))
+	for _, spec := range specs {
+		ln, err := Listen(spec)
+		if err != nil {
+			CloseListeners(listeners)
+			return nil, err
+		}
+		listeners = append(listeners, ln)
+	}
+	return listeners, nil
+}
+
+// Listen opens the listener described by spec:
+//
+//	tcp://127.0.0.1:8080
+//	unix:///run/app.sock?mode=0660  socket file mode, default from the umask
+//	fd://3                          inherited file descriptor
+//	fd://http                       systemd socket named in LISTEN_FDNAMES
+//
+// During an Upgrade the new process gets the socket of the previous one for
+// the same spec instead of opening it again.
+func Listen(spec string) (net.Listener, error) {
+	if ln := takeInherited(spec); ln != nil {
+		return track(ln, spec), nil
+	}
+	u, err := url.Parse(spec)
+	if err != nil {
+		return nil, fmt.Errorf("listener %q: %w", spec, err)
+	}
+	var ln net.Listener
+	switch u.Scheme {
+	case "tcp", "tcp4", "tcp6":
+		ln, err = net.Listen(u.Scheme, u.Host)
+	case "unix":
+		ln, err = listenUnix(u)
+	case "fd":
+		ln, err = listenFD(u.Host)
+	default:
+		return nil, fmt.Errorf("listener %q: unsupported scheme %q (tcp, unix or fd)", spec, u.Scheme)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return track(ln, spec), nil
+}
+
+func listenUnix(u *url.URL) (net.Listener, error) {
+	path := u.Path
+	if path == "" {
+		return nil, fmt.Errorf("listener %q: missing socket path", u)
+	}
+	// Socket cũ còn sót lại sau khi process chết thì xoá, file thường thì không
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("listener %q: %s exists and is not a socket", u, path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if mode := u.Query().Get("mode"); mode != "" {
+		perm, err := strconv.ParseUint(mode, 8, 32)
+		if err == nil {
+			err = os.Chmod(path, os.FileMode(perm))
+		}
+		if err != nil {
+			ln.Close()
+			return nil, fmt.Errorf("listener %q: mode: %w", u, err)
+		}
+	}
+	return ln, nil
+}
+
+// listenSDFirstFD là fd đầu tiên systemd truyền cho service (SD_LISTEN_FDS_START)
+const listenSDFirstFD = 3
+
+func listenFD(name string) (net.Listener, error) {
+	fd, err := strconv.Atoi(name)
+	if err != nil {
+		if fd, err = systemdFD(name); err != nil {
+			return nil, err
+		}
+	}
+	file := os.NewFile(uintptr(fd), "fd://"+name)
+	if file == nil {
+		return nil, fmt.Errorf("listener fd://%s: invalid file descriptor", name)
+	}
+	defer file.Close()
+	ln, err := net.FileListener(file)
+	if err != nil {
+		return nil, fmt.Errorf("listener fd://%s: %w", name, err)
+	}
+	return ln, nil
+}
+
+// systemdFD tìm fd có tên name theo LISTEN_PID, LISTEN_FDS và LISTEN_FDNAMES
+func systemdFD(name string) (int, error) {
+	if pid, _ := strconv.Atoi(os.Getenv("LISTEN_PID")); pid != os.Getpid() {
+		return 0, fmt.Errorf("listener fd://%s: no sockets passed by systemd", name)
+	}
+	count, _ := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	for i, fdName := range strings.Split(os.Getenv("LISTEN_FDNAMES"), ":") {
+		if fdName == name && i < count {
+			return listenSDFirstFD + i, nil
+		}
+	}
+	return 0, fmt.Errorf("listener fd://%s: not found in LISTEN_FDNAMES", name)
+}
+
+// CloseListeners closes every listener, e.g. when Start fails before serving.
+func CloseListeners(listeners []net.Listener) {
+	for _, ln := range listeners {
+		ln.Close()
+	}
+}
+
+// ServeListeners runs serve on every listener and returns the first error,
+// after closing the other listeners. scheme ("http" or "https") is only used
+// in the log of TCP listeners.
+func ServeListeners(scheme string, listeners []net.Listener, serve func(net.Listener) error) error {
+	if len(listeners) == 0 {
+		return errors.New("server: no listener")
+	}
+	errs := make(chan error, len(listeners))
+	for _, ln := range listeners {
+		addr := ln.Addr()
+		if addr.Network() == "unix" {
+			log.Printf("Server is running at unix://%s", addr)
+		} else {
+			log.Printf("Server is running at %s://%s", scheme, addr)
+		}
+		go func(ln net.Listener) {
+			errs <- serve(ln)
+		}(ln)
+	}
+	err := <-errs
+	CloseListeners(listeners)
+	return err
+}
@@ -0,0 +1,458 @@
+package core
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultMultipartMaxMemory is the part of a multipart form kept in memory,
+// the rest of the files goes to temporary files.
+const DefaultMultipartMaxMemory = 32 << 20
+
+// Context keys của multipart: cấu hình đang áp dụng và form đã parse
+const (
+	// MultipartKey is the Context key of the MultipartConfig of the request,
+	// set by the adapters from Config.Multipart and overridden by Multipart.
+	MultipartKey = "core.multipart"
+	// MultipartFormKey is the Context key of the parsed *multipart.Form; the
+	// adapters remove its temporary files when the request ends.
+	MultipartFormKey = "core.multipartForm"
+	formValuesKey    = "core.formValues"
+)
+
+// MultipartConfig limits multipart uploads; 0 or empty means no limit. The
+// whole body is also bounded by the body limit, see BodyLimit.
+type MultipartConfig struct {
+	// MaxMemory is the part of the form kept in memory by MultipartForm,
+	// DefaultMultipartMaxMemory when 0.
+	MaxMemory int64 `mapstructure:"max-memory" yaml:"max-memory"`
+	// MaxFileSize caps each file, MaxTotalSize all the files of the request.
+	MaxFileSize  int64 `mapstructure:"max-file-size" yaml:"max-file-size"`
+	MaxTotalSize int64 `mapstructure:"max-total-size" yaml:"max-total-size"`
+	// AllowedTypes is the allowlist of the types sniffed from the content of
+	// the files (not the Content-Type sent by the client), e.g. image/png or
+	// image/*.
+	AllowedTypes []string `mapstructure:"allowed-types" yaml:"allowed-types"`
+	// Storage receives the files of SaveUploadedFile, DiskStorage when nil.
+	Storage Storage `mapstructure:"-" yaml:"-"`
+	// FileName names the files of StreamUploads from their client file
+	// name, UniqueFileName when nil. Names must not collide.
+	FileName func(filename string) string `mapstructure:"-" yaml:"-"`
+}
+
+func (m MultipartConfig) GetMaxMemory() int64 {
+	if m.MaxMemory <= 0 {
+		return DefaultMultipartMaxMemory
+	}
+	return m.MaxMemory
+}
+
+func (m MultipartConfig) GetStorage() Storage {
+	if m.Storage == nil {
+		return DiskStorage{}
+	}
+	return m.Storage
+}
+
+func (m MultipartConfig) GetFileName() func(string) string {
+	if m.FileName == nil {
+		return UniqueFileName
+	}
+	return m.FileName
+}
+
+// UniqueFileName returns a random name keeping the extension of filename,
+// e.g. 3f9c...e1.png; the client file name itself is never used as a path.
+func UniqueFileName(filename string) string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err)
+	}
+	// Chỉ giữ đuôi ngắn gồm chữ và số, ví dụ .png
+	ext := strings.ToLower(filepath.Ext(filename))
+	if len(ext) < 2 || len(ext) > 16 || strings.IndexFunc(ext[1:], func(r rune) bool {
+		return (r < 'a' || r > 'z') && (r < '0' || r > '9')
+	}) >= 0 {
+		ext = ""
+	}
+	return hex.EncodeToString(b[:]) + ext
+}
+
+// Storage is where uploaded files are written, e.g. a local directory or an
+// object store. Save returns where the file was stored (path, key, URL). The
+// reader fails with *http.MaxBytesError past the size limits; Save must then
+// discard what it wrote and return the error.
+type Storage interface {
+	Save(ctx context.Context, name string, r io.Reader) (string, error)
+}
+
+// DiskStorage writes files under Dir. Names must then stay inside Dir; with
+// an empty Dir they are used as given, like gin's SaveUploadedFile. Existing
+// files are never overwritten: Save fails instead.
+type DiskStorage struct {
+	Dir string
+}
+
+func (d DiskStorage) Save(ctx context.Context, name string, r io.Reader) (string, error) {
+	path := name
+	if d.Dir != "" {
+		if !filepath.IsLocal(name) {
+			return "", fmt.Errorf("storage: invalid file name %q", name)
+		}
+		path = filepath.Join(d.Dir, name)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return "", err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o640)
+	if err != nil {
+		return "", err
+	}
+	// Từ đây file là của Save nên được xoá khi lỗi
+	_, err = io.Copy(f, r)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return "", err
+	}
+	return path, nil
+}
+
+// Multipart replaces the MultipartConfig for the rest of the chain, e.g. to
+// allow bigger files or other types on one route or group.
+func Multipart(cfg MultipartConfig) Handler {
+	return func(c Context) {
+		c.Set(MultipartKey, cfg)
+		c.Next()
+	}
+}
+
+// GetMultipartConfig returns the MultipartConfig of the request.
+func GetMultipartConfig(c Context) MultipartConfig {
+	cfg, _ := c.Get(MultipartKey).(MultipartConfig)
+	return cfg
+}
+
+// ParseMultipartForm reads the multipart body of c within the limits of its
+// MultipartConfig, once per request. Adapters implement
+// Context.MultipartForm with it.
+func ParseMultipartForm(c Context) (*multipart.Form, error) {
+	if form, ok := c.Get(MultipartFormKey).(*multipart.Form); ok {
+		return form, nil
+	}
+	mr, err := multipartReader(c)
+	if err != nil {
+		return nil, err
+	}
+	cfg := GetMultipartConfig(c)
+	if cfg.MaxFileSize > 0 || cfg.MaxTotalSize > 0 {
+		var stop func()
+		mr, stop = limitParts(mr, cfg)
+		defer stop()
+	}
+	form, err := mr.ReadForm(cfg.GetMaxMemory())
+	if err != nil {
+		return nil, multipartError(err)
+	}
+	if err := checkForm(form, cfg); err != nil {
+		form.RemoveAll()
+		return nil, err
+	}
+	c.Set(MultipartFormKey, form)
+	return form, nil
+}
+
+// RemoveMultipartForm removes the temporary files of a form stored under
+// MultipartFormKey; adapters call it when the request ends.
+func RemoveMultipartForm(form interface{}) {
+	if form, ok := form.(*multipart.Form); ok {
+		form.RemoveAll()
+	}
+}
+
+// FormValue returns the first value of a field of a multipart or URL-encoded
+// body, or "". Adapters implement Context.FormValue with it.
+func FormValue(c Context, name string) string {
+	values, ok := c.Get(formValuesKey).(url.Values)
+	if !ok {
+		values = url.Values{}
+		switch mediaType(c) {
+		case MIMEMultipartForm:
+			if form, err := ParseMultipartForm(c); err == nil {
+				values = form.Value
+			}
+		case MIMEApplicationForm:
+			if body, err := c.BodyBytes(); err == nil {
+				values, _ = url.ParseQuery(string(body))
+			}
+		}
+		c.Set(formValuesKey, values)
+	}
+	return values.Get(name)
+}
+
+// FormFile returns the first file of a field of the multipart body.
+// Adapters implement Context.FormFile with it.
+func FormFile(c Context, name string) (*multipart.FileHeader, error) {
+	form, err := ParseMultipartForm(c)
+	if err != nil {
+		return nil, err
+	}
+	if files := form.File[name]; len(files) > 0 {
+		return files[0], nil
+	}
+	return nil, NewHTTPError(StatusBadRequest, fmt.Sprintf("missing file %q", name)).Wrap(http.ErrMissingFile)
+}
+
+// SaveUploadedFile copies file to the Storage of the MultipartConfig of c
+// under dst. Adapters implement Context.SaveUploadedFile with it.
+func SaveUploadedFile(c Context, file *multipart.FileHeader, dst string) error {
+	src, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	_, err = GetMultipartConfig(c).GetStorage().Save(c.Context(), dst, src)
+	return err
+}
+
+// UploadedFile is a file written to a Storage by StreamUploads.
+type UploadedFile struct {
+	Field    string
+	Filename string
+	// ContentType is sniffed from the content.
+	ContentType string
+	Size        int64
+	// Location is what Storage.Save returned.
+	Location string
+}
+
+// StreamUploads passes the files of the multipart body of c to storage as
+// they arrive, without buffering them in memory or temporary files, and
+// returns the other fields. Files are named by the FileName of the
+// MultipartConfig, UniqueFileName by default, never by their client file
+// name; UploadedFile.Filename keeps the latter. The limits and the allowlist
+// of the MultipartConfig are checked on the way, so a rejected file may be
+// partly written before Save gets the error. It reads the body, so it
+// cannot be combined with MultipartForm. A DiskStorage needs a Dir.
+//
+// Example
+//
+//	values, files, err := core.StreamUploads(c, core.DiskStorage{Dir: "/data/uploads"})
+func StreamUploads(c Context, storage Storage) (url.Values, []UploadedFile, error) {
+	if !hasDir(storage) {
+		return nil, nil, errors.New("multipart: StreamUploads needs a DiskStorage with a Dir")
+	}
+	mr, err := multipartReader(c)
+	if err != nil {
+		return nil, nil, err
+	}
+	cfg := GetMultipartConfig(c)
+	values := url.Values{}
+	var files []UploadedFile
+	var total, fieldBytes int64
+	for {
+		part, err := mr.NextPart()
+		if errors.Is(err, io.EOF) {
+			return values, files, nil
+		}
+		if err != nil {
+			return nil, files, multipartError(err)
+		}
+		if part.FileName() == "" {
+			// Field giữ trong memory nên vẫn bị giới hạn bởi MaxMemory
+			value, err := io.ReadAll(io.LimitReader(part, cfg.GetMaxMemory()-fieldBytes+1))
+			if err != nil {
+				return nil, files, multipartError(err)
+			}
+			if fieldBytes += int64(len(value)); fieldBytes > cfg.GetMaxMemory() {
+				return nil, files, &http.MaxBytesError{Limit: cfg.GetMaxMemory()}
+			}
+			values.Add(part.FormName(), string(value))
+			continue
+		}
+
+		br := bufio.NewReaderSize(part, sniffLen)
+		head, _ := br.Peek(sniffLen)
+		contentType, err := checkFileType(part.FileName(), head, cfg.AllowedTypes)
+		if err != nil {
+			return nil, files, err
+		}
+		counter := &countingReader{r: fileLimitReader(br, cfg, total)}
+		location, err := storage.Save(c.Context(), cfg.GetFileName()(part.FileName()), counter)
+		total += counter.n
+		if err != nil {
+			return nil, files, err
+		}
+		files = append(files, UploadedFile{
+			Field:       part.FormName(),
+			Filename:    part.FileName(),
+			ContentType: contentType,
+			Size:        counter.n,
+			Location:    location,
+		})
+	}
+}
+
+// hasDir từ chối DiskStorage không có Dir: file sẽ rơi vào thư mục làm việc
+func hasDir(storage Storage) bool {
+	switch d := storage.(type) {
+	case DiskStorage:
+		return d.Dir != ""
+	case *DiskStorage:
+		return d != nil && d.Dir != ""
+	}
+	return storage != nil
+}
+
+// sniffLen là số byte http.DetectContentType xét
+const sniffLen = 512
+
+func mediaType(c Context) string {
+	mt, _, _ := mime.ParseMediaType(c.Header(HeaderContentType))
+	return mt
+}
+
+func multipartReader(c Context) (*multipart.Reader, error) {
+	mt, params, err := mime.ParseMediaType(c.Header(HeaderContentType))
+	if err != nil || mt != MIMEMultipartForm || params["boundary"] == "" {
+		return nil, NewHTTPError(StatusUnsupportedMediaType, "request body is not multipart/form-data").Wrap(http.ErrNotMultipart)
+	}
+	return multipart.NewReader(c.Body(), params["boundary"]), nil
+}
+
+// multipartError giữ lỗi vượt giới hạn (413), các lỗi parse khác là 400
+func multipartError(err error) error {
+	var mbe *http.MaxBytesError
+	if errors.As(err, &mbe) {
+		return err
+	}
+	if errors.Is(err, multipart.ErrMessageTooLarge) {
+		return NewHTTPError(StatusRequestEntityTooLarge, "multipart form too large").Wrap(err)
+	}
+	return NewHTTPError(StatusBadRequest, "invalid multipart body").Wrap(err)
+}
+
+// limitParts chuyển từng part của mr qua một pipe tới multipart.Reader mà
+// ReadForm đọc, với file bị giới hạn bởi MaxFileSize và MaxTotalSize, nên
+// việc đọc body dừng ngay khi vượt giới hạn thay vì sau khi ReadForm đã ghi cả
+// file. stop đóng pipe và chờ goroutine copy kết thúc.
+func limitParts(mr *multipart.Reader, cfg MultipartConfig) (limited *multipart.Reader, stop func()) {
+	pr, pw := io.Pipe()
+	mw := multipart.NewWriter(pw)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		pw.CloseWithError(copyParts(mw, mr, cfg))
+	}()
+	return multipart.NewReader(pr, mw.Boundary()), func() {
+		pr.Close()
+		<-done
+	}
+}
+
+func copyParts(mw *multipart.Writer, mr *multipart.Reader, cfg MultipartConfig) error {
+	var total int64
+	for {
+		part, err := mr.NextPart()
+		if errors.Is(err, io.EOF) {
+			return mw.Close()
+		}
+		if err != nil {
+			return err
+		}
+		w, err := mw.CreatePart(part.Header)
+		if err != nil {
+			return err
+		}
+		if part.FileName() == "" {
+			// Field do ReadForm giới hạn theo MaxMemory
+			if _, err := io.Copy(w, part); err != nil {
+				return err
+			}
+			continue
+		}
+		counter := &countingReader{r: fileLimitReader(part, cfg, total)}
+		_, err = io.Copy(w, counter)
+		total += counter.n
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// checkForm kiểm tra kiểu của các file sau khi ReadForm đọc xong; kích thước
+// đã được limitParts giới hạn trong lúc đọc
+func checkForm(form *multipart.Form, cfg MultipartConfig) error {
+	if len(cfg.AllowedTypes) == 0 {
+		return nil
+	}
+	for _, files := range form.File {
+		for _, file := range files {
+			f, err := file.Open()
+			if err != nil {
+				return err
+			}
+			head := make([]byte, sniffLen)
+			n, _ := io.ReadFull(f, head)
+			f.Close()
+			if _, err := checkFileType(file.Filename, head[:n], cfg.AllowedTypes); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkFileType trả về kiểu sniff từ head, lỗi 415 khi không nằm trong allowed
+func checkFileType(filename string, head []byte, allowed []string) (string, error) {
+	contentType, _, _ := mime.ParseMediaType(http.DetectContentType(head))
+	if len(allowed) == 0 {
+		return contentType, nil
+	}
+	for _, pattern := range allowed {
+		prefix, wildcard := strings.CutSuffix(pattern, "/*")
+		if pattern == contentType || wildcard && strings.HasPrefix(contentType, prefix+"/") {
+			return contentType, nil
+		}
+	}
+	return "", NewHTTPError(StatusUnsupportedMediaType, fmt.Sprintf("file %q has a type that is not allowed", filename)).
+		With("contentType", contentType)
+}
+
+// fileLimitReader giới hạn một file theo MaxFileSize và phần còn lại của MaxTotalSize
+func fileLimitReader(r io.Reader, cfg MultipartConfig, total int64) io.Reader {
+	remaining, limit := cfg.MaxFileSize, cfg.MaxFileSize
+	if cfg.MaxTotalSize > 0 && (limit <= 0 || cfg.MaxTotalSize-total < remaining) {
+		remaining, limit = cfg.MaxTotalSize-total, cfg.MaxTotalSize
+	}
+	if limit <= 0 {
+		return r
+	}
+	return &limitedReader{r: r, remaining: remaining, limit: limit}
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
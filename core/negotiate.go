@@ -0,0 +1,205 @@
+package core
+
+import (
+	"fmt"
+	"mime"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Offers offers v in every registered media type whose codec can encode it,
+// for handlers that return one value in whatever format the client wants:
+//
+//	return c.Negotiate(core.StatusOK, core.Offers(user))
+//
+// With types, only those are offered.
+func Offers(v interface{}, types ...string) map[string]interface{} {
+	if len(types) == 0 {
+		types = CodecTypes()
+	}
+	offers := make(map[string]interface{}, len(types))
+	for _, t := range types {
+		if s, ok := GetCodec(t).(Supporter); ok && !s.Supports(v) {
+			continue
+		}
+		offers[t] = v
+	}
+	return offers
+}
+
+// ProducesKey is the Context key of the media types set by Produces.
+const ProducesKey = "core.produces"
+
+// Produces lets the typed handlers (NewHandler) of the rest of the chain
+// write their result in any of types the client accepts, instead of JSON
+// only. Codecs other than JSON may ignore json tags, so the field names of
+// the result can change with the format.
+//
+//	server.Add("GET", "/report", core.MustHandler(report), core.Produces(core.MIMEApplicationJSON, core.MIMETextCSV))
+func Produces(types ...string) Handler {
+	return func(c Context) {
+		c.Set(ProducesKey, types)
+		c.Next()
+	}
+}
+
+// Negotiate writes the offer that best matches the Accept header, encoded
+// with the codec of its media type; []byte and string offers of types
+// without a codec are written as is. Offers the client accepts equally are
+// picked in the order of CodecTypes, and without Accept the first one wins.
+// When nothing matches it returns a 406 problem listing the offered types.
+// Adapters implement Context.Negotiate with it.
+func Negotiate(c Context, code int, offers map[string]interface{}) error {
+	types := make([]string, 0, len(offers))
+	for t := range offers {
+		types = append(types, t)
+	}
+	sortOffers(types)
+	c.SetHeader(HeaderVary, HeaderAccept)
+	chosen := NegotiateType(c.Header(HeaderAccept), types)
+	if chosen == "" {
+		return NewHTTPError(StatusNotAcceptable, "none of the offered media types is acceptable").With("available", types)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(chosen)
+	v := offers[chosen]
+	var body []byte
+	if codec := GetCodec(mediaType); codec != nil {
+		var err error
+		if body, err = codec.Marshal(v); err != nil {
+			return err
+		}
+	} else {
+		switch raw := v.(type) {
+		case []byte:
+			body = raw
+		case string:
+			body = []byte(raw)
+		default:
+			return fmt.Errorf("negotiate: no codec registered for %s", mediaType)
+		}
+	}
+	// Giữ charset như c.JSON/c.XML cho các kiểu văn bản
+	contentType := chosen
+	if chosen == mediaType && (strings.HasPrefix(mediaType, "text/") || mediaType == MIMEApplicationJSON || mediaType == MIMEApplicationXML) {
+		contentType += "; charset=utf-8"
+	}
+	return c.Blob(code, contentType, body)
+}
+
+// sortOffers xếp offer theo thứ tự của registry, kiểu chưa đăng ký theo alphabet
+func sortOffers(types []string) {
+	rank := func(t string) int {
+		mediaType, _, _ := mime.ParseMediaType(t)
+		if i := codecIndex(mediaType); i >= 0 {
+			return i
+		}
+		return 1 << 30
+	}
+	sort.SliceStable(types, func(i, j int) bool {
+		ri, rj := rank(types[i]), rank(types[j])
+		if ri != rj {
+			return ri < rj
+		}
+		return types[i] < types[j]
+	})
+}
+
+// NegotiateType returns the offer the Accept header prefers, or "" when it
+// accepts none. Ties keep the order of offers, except that JSON comes first
+// when the best offers only match a wildcard; an empty Accept accepts the
+// first offer.
+func NegotiateType(accept string, offers []string) string {
+	if len(offers) == 0 {
+		return ""
+	}
+	if strings.TrimSpace(accept) == "" {
+		return offers[0]
+	}
+	ranges := parseAccept(accept)
+	best, bestQ, bestSpecificity := "", 0.0, -1
+	jsonQ := 0.0
+	for _, offer := range offers {
+		q, specificity := acceptQuality(ranges, offer)
+		if q > bestQ || (q == bestQ && q > 0 && specificity > bestSpecificity) {
+			best, bestQ, bestSpecificity = offer, q, specificity
+		}
+		if offer == MIMEApplicationJSON {
+			jsonQ = q
+		}
+	}
+	if bestSpecificity == 0 && bestQ > 0 && jsonQ == bestQ {
+		return MIMEApplicationJSON
+	}
+	return best
+}
+
+type acceptRange struct {
+	typ, subtype string
+	q            float64
+}
+
+func parseAccept(accept string) []acceptRange {
+	var ranges []acceptRange
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if raw, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(raw, 64); err != nil {
+				continue
+			}
+		}
+		typ, subtype, _ := strings.Cut(mediaType, "/")
+		ranges = append(ranges, acceptRange{typ: typ, subtype: subtype, q: q})
+	}
+	return ranges
+}
+
+// acceptQuality là q và độ cụ thể của range cụ thể nhất khớp với offer
+// (type/sub 2 > type/* 1 > */* 0)
+func acceptQuality(ranges []acceptRange, offer string) (float64, int) {
+	mediaType, _, err := mime.ParseMediaType(offer)
+	if err != nil {
+		return 0, -1
+	}
+	typ, subtype, _ := strings.Cut(mediaType, "/")
+	q, specificity := 0.0, -1
+	for _, r := range ranges {
+		s := -1
+		switch {
+		case r.typ == typ && r.subtype == subtype:
+			s = 2
+		case r.typ == typ && r.subtype == "*":
+			s = 1
+		case r.typ == "*" && r.subtype == "*":
+			s = 0
+		}
+		if s > specificity {
+			q, specificity = r.q, s
+		}
+	}
+	return q, specificity
+}
+
+// BindBody decodes the body of c with the codec registered for its
+// Content-Type and validates obj. It returns handled false for the types
+// without a codec (forms, empty Content-Type), which adapters bind with the
+// engine instead.
+func BindBody(c Context, obj interface{}) (handled bool, err error) {
+	codec := GetCodec(mediaType(c))
+	if codec == nil {
+		return false, nil
+	}
+	body, err := c.BodyBytes()
+	if err != nil {
+		return true, err
+	}
+	if err := codec.Unmarshal(body, obj); err != nil {
+		return true, err
+	}
+	return true, Validate(obj)
+}
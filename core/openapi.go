@@ -0,0 +1,471 @@
+package core
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// OpenAPIPath is where Server.OpenAPI serves the generated document.
+const OpenAPIPath = "/openapi.json"
+
+// OpenAPIInfo is the info object of the generated document.
+type OpenAPIInfo struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// OpenAPI is an OpenAPI 3.1 document, limited to what the annotations can describe.
+type OpenAPI struct {
+	OpenAPI    string                           `json:"openapi"`
+	Info       OpenAPIInfo                      `json:"info"`
+	Paths      map[string]map[string]*Operation `json:"paths"`
+	Components *Components                      `json:"components,omitempty"`
+}
+
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas,omitempty"`
+}
+
+type Operation struct {
+	OperationID string               `json:"operationId,omitempty"`
+	Summary     string               `json:"summary,omitempty"`
+	Tags        []string             `json:"tags,omitempty"`
+	Parameters  []*Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema,omitempty"`
+}
+
+type RequestBody struct {
+	Description string                `json:"description,omitempty"`
+	Required    bool                  `json:"required,omitempty"`
+	Content     map[string]*MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string                `json:"description"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema,omitempty"`
+}
+
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+}
+
+// OpenAPIHandler serves the document built from the loaded @Api routes. The
+// document is built on the first request, after the server has loaded its routes.
+func (b *DynamicRouter) OpenAPIHandler(info OpenAPIInfo, rootPath string) Handler {
+	var once sync.Once
+	var doc *OpenAPI
+	return func(c Context) {
+		once.Do(func() {
+			doc = b.BuildOpenAPI(info, rootPath)
+		})
+		c.JSON(StatusOK, doc)
+	}
+}
+
+// BuildOpenAPI builds the document from the @Api routes and the Go types their
+// annotations reference: the Schemas emitted by go-server-gen, or else the
+// types read from the source of the handler's package.
+func (b *DynamicRouter) BuildOpenAPI(info OpenAPIInfo, rootPath string) *OpenAPI {
+	if info.Title == "" {
+		info.Title = "API"
+	}
+	if info.Version == "" {
+		info.Version = "1.0.0"
+	}
+	doc := &OpenAPI{
+		OpenAPI: "3.1.0",
+		Info:    info,
+		Paths:   make(map[string]map[string]*Operation),
+	}
+	schemas := newSchemaBuilder()
+
+	for _, d := range b.docs {
+		// OpenAPI không mô tả được WebSocket
+		if d.Route.Method == MethodWS {
+			continue
+		}
+		var pkgTypes map[string]*ast.TypeSpec
+		if d.Route.Schemas != nil {
+			for name, schema := range d.Route.Schemas {
+				schemas.components[name] = schema
+			}
+		} else {
+			pkgTypes = schemas.loadPackage(filepath.Dir(d.File))
+		}
+		path := openAPIPath(strings.TrimRight(rootPath, "/") + "/" + strings.TrimLeft(d.Route.Path, "/"))
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = make(map[string]*Operation)
+		}
+		doc.Paths[path][strings.ToLower(d.Route.Method)] = buildOperation(d, path, schemas, pkgTypes)
+	}
+
+	if len(schemas.components) > 0 {
+		doc.Components = &Components{Schemas: schemas.components}
+	}
+	return doc
+}
+
+func buildOperation(d apiDoc, path string, schemas *schemaBuilder, pkgTypes map[string]*ast.TypeSpec) *Operation {
+	r := d.Route
+	op := &Operation{
+		OperationID: d.OperationID,
+		Summary:     r.Summary,
+		Tags:        r.Tags,
+		Responses:   make(map[string]*Response),
+	}
+
+	declared := make(map[string]bool)
+	for _, p := range r.Params {
+		declared[p.In+":"+p.Name] = true
+		op.Parameters = append(op.Parameters, &Parameter{
+			Name:        p.Name,
+			In:          p.In,
+			Description: p.Description,
+			Required:    p.Required,
+			Schema:      schemas.typeString(p.Type, pkgTypes),
+		})
+	}
+	// Path param chưa khai báo bằng @Param vẫn phải có trong tài liệu
+	for _, name := range pathParams(path) {
+		if !declared["path:"+name] {
+			op.Parameters = append(op.Parameters, &Parameter{
+				Name:     name,
+				In:       "path",
+				Required: true,
+				Schema:   &Schema{Type: "string"},
+			})
+		}
+	}
+
+	if r.Body != nil {
+		op.RequestBody = &RequestBody{
+			Description: r.Body.Description,
+			Required:    true,
+			Content: map[string]*MediaType{
+				MIMEApplicationJSON: {Schema: schemas.typeString(r.Body.Type, pkgTypes)},
+			},
+		}
+	}
+
+	for _, res := range r.Response {
+		response := &Response{Description: res.Description}
+		if response.Description == "" {
+			response.Description = statusText(res.Code)
+		}
+		if res.Type != "" {
+			response.Content = map[string]*MediaType{
+				MIMEApplicationJSON: {Schema: schemas.typeString(res.Type, pkgTypes)},
+			}
+		}
+		op.Responses[res.Code] = response
+	}
+	if len(op.Responses) == 0 {
+		op.Responses["200"] = &Response{Description: statusText("200")}
+	}
+	return op
+}
+
+// openAPIPath converts /users/:id and /files/*path to /users/{id} and /files/{path}.
+func openAPIPath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, seg := range segments {
+		if strings.HasPrefix(seg, ":") || strings.HasPrefix(seg, "*") {
+			segments[i] = "{" + seg[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+func pathParams(path string) []string {
+	var names []string
+	for _, seg := range strings.Split(path, "/") {
+		if strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}") {
+			names = append(names, seg[1:len(seg)-1])
+		}
+	}
+	return names
+}
+
+func statusText(code string) string {
+	if n, err := strconv.Atoi(code); err == nil && http.StatusText(n) != "" {
+		return http.StatusText(n)
+	}
+	return "Response " + code
+}
+
+// RouteSchemas returns the component schemas of the named types route
+// references, read from the Go sources in dir. go-server-gen emits them as
+// ParseRoute.Schemas.
+func RouteSchemas(dir string, route ParseRoute) map[string]*Schema {
+	s := newSchemaBuilder()
+	pkgTypes := s.loadPackage(dir)
+	for _, p := range route.Params {
+		s.typeString(p.Type, pkgTypes)
+	}
+	if route.Body != nil {
+		s.typeString(route.Body.Type, pkgTypes)
+	}
+	for _, res := range route.Response {
+		if res.Type != "" {
+			s.typeString(res.Type, pkgTypes)
+		}
+	}
+	return s.components
+}
+
+// schemaBuilder converts Go type expressions from the handler package source
+// into schemas, collecting named struct types as reusable components.
+type schemaBuilder struct {
+	packages   map[string]map[string]*ast.TypeSpec
+	components map[string]*Schema
+}
+
+func newSchemaBuilder() *schemaBuilder {
+	return &schemaBuilder{
+		packages:   make(map[string]map[string]*ast.TypeSpec),
+		components: make(map[string]*Schema),
+	}
+}
+
+// loadPackage parses the non-test Go files of dir and indexes their type declarations.
+func (s *schemaBuilder) loadPackage(dir string) map[string]*ast.TypeSpec {
+	if types, ok := s.packages[dir]; ok {
+		return types
+	}
+	types := make(map[string]*ast.TypeSpec)
+	s.packages[dir] = types
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		log.Printf("failed to read package %s: %v", dir, err)
+		return types
+	}
+	set := token.NewFileSet()
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		file, err := parser.ParseFile(set, filepath.Join(dir, name), nil, parser.ParseComments)
+		if err != nil {
+			log.Printf("failed to parse file %s: %v", name, err)
+			continue
+		}
+		for _, decl := range file.Decls {
+			genDecl, ok := decl.(*ast.GenDecl)
+			if !ok || genDecl.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range genDecl.Specs {
+				typeSpec := spec.(*ast.TypeSpec)
+				types[typeSpec.Name.Name] = typeSpec
+			}
+		}
+	}
+	return types
+}
+
+// typeString parses an annotation type such as User, []User or map[string]int.
+func (s *schemaBuilder) typeString(typ string, pkgTypes map[string]*ast.TypeSpec) *Schema {
+	expr, err := parser.ParseExpr(typ)
+	if err != nil {
+		log.Printf("invalid type %q in annotation: %v", typ, err)
+		return &Schema{}
+	}
+	return s.expr(expr, pkgTypes)
+}
+
+func (s *schemaBuilder) expr(expr ast.Expr, pkgTypes map[string]*ast.TypeSpec) *Schema {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		if schema := basicSchema(e.Name); schema != nil {
+			return schema
+		}
+		spec, ok := pkgTypes[e.Name]
+		if !ok {
+			// Kiểu đã có schema sinh sẵn bởi go-server-gen
+			if _, generated := s.components[e.Name]; generated {
+				return &Schema{Ref: "#/components/schemas/" + e.Name}
+			}
+			return &Schema{}
+		}
+		if _, done := s.components[e.Name]; !done {
+			// Đặt trước để kiểu đệ quy chỉ tham chiếu tới chính nó
+			s.components[e.Name] = &Schema{}
+			*s.components[e.Name] = *s.expr(spec.Type, pkgTypes)
+		}
+		return &Schema{Ref: "#/components/schemas/" + e.Name}
+	case *ast.StarExpr:
+		return s.expr(e.X, pkgTypes)
+	case *ast.ArrayType:
+		if ident, ok := e.Elt.(*ast.Ident); ok && ident.Name == "byte" {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: s.expr(e.Elt, pkgTypes)}
+	case *ast.MapType:
+		return &Schema{Type: "object", AdditionalProperties: s.expr(e.Value, pkgTypes)}
+	case *ast.SelectorExpr:
+		if pkg, ok := e.X.(*ast.Ident); ok && pkg.Name == "time" && e.Sel.Name == "Time" {
+			return &Schema{Type: "string", Format: "date-time"}
+		}
+		return &Schema{}
+	case *ast.StructType:
+		return s.structSchema(e, pkgTypes)
+	default:
+		return &Schema{}
+	}
+}
+
+func (s *schemaBuilder) structSchema(st *ast.StructType, pkgTypes map[string]*ast.TypeSpec) *Schema {
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	for _, field := range st.Fields.List {
+		tag := reflect.StructTag("")
+		if field.Tag != nil {
+			tag = reflect.StructTag(strings.Trim(field.Tag.Value, "`"))
+		}
+		name, skip := jsonName(tag)
+		if skip {
+			continue
+		}
+
+		// Struct nhúng: gộp các field vào struct cha như encoding/json
+		if len(field.Names) == 0 {
+			if embedded := s.embeddedStruct(field.Type, pkgTypes); embedded != nil && name == "" {
+				for k, v := range embedded.Properties {
+					schema.Properties[k] = v
+				}
+				schema.Required = append(schema.Required, embedded.Required...)
+			}
+			continue
+		}
+
+		for _, ident := range field.Names {
+			if !ident.IsExported() {
+				continue
+			}
+			propName := name
+			if propName == "" {
+				propName = ident.Name
+			}
+			prop := s.expr(field.Type, pkgTypes)
+			if field.Doc != nil {
+				prop = withDescription(prop, strings.TrimSpace(field.Doc.Text()))
+			} else if field.Comment != nil {
+				prop = withDescription(prop, strings.TrimSpace(field.Comment.Text()))
+			}
+			schema.Properties[propName] = prop
+			if isRequired(tag) {
+				schema.Required = append(schema.Required, propName)
+			}
+		}
+	}
+	sort.Strings(schema.Required)
+	return schema
+}
+
+func (s *schemaBuilder) embeddedStruct(expr ast.Expr, pkgTypes map[string]*ast.TypeSpec) *Schema {
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+	ident, ok := expr.(*ast.Ident)
+	if !ok {
+		return nil
+	}
+	spec, ok := pkgTypes[ident.Name]
+	if !ok {
+		return nil
+	}
+	st, ok := spec.Type.(*ast.StructType)
+	if !ok {
+		return nil
+	}
+	return s.structSchema(st, pkgTypes)
+}
+
+// withDescription adds a description without mutating a shared $ref schema.
+func withDescription(schema *Schema, desc string) *Schema {
+	if desc == "" {
+		return schema
+	}
+	copied := *schema
+	copied.Description = desc
+	return &copied
+}
+
+func jsonName(tag reflect.StructTag) (string, bool) {
+	value, ok := tag.Lookup("json")
+	if !ok {
+		return "", false
+	}
+	name, _, _ := strings.Cut(value, ",")
+	if name == "-" {
+		return "", true
+	}
+	return name, false
+}
+
+// isRequired đọc rule required từ tag binding (gin) hoặc validate
+func isRequired(tag reflect.StructTag) bool {
+	for _, key := range []string{"binding", "validate"} {
+		for _, rule := range strings.Split(tag.Get(key), ",") {
+			if rule == "required" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func basicSchema(name string) *Schema {
+	switch name {
+	case "string":
+		return &Schema{Type: "string"}
+	case "bool", "boolean":
+		return &Schema{Type: "boolean"}
+	case "int", "int8", "int16", "uint", "uint8", "uint16", "uint32", "byte", "integer":
+		return &Schema{Type: "integer"}
+	case "int32", "rune":
+		return &Schema{Type: "integer", Format: "int32"}
+	case "int64", "uint64":
+		return &Schema{Type: "integer", Format: "int64"}
+	case "float32":
+		return &Schema{Type: "number", Format: "float"}
+	case "float64", "number":
+		return &Schema{Type: "number", Format: "double"}
+	case "any", "interface{}":
+		return &Schema{}
+	default:
+		return nil
+	}
+}
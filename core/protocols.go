@@ -0,0 +1,83 @@
+package core
+
+import (
+	"crypto/tls"
+	"fmt"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"net/http"
+	"slices"
+)
+
+// Giao thức khai báo trong Config.Protocols
+const (
+	// ProtocolHTTP1 is HTTP/1.1, always served.
+	ProtocolHTTP1 = "http1"
+	// ProtocolHTTP2 is HTTP/2 over TLS, negotiated with ALPN.
+	ProtocolHTTP2 = "h2"
+	// ProtocolH2C is HTTP/2 over cleartext TCP (prior knowledge or Upgrade).
+	ProtocolH2C = "h2c"
+	// ProtocolHTTP3 is HTTP/3 over QUIC; no engine supports it yet.
+	ProtocolHTTP3 = "h3"
+)
+
+// DefaultProtocols is used when Config.Protocols is empty.
+var DefaultProtocols = []string{ProtocolHTTP1, ProtocolHTTP2}
+
+// NetHTTPProtocols are the protocols of the net/http based adapters.
+var NetHTTPProtocols = []string{ProtocolHTTP1, ProtocolHTTP2, ProtocolH2C}
+
+var knownProtocols = []string{ProtocolHTTP1, ProtocolHTTP2, ProtocolH2C, ProtocolHTTP3}
+
+// UnsupportedProtocolError is returned by Start when Config.Protocols lists a
+// protocol the engine cannot serve.
+type UnsupportedProtocolError struct {
+	Engine   string
+	Protocol string
+}
+
+func (e *UnsupportedProtocolError) Error() string {
+	return fmt.Sprintf("server: protocol %q is not supported by the %s engine", e.Protocol, e.Engine)
+}
+
+// CheckProtocols fails with UnsupportedProtocolError for the first protocol
+// of protocols that engine does not list in supported.
+func CheckProtocols(engine string, protocols []string, supported ...string) error {
+	for _, p := range protocols {
+		if !slices.Contains(knownProtocols, p) {
+			return fmt.Errorf("server: unknown protocol %q", p)
+		}
+		if !slices.Contains(supported, p) {
+			return &UnsupportedProtocolError{Engine: engine, Protocol: p}
+		}
+	}
+	return nil
+}
+
+// ConfigureProtocols applies protocols to a net/http server whose Handler,
+// TLSConfig and limits (ApplyLimits) are already set: h2c wraps the handler,
+// and HTTP/2 over TLS is turned off when h2 is not listed. h2 and h2c share
+// one http2.Server with the IdleTimeout of srv. Adapters call it from Start
+// after CheckProtocols.
+func ConfigureProtocols(srv *http.Server, protocols []string) error {
+	h2s := &http2.Server{IdleTimeout: srv.IdleTimeout}
+	if slices.Contains(protocols, ProtocolHTTP2) {
+		if err := http2.ConfigureServer(srv, h2s); err != nil {
+			return err
+		}
+	}
+	if slices.Contains(protocols, ProtocolH2C) {
+		srv.Handler = h2c.NewHandler(srv.Handler, h2s)
+	}
+	if slices.Contains(protocols, ProtocolHTTP2) {
+		return nil
+	}
+	// TLSNextProto rỗng (khác nil) tắt HTTP/2 mặc định của net/http
+	srv.TLSNextProto = map[string]func(*http.Server, *tls.Conn, http.Handler){}
+	if srv.TLSConfig != nil {
+		srv.TLSConfig.NextProtos = slices.DeleteFunc(slices.Clone(srv.TLSConfig.NextProtos), func(p string) bool {
+			return p == "h2"
+		})
+	}
+	return nil
+}
@@ -0,0 +1,373 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// TokenBucket refills Limit tokens per Window up to Burst, so short
+	// bursts pass while the average rate is kept.
+	TokenBucket = "token-bucket"
+	// SlidingWindow allows Limit requests in any Window, weighting the
+	// previous fixed window by how much of it still overlaps.
+	SlidingWindow = "sliding-window"
+)
+
+// RateLimitKeyFunc returns the key a request is counted under; an empty key
+// falls back to the client IP.
+type RateLimitKeyFunc func(c Context) string
+
+// RateLimitOptions configures RateLimit.
+type RateLimitOptions struct {
+	// Limit requests per Window.
+	Limit  int
+	Window time.Duration
+	// Algorithm is TokenBucket (default) or SlidingWindow.
+	Algorithm string
+	// Burst is the size of the token bucket; 0 means Limit.
+	Burst int
+	// Key defaults to RateLimitByIP. jwt.RateLimitByUser counts the requests
+	// of a user, RateLimitByHeader those of an API key.
+	Key RateLimitKeyFunc
+	// Store keeps the counters; nil means a new NewMemoryRateLimitStore.
+	Store RateLimitStore
+	// Prefix separates the counters of limiters sharing a Store.
+	Prefix string
+}
+
+// RateLimitPolicy is the limit a RateLimitStore applies to a key.
+type RateLimitPolicy struct {
+	Algorithm string
+	Limit     int
+	Window    time.Duration
+	Burst     int
+}
+
+// String formats the policy for the RateLimit-Policy header, e.g. 100;w=60.
+func (p RateLimitPolicy) String() string {
+	s := strconv.Itoa(p.Limit) + ";w=" + strconv.Itoa(ceilSeconds(p.Window))
+	if p.Algorithm == TokenBucket && p.Burst != p.Limit {
+		s += ";burst=" + strconv.Itoa(p.Burst)
+	}
+	return s
+}
+
+// RateLimitResult is the outcome of counting one request.
+type RateLimitResult struct {
+	Allowed bool
+	// Limit is the quota (the bucket size for TokenBucket) and Remaining
+	// what is left of it after this request.
+	Limit     int
+	Remaining int
+	// Reset is when the quota is whole again.
+	Reset time.Duration
+	// RetryAfter is when a denied request may be retried.
+	RetryAfter time.Duration
+}
+
+// RateLimitStore counts the requests of a key under a policy. Distributed
+// backends (Redis, ...) implement it to share the limits between instances.
+type RateLimitStore interface {
+	Allow(ctx context.Context, key string, policy RateLimitPolicy) (RateLimitResult, error)
+}
+
+// RateLimit returns the middleware limiting requests with opts. Every
+// response carries the RateLimit-Limit, -Remaining, -Reset and -Policy
+// headers; a request over the limit gets a 429 problem with Retry-After.
+// When the Store fails the request is let through and the error logged.
+func RateLimit(opts RateLimitOptions) Handler {
+	if opts.Limit <= 0 || opts.Window <= 0 {
+		panic("ratelimit: Limit and Window must be positive")
+	}
+	policy := RateLimitPolicy{Algorithm: opts.Algorithm, Limit: opts.Limit, Window: opts.Window, Burst: opts.Burst}
+	if policy.Algorithm == "" {
+		policy.Algorithm = TokenBucket
+	}
+	if policy.Algorithm != TokenBucket && policy.Algorithm != SlidingWindow {
+		panic(fmt.Sprintf("ratelimit: unknown algorithm %q", opts.Algorithm))
+	}
+	if policy.Burst <= 0 {
+		policy.Burst = policy.Limit
+	}
+	key := opts.Key
+	if key == nil {
+		key = RateLimitByIP
+	}
+	store := opts.Store
+	if store == nil {
+		store = NewMemoryRateLimitStore()
+	}
+	header := policy.String()
+
+	return func(c Context) {
+		k := key(c)
+		if k == "" {
+			k = RateLimitByIP(c)
+		}
+		res, err := store.Allow(c.Context(), opts.Prefix+k, policy)
+		if err != nil {
+			log.Printf("rate limit store failed, request allowed: %v", err)
+			c.Next()
+			return
+		}
+		c.SetHeader(HeaderRateLimitLimit, strconv.Itoa(res.Limit))
+		c.SetHeader(HeaderRateLimitRemaining, strconv.Itoa(res.Remaining))
+		c.SetHeader(HeaderRateLimitReset, strconv.Itoa(ceilSeconds(res.Reset)))
+		c.SetHeader(HeaderRateLimitPolicy, header)
+		if !res.Allowed {
+			retry := ceilSeconds(res.RetryAfter)
+			if retry < 1 {
+				retry = 1
+			}
+			c.SetHeader(HeaderRetryAfter, strconv.Itoa(retry))
+			c.Error(NewHTTPError(StatusTooManyRequests, "rate limit exceeded").With("retryAfter", retry))
+			return
+		}
+		c.Next()
+	}
+}
+
+// RateLimitByIP keys requests by the IP of the peer. Behind a proxy the peer
+// is the proxy; use a Key reading the header the proxy sets instead.
+func RateLimitByIP(c Context) string {
+	host, _, err := net.SplitHostPort(c.RemoteAddr())
+	if err != nil {
+		host = c.RemoteAddr()
+	}
+	return "ip:" + host
+}
+
+// RateLimitByHeader keys requests by an API key header, e.g. X-API-Key.
+func RateLimitByHeader(name string) RateLimitKeyFunc {
+	return func(c Context) string {
+		if v := c.Header(name); v != "" {
+			return "key:" + v
+		}
+		return ""
+	}
+}
+
+// ParseRate parses the value of @RateLimit: <limit>/<window> [algorithm],
+// where window is s, m, h, d or a duration, e.g. 100/m or 10/30s sliding-window.
+func ParseRate(s string) (RateLimitOptions, error) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 || len(fields) > 2 {
+		return RateLimitOptions{}, fmt.Errorf("invalid rate %q", s)
+	}
+	limit, window, ok := strings.Cut(fields[0], "/")
+	n, err := strconv.Atoi(limit)
+	if !ok || err != nil || n <= 0 {
+		return RateLimitOptions{}, fmt.Errorf("invalid rate %q", s)
+	}
+	opts := RateLimitOptions{Limit: n}
+	switch window {
+	case "s":
+		opts.Window = time.Second
+	case "m":
+		opts.Window = time.Minute
+	case "h":
+		opts.Window = time.Hour
+	case "d":
+		opts.Window = 24 * time.Hour
+	default:
+		if opts.Window, err = time.ParseDuration(window); err != nil || opts.Window <= 0 {
+			return RateLimitOptions{}, fmt.Errorf("invalid rate window %q", window)
+		}
+	}
+	if len(fields) == 2 {
+		if fields[1] != TokenBucket && fields[1] != SlidingWindow {
+			return RateLimitOptions{}, fmt.Errorf("unknown rate limit algorithm %q", fields[1])
+		}
+		opts.Algorithm = fields[1]
+	}
+	return opts, nil
+}
+
+const (
+	// rateLimitShards is the number of shards of a MemoryRateLimitStore,
+	// spreading the lock contention over the keys.
+	rateLimitShards = 64
+	// DefaultRateLimitMaxKeys is the number of keys a MemoryRateLimitStore
+	// holds by default.
+	DefaultRateLimitMaxKeys = 64 * 4096
+)
+
+// MemoryRateLimitStore keeps the counters in memory, sharded by key. Idle
+// keys are dropped once their quota is whole again, and a full shard evicts
+// the key closest to being whole to make room for a new one.
+type MemoryRateLimitStore struct {
+	shards [rateLimitShards]rateShard
+	// maxEntries is the number of keys per shard.
+	maxEntries int
+}
+
+type rateShard struct {
+	mu        sync.Mutex
+	entries   map[string]*rateEntry
+	nextSweep time.Time
+}
+
+// rateEntry holds the state of both algorithms for a key.
+type rateEntry struct {
+	// token bucket
+	tokens float64
+	last   time.Time
+	// sliding window
+	start time.Time
+	count int
+	prev  int
+
+	expires time.Time
+}
+
+// NewMemoryRateLimitStore returns an empty MemoryRateLimitStore holding at
+// most maxKeys keys, DefaultRateLimitMaxKeys when omitted. The bound is
+// split between the shards, so each keeps at least one key.
+func NewMemoryRateLimitStore(maxKeys ...int) *MemoryRateLimitStore {
+	n := DefaultRateLimitMaxKeys
+	if len(maxKeys) > 0 && maxKeys[0] > 0 {
+		n = maxKeys[0]
+	}
+	s := &MemoryRateLimitStore{maxEntries: max(1, n/rateLimitShards)}
+	for i := range s.shards {
+		s.shards[i].entries = make(map[string]*rateEntry)
+	}
+	return s
+}
+
+// Allow counts a request of key under policy; it never fails.
+func (s *MemoryRateLimitStore) Allow(_ context.Context, key string, policy RateLimitPolicy) (RateLimitResult, error) {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	shard := &s.shards[h.Sum32()%rateLimitShards]
+	now := time.Now()
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+	if now.After(shard.nextSweep) {
+		shard.sweep(now)
+	}
+	e, ok := shard.entries[key]
+	if !ok {
+		if len(shard.entries) >= s.maxEntries {
+			shard.sweep(now)
+		}
+		if len(shard.entries) >= s.maxEntries {
+			shard.evict()
+		}
+		e = &rateEntry{}
+		shard.entries[key] = e
+	}
+	if policy.Algorithm == SlidingWindow {
+		return e.slidingWindow(now, policy), nil
+	}
+	return e.tokenBucket(now, policy), nil
+}
+
+// Len returns the number of keys the store holds.
+func (s *MemoryRateLimitStore) Len() int {
+	n := 0
+	for i := range s.shards {
+		s.shards[i].mu.Lock()
+		n += len(s.shards[i].entries)
+		s.shards[i].mu.Unlock()
+	}
+	return n
+}
+
+// sweep drops the keys whose quota is whole again.
+func (r *rateShard) sweep(now time.Time) {
+	for k, e := range r.entries {
+		if now.After(e.expires) {
+			delete(r.entries, k)
+		}
+	}
+	r.nextSweep = now.Add(time.Minute)
+}
+
+// evict drops the key that expires first, losing the least of its count.
+func (r *rateShard) evict() {
+	var key string
+	var first *rateEntry
+	for k, e := range r.entries {
+		if first == nil || e.expires.Before(first.expires) {
+			key, first = k, e
+		}
+	}
+	delete(r.entries, key)
+}
+
+func (e *rateEntry) tokenBucket(now time.Time, p RateLimitPolicy) RateLimitResult {
+	burst := float64(p.Burst)
+	// Tokens refilled per second
+	rate := float64(p.Limit) / p.Window.Seconds()
+	if e.last.IsZero() {
+		e.tokens = burst
+	} else {
+		e.tokens = math.Min(burst, e.tokens+now.Sub(e.last).Seconds()*rate)
+	}
+	e.last = now
+
+	res := RateLimitResult{Limit: p.Burst}
+	if e.tokens >= 1 {
+		e.tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = seconds((1 - e.tokens) / rate)
+	}
+	res.Remaining = int(e.tokens)
+	res.Reset = seconds((burst - e.tokens) / rate)
+	e.expires = now.Add(res.Reset)
+	return res
+}
+
+func (e *rateEntry) slidingWindow(now time.Time, p RateLimitPolicy) RateLimitResult {
+	w := p.Window
+	start := now.Truncate(w)
+	if !e.start.Equal(start) {
+		// The previous window only counts when it is adjacent to the current one
+		if start.Sub(e.start) == w {
+			e.prev = e.count
+		} else {
+			e.prev = 0
+		}
+		e.start, e.count = start, 0
+	}
+	elapsed := now.Sub(start)
+	limit := float64(p.Limit)
+	estimate := float64(e.prev)*(1-float64(elapsed)/float64(w)) + float64(e.count)
+
+	res := RateLimitResult{Limit: p.Limit, Reset: w - elapsed}
+	e.expires = start.Add(2 * w)
+	if estimate+1 > limit {
+		// Wait until the share of the previous window has shrunk enough to leave
+		// room for one request
+		if e.count+1 <= p.Limit {
+			res.RetryAfter = time.Duration(float64(w)*(1-(limit-1-float64(e.count))/float64(e.prev))) - elapsed
+		} else {
+			res.RetryAfter = w - elapsed + time.Duration(float64(w)*(1-(limit-1)/float64(e.count)))
+		}
+		return res
+	}
+	e.count++
+	res.Allowed = true
+	res.Remaining = int(limit - estimate - 1)
+	return res
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
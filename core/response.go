@@ -0,0 +1,129 @@
+package core
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// HTMLTemplateKey is the Context key under which adapters store the
+// templates of Server.SetHTMLTemplate for Context.HTML.
+const HTMLTemplateKey = "core.htmlTemplate"
+
+// XML writes obj as XML with the XML header. Adapters implement Context.XML
+// with it.
+func XML(c Context, code int, obj interface{}) error {
+	body, err := xml.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	return c.Blob(code, MIMEApplicationXMLCharsetUTF8, append([]byte(xml.Header), body...))
+}
+
+// HTML renders the template name of Server.SetHTMLTemplate with data. The
+// template is rendered before anything is written, so a template error can
+// still be returned to Context.Error. Adapters implement Context.HTML with it.
+func HTML(c Context, code int, name string, data interface{}) error {
+	tmpl, _ := c.Get(HTMLTemplateKey).(*template.Template)
+	if tmpl == nil {
+		return errors.New("html: no template, see Server.SetHTMLTemplate")
+	}
+	var buf bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&buf, name, data); err != nil {
+		return err
+	}
+	return c.Blob(code, MIMETextHTMLCharsetUTF8, buf.Bytes())
+}
+
+// Attachment sends the file at path as a download named filename (the base
+// of path when empty). Adapters implement Context.Attachment with it.
+func Attachment(c Context, path, filename string) error {
+	if filename == "" {
+		filename = filepath.Base(path)
+	}
+	c.SetHeader(HeaderContentDisposition, mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	return c.File(path)
+}
+
+// Redirect sends a redirect to location; code must be a 3xx status.
+// Adapters implement Context.Redirect with it.
+func Redirect(c Context, code int, location string) error {
+	if code < StatusMultipleChoices || code > StatusPermanentRedirect {
+		return fmt.Errorf("redirect: invalid status %d", code)
+	}
+	c.SetHeader(HeaderLocation, location)
+	return c.Blob(code, "", nil)
+}
+
+// OpenFile opens the file sent by Context.File, or returns a 404 problem
+// when it does not exist or is a directory.
+func OpenFile(path string) (*os.File, fs.FileInfo, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil, NewHTTPError(StatusNotFound, "").Wrap(err)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	info, err := f.Stat()
+	if err == nil && info.IsDir() {
+		err = NewHTTPError(StatusNotFound, "")
+	}
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	return f, info, nil
+}
+
+// ServeFile sends the file at path for the net/http adapters, with the
+// Content-Type of its extension, Last-Modified and range requests.
+func ServeFile(w http.ResponseWriter, r *http.Request, path string) error {
+	f, info, err := OpenFile(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	http.ServeContent(w, r, info.Name(), info.ModTime(), f)
+	return nil
+}
+
+// CopyFlush copies r to w and flushes after every read, so the client gets
+// the data as it is produced; r is closed when it is an io.Closer. It is
+// Context.Stream for the net/http adapters.
+func CopyFlush(w http.ResponseWriter, r io.Reader) error {
+	if rc, ok := r.(io.Closer); ok {
+		defer rc.Close()
+	}
+	rc := http.NewResponseController(w)
+	// Gửi header trước: response đã flush là stream, Compress không nén nó
+	if err := rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	buf := make([]byte, 32<<10)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			if _, werr := w.Write(buf[:n]); werr != nil {
+				return werr
+			}
+			if ferr := rc.Flush(); ferr != nil && !errors.Is(ferr, http.ErrNotSupported) {
+				return ferr
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -2,6 +2,9 @@ package core
 
 import (
 	"context"
+	"html/template"
+	"net"
+	"net/http"
 )
 
 // Handler defines a generic HTTP handler.
@@ -18,11 +21,21 @@ type RouteConfig struct {
 	Method     string
 	Handler    Handler
 	Middleware []Handler
+	// Doc is the @Api annotation of the route, used for the OpenAPI document.
+	Doc *ParseRoute
+	// WebSocket and WSOptions serve the routes whose Method is MethodWS,
+	// instead of Handler.
+	WebSocket WSHandler
+	WSOptions WSOptions
 }
 
 // Server defines generic server operations.
 type Server interface {
+	// Start listens on Config.Listeners, or Host:Port, and serves until
+	// Shutdown.
 	Start() error
+	// Serve is Start on a listener opened by the caller.
+	Serve(ln net.Listener) error
 	Shutdown(ctx context.Context) error
 	Use(middleware ...Handler)
 	AddGroup(relativePath string, register func(rg RouterGroup), middleware ...Handler)
@@ -30,6 +43,38 @@ type Server interface {
 	RegisterHandlersWithTags(...interface{})
 	RegisterHandlers(handlers ...interface{})
 	Routes(routes []RouteConfig)
+	// WebSocket adds a GET route that upgrades to WebSocket and runs handler
+	// on the connection; Shutdown closes the connections with 1001.
+	WebSocket(relativePath string, handler WSHandler, opts ...WSOptions)
 	Static(relativePath, root string)
+	// HealthCheck registers /ping, /liveness, /readiness and /terminate.
+	// /readiness and /liveness run the checks added by RegisterHealthCheck.
+	// The control routes (/terminate, /routes, /log-level) are only added on
+	// the public listener when Config.Admin has no Port of its own and an
+	// admin Token or UseAdmin middleware guards them.
 	HealthCheck()
+	RegisterHealthCheck(name string, checker HealthChecker, opts ...HealthCheckOptions)
+	// SetReady(false) makes /readiness fail while the server keeps serving.
+	SetReady(ready bool)
+	// OnStart hooks run in order when Start is called, before listening.
+	OnStart(hooks ...Hook)
+	// OnShutdown hooks run in reverse order when Shutdown has drained the
+	// in-flight requests.
+	OnShutdown(hooks ...Hook)
+	// OpenAPI serves the document generated from the @Api annotations at OpenAPIPath.
+	OpenAPI(info OpenAPIInfo)
+	// SetErrorHandler replaces DefaultErrorHandler for Context.Error, panics
+	// and 404/405 responses.
+	SetErrorHandler(h ErrorHandler)
+	// SetHTMLTemplate sets the templates rendered by Context.HTML.
+	SetHTMLTemplate(t *template.Template)
+	// UseAdmin adds auth middleware (e.g. jwt.AuthMiddleware) to the admin
+	// control routes, after the token and loopback checks of Config.Admin.
+	UseAdmin(middleware ...Handler)
+	// AdminHandler returns the admin listener as an http.Handler, or nil when
+	// Config.Admin has no Port.
+	AdminHandler() http.Handler
+	// Handler returns the server as an http.Handler with all routes loaded,
+	// so requests can be served in-process (tests, custom listeners).
+	Handler() http.Handler
 }
@@ -0,0 +1,242 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultSSEHeartbeat is how often an idle event stream sends a comment, so
+// proxies keep the connection open and a gone client is noticed.
+const DefaultSSEHeartbeat = 15 * time.Second
+
+// Event is one Server-Sent Event. Data is sent as is when it is a string or
+// []byte and as JSON otherwise; multi-line data is split into data lines.
+type Event struct {
+	ID    string
+	Event string
+	Data  interface{}
+	// Retry tells the browser how long to wait before reconnecting.
+	Retry time.Duration
+}
+
+// EventStream writes Server-Sent Events; every Send and Comment is flushed
+// to the client. It is safe for concurrent use.
+type EventStream interface {
+	Send(e Event) error
+	// Comment sends a comment line, which clients ignore.
+	Comment(text string) error
+	// SetHeartbeat changes the interval of the heartbeat comments
+	// (DefaultSSEHeartbeat); 0 turns them off.
+	SetHeartbeat(interval time.Duration)
+	// LastEventID is the Last-Event-ID header a reconnecting client sends,
+	// to resume after the last event it received.
+	LastEventID() string
+	// Context is done when the client goes away or the handler returns.
+	Context() context.Context
+}
+
+// SSE sends the event stream of fn on a net/http response: it writes the
+// headers, lifts the write deadline of the server and runs ServeSSE with
+// the request context. The net/http adapters implement Context.SSE with it.
+func SSE(c Context, w http.ResponseWriter, fn func(EventStream) error) error {
+	SetSSEHeaders(c)
+	w.WriteHeader(StatusOK)
+	rc := http.NewResponseController(w)
+	// Stream sống lâu hơn WriteTimeout của server
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	flush := func() error {
+		if err := rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			return err
+		}
+		return nil
+	}
+	return ServeSSE(c.Context(), w, flush, c.Header(HeaderLastEventID), fn)
+}
+
+// SetSSEHeaders sets the response headers of an event stream on c.
+func SetSSEHeaders(c Context) {
+	c.SetHeader(HeaderContentType, MIMETextEventStream)
+	c.SetHeader(HeaderCacheControl, "no-cache")
+	// Tắt buffer của nginx
+	c.SetHeader("X-Accel-Buffering", "no")
+}
+
+// ServeSSE runs fn with an EventStream that writes to w and calls flush after
+// every event, once the headers have been written. The stream context is
+// derived from ctx and is cancelled when a write fails, i.e. the client is
+// gone; fn returning that cancellation is not an error.
+func ServeSSE(ctx context.Context, w io.Writer, flush func() error, lastEventID string, fn func(EventStream) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	s := &eventStream{
+		w:           w,
+		flush:       flush,
+		ctx:         ctx,
+		cancel:      cancel,
+		lastEventID: lastEventID,
+		heartbeat:   make(chan time.Duration),
+	}
+	defer func() {
+		cancel()
+		s.wg.Wait()
+	}()
+	// Gửi header ngay để client biết stream đã mở
+	if err := s.write(nil); err != nil {
+		return nil
+	}
+	s.wg.Add(1)
+	go s.keepAlive(DefaultSSEHeartbeat)
+
+	err := fn(s)
+	if err != nil && (s.failed() || (errors.Is(err, context.Canceled) && ctx.Err() != nil)) {
+		return nil
+	}
+	return err
+}
+
+type eventStream struct {
+	mu          sync.Mutex
+	w           io.Writer
+	flush       func() error
+	err         error
+	ctx         context.Context
+	cancel      context.CancelFunc
+	lastEventID string
+	heartbeat   chan time.Duration
+	wg          sync.WaitGroup
+}
+
+func (s *eventStream) Send(e Event) error {
+	if strings.ContainsAny(e.ID, "\r\n\x00") || strings.ContainsAny(e.Event, "\r\n\x00") {
+		return errors.New("sse: event id and name must be one line")
+	}
+	var data []byte
+	switch v := e.Data.(type) {
+	case nil:
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		var err error
+		if data, err = json.Marshal(v); err != nil {
+			return err
+		}
+	}
+
+	var buf bytes.Buffer
+	if e.ID != "" {
+		buf.WriteString("id: " + e.ID + "\n")
+	}
+	if e.Event != "" {
+		buf.WriteString("event: " + e.Event + "\n")
+	}
+	if e.Retry > 0 {
+		buf.WriteString("retry: " + strconv.FormatInt(e.Retry.Milliseconds(), 10) + "\n")
+	}
+	if data != nil {
+		for _, line := range sseLines(string(data)) {
+			buf.WriteString("data: " + line + "\n")
+		}
+	}
+	buf.WriteByte('\n')
+	return s.write(buf.Bytes())
+}
+
+func (s *eventStream) Comment(text string) error {
+	var buf bytes.Buffer
+	for _, line := range sseLines(text) {
+		buf.WriteString(": " + line + "\n")
+	}
+	buf.WriteByte('\n')
+	return s.write(buf.Bytes())
+}
+
+// sseNewlines chuẩn hoá CRLF và CR đơn, cả hai đều kết thúc dòng với EventSource
+var sseNewlines = strings.NewReplacer("\r\n", "\n", "\r", "\n")
+
+// sseLines tách s thành các dòng theo mọi kiểu xuống dòng của EventSource
+func sseLines(s string) []string {
+	return strings.Split(sseNewlines.Replace(s), "\n")
+}
+
+func (s *eventStream) SetHeartbeat(interval time.Duration) {
+	select {
+	case s.heartbeat <- interval:
+	case <-s.ctx.Done():
+	}
+}
+
+func (s *eventStream) LastEventID() string {
+	return s.lastEventID
+}
+
+func (s *eventStream) Context() context.Context {
+	return s.ctx
+}
+
+// write ghi và flush một event; lỗi ghi nghĩa là client đã đi, huỷ context
+func (s *eventStream) write(p []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	if err := s.ctx.Err(); err != nil {
+		return err
+	}
+	_, err := s.w.Write(p)
+	if err == nil {
+		err = s.flush()
+	}
+	if err != nil {
+		s.err = err
+		s.cancel()
+	}
+	return err
+}
+
+func (s *eventStream) failed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err != nil
+}
+
+func (s *eventStream) keepAlive(interval time.Duration) {
+	defer s.wg.Done()
+	var ticker *time.Ticker
+	var tick <-chan time.Time
+	start := func(d time.Duration) {
+		if ticker != nil {
+			ticker.Stop()
+			ticker, tick = nil, nil
+		}
+		if d > 0 {
+			ticker = time.NewTicker(d)
+			tick = ticker.C
+		}
+	}
+	start(interval)
+	defer start(0)
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case d := <-s.heartbeat:
+			start(d)
+		case <-tick:
+			if s.Comment("heartbeat") != nil {
+				return
+			}
+		}
+	}
+}
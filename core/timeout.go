@@ -0,0 +1,46 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// timeoutParentKey giữ context trước Timeout ngoài cùng, để Timeout của route
+// (ví dụ @Timeout) thay thế được timeout global thay vì chỉ rút ngắn nó mà vẫn
+// bị huỷ cùng request
+const timeoutParentKey = "core.timeoutParent"
+
+// Timeout bounds the rest of the chain to d: Context() is cancelled when the
+// deadline passes, and if the handler returns without writing a response a
+// 503 problem is rendered. The handler is not interrupted, so it must watch
+// Context() (database calls, outgoing requests, ...) to stop early.
+//
+// A Timeout closer to the route replaces an outer one, so a global
+// server.Use(core.Timeout(5*time.Second)) can be raised or lowered per route,
+// e.g. with the @Timeout annotation.
+func Timeout(d time.Duration) Handler {
+	return func(c Context) {
+		current := c.Context()
+		var ctx context.Context
+		var cancel context.CancelFunc
+		if root, ok := c.Get(timeoutParentKey).(context.Context); ok {
+			// Bỏ deadline của Timeout ngoài nhưng giữ các giá trị middleware đã
+			// thêm vào current, và vẫn huỷ khi request gốc bị huỷ
+			ctx, cancel = context.WithTimeout(context.WithoutCancel(current), d)
+			stop := context.AfterFunc(root, cancel)
+			defer stop()
+		} else {
+			c.Set(timeoutParentKey, current)
+			ctx, cancel = context.WithTimeout(current, d)
+		}
+		defer cancel()
+		c.SetContext(ctx)
+		c.Next()
+
+		if errors.Is(c.Context().Err(), context.DeadlineExceeded) && !c.Written() {
+			c.Error(context.DeadlineExceeded)
+		}
+		c.SetContext(current)
+	}
+}
@@ -0,0 +1,249 @@
+package core
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultTLSReloadInterval is how often the certificate files are checked
+// for changes when TLSConfig.ReloadInterval is not set.
+const DefaultTLSReloadInterval = 10 * time.Second
+
+// Chế độ xác thực client certificate (mTLS)
+const (
+	ClientAuthNone             = "none"
+	ClientAuthRequest          = "request"
+	ClientAuthRequire          = "require"
+	ClientAuthVerifyIfGiven    = "verify-if-given"
+	ClientAuthRequireAndVerify = "require-and-verify"
+)
+
+var clientAuthTypes = map[string]tls.ClientAuthType{
+	ClientAuthNone:             tls.NoClientCert,
+	ClientAuthRequest:          tls.RequestClientCert,
+	ClientAuthRequire:          tls.RequireAnyClientCert,
+	ClientAuthVerifyIfGiven:    tls.VerifyClientCertIfGiven,
+	ClientAuthRequireAndVerify: tls.RequireAndVerifyClientCert,
+}
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// TLSConfig configures HTTPS. TLS is enabled when CertFile is set.
+type TLSConfig struct {
+	CertFile string `mapstructure:"cert-file" yaml:"cert-file"`
+	KeyFile  string `mapstructure:"key-file" yaml:"key-file"`
+	// MinVersion is 1.0, 1.1, 1.2 or 1.3; default 1.2.
+	MinVersion string `mapstructure:"min-version" yaml:"min-version"`
+	// CipherSuites are names from tls.CipherSuites(), e.g.
+	// TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256; empty uses the Go defaults.
+	// TLS 1.3 suites are not configurable.
+	CipherSuites []string `mapstructure:"cipher-suites" yaml:"cipher-suites"`
+	// ClientCAFile enables mutual TLS with the CAs in this PEM file.
+	ClientCAFile string `mapstructure:"client-ca-file" yaml:"client-ca-file"`
+	// ClientAuth is none, request, require, verify-if-given or
+	// require-and-verify (default when ClientCAFile is set).
+	ClientAuth string `mapstructure:"client-auth" yaml:"client-auth"`
+	// ReloadInterval is how often the files are checked for changes; 0 means
+	// DefaultTLSReloadInterval.
+	ReloadInterval time.Duration `mapstructure:"reload-interval" yaml:"reload-interval"`
+}
+
+func (c TLSConfig) Enabled() bool {
+	return c.CertFile != ""
+}
+
+// NewTLSConfig builds the server *tls.Config of cfg. The certificate and the
+// client CA are reloaded from disk when their files change, so rotated
+// certificates are served without a restart. NextProtos advertises h2 and
+// http/1.1; adapters that only speak HTTP/1.1 override it.
+func NewTLSConfig(cfg TLSConfig) (*tls.Config, error) {
+	minVersion := uint16(tls.VersionTLS12)
+	if cfg.MinVersion != "" {
+		v, ok := tlsVersions[cfg.MinVersion]
+		if !ok {
+			return nil, fmt.Errorf("tls: unknown min-version %q", cfg.MinVersion)
+		}
+		minVersion = v
+	}
+
+	suites, err := cipherSuites(cfg.CipherSuites)
+	if err != nil {
+		return nil, err
+	}
+
+	clientAuth := cfg.ClientAuth
+	if clientAuth == "" {
+		clientAuth = ClientAuthNone
+		if cfg.ClientCAFile != "" {
+			clientAuth = ClientAuthRequireAndVerify
+		}
+	}
+	authType, ok := clientAuthTypes[clientAuth]
+	if !ok {
+		return nil, fmt.Errorf("tls: unknown client-auth %q", cfg.ClientAuth)
+	}
+	if cfg.ClientCAFile == "" && (authType == tls.VerifyClientCertIfGiven || authType == tls.RequireAndVerifyClientCert) {
+		return nil, fmt.Errorf("tls: client-auth %q needs client-ca-file", clientAuth)
+	}
+
+	reloader := &certReloader{cfg: cfg, interval: cfg.ReloadInterval}
+	if reloader.interval <= 0 {
+		reloader.interval = DefaultTLSReloadInterval
+	}
+	if err := reloader.load(); err != nil {
+		return nil, err
+	}
+
+	base := &tls.Config{
+		MinVersion:     minVersion,
+		CipherSuites:   suites,
+		ClientAuth:     authType,
+		NextProtos:     []string{"h2", "http/1.1"},
+		GetCertificate: reloader.getCertificate,
+	}
+	if cfg.ClientCAFile != "" {
+		// ClientCAs được đọc theo từng handshake để CA mới có hiệu lực ngay
+		base.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			conf := base.Clone()
+			conf.GetConfigForClient = nil
+			conf.ClientCAs = reloader.clientCAs()
+			return conf, nil
+		}
+	}
+	return base, nil
+}
+
+func cipherSuites(names []string) ([]uint16, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	known := make(map[string]uint16)
+	for _, s := range tls.CipherSuites() {
+		known[s.Name] = s.ID
+	}
+	ids := make([]uint16, 0, len(names))
+	for _, name := range names {
+		id, ok := known[strings.TrimSpace(name)]
+		if !ok {
+			return nil, fmt.Errorf("tls: unknown or insecure cipher suite %q", name)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// certReloader giữ certificate và client CA hiện tại, đọc lại khi file đổi
+type certReloader struct {
+	cfg      TLSConfig
+	interval time.Duration
+
+	mu      sync.RWMutex
+	checked time.Time
+	modTime map[string]time.Time
+	cert    *tls.Certificate
+	pool    *x509.CertPool
+}
+
+func (r *certReloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.maybeReload()
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+func (r *certReloader) clientCAs() *x509.CertPool {
+	r.maybeReload()
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.pool
+}
+
+// maybeReload kiểm tra file tối đa một lần mỗi interval; lỗi thì giữ cert cũ
+func (r *certReloader) maybeReload() {
+	r.mu.RLock()
+	due := time.Since(r.checked) >= r.interval
+	r.mu.RUnlock()
+	if !due || !r.changed() {
+		return
+	}
+	if err := r.load(); err != nil {
+		log.Printf("tls: keeping the current certificate, reload failed: %v", err)
+	}
+}
+
+func (r *certReloader) files() []string {
+	files := []string{r.cfg.CertFile, r.cfg.KeyFile}
+	if r.cfg.ClientCAFile != "" {
+		files = append(files, r.cfg.ClientCAFile)
+	}
+	return files
+}
+
+func (r *certReloader) changed() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checked = time.Now()
+	for _, file := range r.files() {
+		info, err := os.Stat(file)
+		if err != nil {
+			continue
+		}
+		if !info.ModTime().Equal(r.modTime[file]) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *certReloader) load() error {
+	modTime := make(map[string]time.Time)
+	for _, file := range r.files() {
+		info, err := os.Stat(file)
+		if err != nil {
+			return fmt.Errorf("tls: %w", err)
+		}
+		modTime[file] = info.ModTime()
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.cfg.CertFile, r.cfg.KeyFile)
+	if err != nil {
+		return fmt.Errorf("tls: load key pair: %w", err)
+	}
+	var pool *x509.CertPool
+	if r.cfg.ClientCAFile != "" {
+		pem, err := os.ReadFile(r.cfg.ClientCAFile)
+		if err != nil {
+			return fmt.Errorf("tls: %w", err)
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("tls: no certificate found in %s", r.cfg.ClientCAFile)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cert, r.pool, r.modTime, r.checked = &cert, pool, modTime, time.Now()
+	return nil
+}
+
+// VerifiedClientCertificate returns the client certificate of state when it
+// was verified against the client CA, or nil. Adapters implement
+// Context.ClientCertificate with it.
+func VerifiedClientCertificate(state *tls.ConnectionState) *x509.Certificate {
+	if state == nil || len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return nil
+	}
+	return state.VerifiedChains[0][0]
+}
@@ -0,0 +1,176 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// Biến môi trường truyền từ process cũ sang process mới khi upgrade
+const (
+	// EnvUpgradeListeners is the JSON list of the specs of the inherited
+	// listeners, passed from fd 3 on.
+	EnvUpgradeListeners = "SERVER_UPGRADE_LISTENERS"
+	// EnvUpgradeReadyFD is the pipe the new process writes to once started.
+	EnvUpgradeReadyFD = "SERVER_UPGRADE_READY_FD"
+)
+
+// DefaultUpgradeTimeout bounds how long Upgrade waits for the new process.
+const DefaultUpgradeTimeout = 30 * time.Second
+
+// upgradeFirstFD là fd đầu tiên của ExtraFiles ở process con
+const upgradeFirstFD = 3
+
+// listener ghi nhớ spec đã mở để process mới nhận lại đúng socket
+type listener struct {
+	net.Listener
+	spec string
+}
+
+func (l *listener) Close() error {
+	openListeners.Delete(l)
+	return l.Listener.Close()
+}
+
+var (
+	// openListeners là các listener mở bởi Listen, được chuyển giao khi upgrade
+	openListeners sync.Map
+	inheritOnce   sync.Once
+	inheritMu     sync.Mutex
+	inherited     map[string][]net.Listener
+)
+
+func track(ln net.Listener, spec string) net.Listener {
+	l := &listener{Listener: ln, spec: spec}
+	openListeners.Store(l, struct{}{})
+	return l
+}
+
+// takeInherited returns the listener for spec passed by the previous process,
+// if any.
+func takeInherited(spec string) net.Listener {
+	inheritOnce.Do(loadInherited)
+	inheritMu.Lock()
+	defer inheritMu.Unlock()
+	ls := inherited[spec]
+	if len(ls) == 0 {
+		return nil
+	}
+	inherited[spec] = ls[1:]
+	return ls[0]
+}
+
+func loadInherited() {
+	value := os.Getenv(EnvUpgradeListeners)
+	if value == "" {
+		return
+	}
+	os.Unsetenv(EnvUpgradeListeners)
+	var specs []string
+	if err := json.Unmarshal([]byte(value), &specs); err != nil {
+		log.Printf("upgrade: ignoring %s: %v", EnvUpgradeListeners, err)
+		return
+	}
+	inherited = make(map[string][]net.Listener)
+	for i, spec := range specs {
+		file := os.NewFile(uintptr(upgradeFirstFD+i), spec)
+		ln, err := net.FileListener(file)
+		file.Close()
+		if err != nil {
+			log.Printf("upgrade: cannot inherit %s: %v", spec, err)
+			continue
+		}
+		inherited[spec] = append(inherited[spec], ln)
+	}
+}
+
+// Upgrade re-executes the current binary with the same arguments and passes
+// it the listeners opened by Listen. It returns once the new process has run
+// its start hooks, or with an error if it exits or does not start within
+// timeout; the caller then drains and exits. Listeners given to Serve
+// directly are not passed on.
+func Upgrade(timeout time.Duration) error {
+	if timeout <= 0 {
+		timeout = DefaultUpgradeTimeout
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("upgrade: %w", err)
+	}
+
+	var specs []string
+	var fds []uintptr
+	defer func() {
+		for _, fd := range fds {
+			closeFD(fd)
+		}
+	}()
+	openListeners.Range(func(key, _ any) bool {
+		l := key.(*listener)
+		fd, err := dupListener(l.Listener)
+		if err != nil {
+			log.Printf("upgrade: cannot pass on %s: %v", l.spec, err)
+			return true
+		}
+		// Process cũ đóng listener khi drain, không được xoá file socket của process mới
+		if ul, ok := l.Listener.(*net.UnixListener); ok {
+			ul.SetUnlinkOnClose(false)
+		}
+		specs = append(specs, l.spec)
+		fds = append(fds, fd)
+		return true
+	})
+	if len(fds) == 0 {
+		return errors.New("upgrade: no listener to pass on")
+	}
+
+	ready, readyW, err := os.Pipe()
+	if err != nil {
+		return fmt.Errorf("upgrade: %w", err)
+	}
+	defer ready.Close()
+	encoded, _ := json.Marshal(specs)
+	files := append([]uintptr{os.Stdin.Fd(), os.Stdout.Fd(), os.Stderr.Fd()}, fds...)
+	env := append(os.Environ(),
+		EnvUpgradeListeners+"="+string(encoded),
+		fmt.Sprintf("%s=%d", EnvUpgradeReadyFD, upgradeFirstFD+len(fds)),
+	)
+	process, err := startProcess(executable, os.Args[1:], env, append(files, readyW.Fd()))
+	readyW.Close()
+	if err != nil {
+		return fmt.Errorf("upgrade: %w", err)
+	}
+
+	// Process mới ghi 1 byte khi sẵn sàng; EOF nghĩa là nó đã thoát
+	_ = ready.SetReadDeadline(time.Now().Add(timeout))
+	if _, err := ready.Read(make([]byte, 1)); err != nil {
+		_ = process.Kill()
+		_, _ = process.Wait()
+		return fmt.Errorf("upgrade: new process %d did not start: %w", process.Pid, err)
+	}
+	log.Printf("upgrade: new process %d is serving, draining this one", process.Pid)
+	return process.Release()
+}
+
+// notifyUpgraded tells the previous process that this one has started, when
+// it was started by Upgrade.
+func notifyUpgraded() error {
+	value := os.Getenv(EnvUpgradeReadyFD)
+	if value == "" {
+		return nil
+	}
+	os.Unsetenv(EnvUpgradeReadyFD)
+	var fd int
+	if _, err := fmt.Sscan(value, &fd); err != nil {
+		return fmt.Errorf("upgrade: %s: %w", EnvUpgradeReadyFD, err)
+	}
+	file := os.NewFile(uintptr(fd), "upgrade-ready")
+	defer file.Close()
+	_, err := file.Write([]byte{1})
+	return err
+}
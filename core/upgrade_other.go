@@ -0,0 +1,24 @@
+//go:build !unix
+
+package core
+
+import (
+	"errors"
+	"net"
+	"os"
+	"syscall"
+)
+
+var defaultUpgradeSignals = []os.Signal{syscall.SIGHUP}
+
+var errUpgradeUnsupported = errors.New("not supported on this platform")
+
+func dupListener(net.Listener) (uintptr, error) {
+	return 0, errUpgradeUnsupported
+}
+
+func closeFD(uintptr) {}
+
+func startProcess(string, []string, []string, []uintptr) (*os.Process, error) {
+	return nil, errUpgradeUnsupported
+}
@@ -0,0 +1,54 @@
+//go:build unix
+
+package core
+
+import (
+	"errors"
+	"net"
+	"os"
+	"syscall"
+)
+
+var defaultUpgradeSignals = []os.Signal{syscall.SIGHUP, syscall.SIGUSR2}
+
+// dupListener dup fd của listener qua SyscallConn. Không dùng File(): exec gọi
+// Fd() trên file đó và chuyển socket (dùng chung với listener gốc) sang
+// blocking, làm Accept của process cũ kẹt trong syscall và Close không trả về.
+func dupListener(ln net.Listener) (uintptr, error) {
+	sc, ok := ln.(syscall.Conn)
+	if !ok {
+		return 0, errors.New("listener has no file descriptor")
+	}
+	raw, err := sc.SyscallConn()
+	if err != nil {
+		return 0, err
+	}
+	var fd int
+	var dupErr error
+	if err := raw.Control(func(s uintptr) {
+		fd, dupErr = syscall.Dup(int(s))
+	}); err != nil {
+		return 0, err
+	}
+	if dupErr != nil {
+		return 0, dupErr
+	}
+	syscall.CloseOnExec(fd)
+	return uintptr(fd), nil
+}
+
+func closeFD(fd uintptr) {
+	syscall.Close(int(fd))
+}
+
+// startProcess chạy executable với files làm fd 0, 1, 2, ... của process con
+func startProcess(executable string, args, env []string, files []uintptr) (*os.Process, error) {
+	pid, err := syscall.ForkExec(executable, append([]string{executable}, args...), &syscall.ProcAttr{
+		Env:   env,
+		Files: files,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return os.FindProcess(pid)
+}
@@ -0,0 +1,179 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// validationTags are the struct tags checked by Validate: validate, and
+// binding so structs written for gin keep working on every engine.
+var validationTags = []string{"validate", "binding"}
+
+var (
+	validatorsOnce sync.Once
+	validators     []*validator.Validate
+)
+
+// FieldError describes one failed rule. Field is the JSON path of the value,
+// e.g. items[0].name.
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+// ValidationError is returned by Context.Bind and Validate when the bound
+// value breaks its validation rules; it maps to 422.
+type ValidationError struct {
+	Errors []FieldError `json:"errors"`
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+	for _, fe := range e.Errors {
+		msgs = append(msgs, fe.Message)
+	}
+	return "validation failed: " + strings.Join(msgs, "; ")
+}
+
+func (e *ValidationError) StatusCode() int {
+	return StatusUnprocessableEntity
+}
+
+func getValidators() []*validator.Validate {
+	validatorsOnce.Do(func() {
+		for _, tag := range validationTags {
+			v := validator.New(validator.WithRequiredStructEnabled())
+			v.SetTagName(tag)
+			v.RegisterTagNameFunc(jsonFieldName)
+			validators = append(validators, v)
+		}
+	})
+	return validators
+}
+
+// RegisterValidation adds a custom rule usable in validate and binding tags.
+func RegisterValidation(tag string, fn validator.Func) error {
+	for _, v := range getValidators() {
+		if err := v.RegisterValidation(tag, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Validate checks obj against its validate/binding tags. Values that are not
+// structs (maps, slices...) are not validated.
+func Validate(obj interface{}) error {
+	typ := reflect.TypeOf(obj)
+	for typ != nil && typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ == nil || typ.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var fieldErrors []FieldError
+	for _, v := range getValidators() {
+		err := v.Struct(obj)
+		var errs validator.ValidationErrors
+		if errors.As(err, &errs) {
+			for _, fe := range errs {
+				fieldErrors = append(fieldErrors, toFieldError(fe))
+			}
+		} else if err != nil {
+			return err
+		}
+	}
+	if len(fieldErrors) > 0 {
+		return &ValidationError{Errors: fieldErrors}
+	}
+	return nil
+}
+
+// BindAndValidate binds and validates obj. On failure it passes the error to
+// Context.Error (400 when the body can't be decoded, 422 when rules fail)
+// and returns false.
+//
+// Example
+//
+//	var req CreateUserReq
+//	if !core.BindAndValidate(c, &req) {
+//		return
+//	}
+func BindAndValidate(c Context, obj interface{}) bool {
+	err := c.Bind(obj)
+	if err == nil {
+		return true
+	}
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		err = &BindError{Err: err}
+	}
+	c.Error(err)
+	return false
+}
+
+func toFieldError(fe validator.FieldError) FieldError {
+	// Bỏ tên struct gốc: CreateUserReq.items[0].name -> items[0].name
+	field := fe.Namespace()
+	if _, rest, ok := strings.Cut(field, "."); ok {
+		field = rest
+	}
+	return FieldError{
+		Field:   field,
+		Rule:    fe.Tag(),
+		Param:   fe.Param(),
+		Message: fieldMessage(field, fe),
+	}
+}
+
+func fieldMessage(field string, fe validator.FieldError) string {
+	unit := ""
+	switch fe.Kind() {
+	case reflect.String:
+		unit = " characters"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		unit = " items"
+	}
+	switch fe.Tag() {
+	case "required", "required_if", "required_unless", "required_with", "required_without":
+		return fmt.Sprintf("%s is required", field)
+	case "min", "gte":
+		return fmt.Sprintf("%s must be at least %s%s", field, fe.Param(), unit)
+	case "max", "lte":
+		return fmt.Sprintf("%s must be at most %s%s", field, fe.Param(), unit)
+	case "gt":
+		return fmt.Sprintf("%s must be greater than %s%s", field, fe.Param(), unit)
+	case "lt":
+		return fmt.Sprintf("%s must be less than %s%s", field, fe.Param(), unit)
+	case "len":
+		return fmt.Sprintf("%s must be exactly %s%s", field, fe.Param(), unit)
+	case "oneof":
+		return fmt.Sprintf("%s must be one of [%s]", field, fe.Param())
+	case "email", "url", "uri", "uuid", "ip", "ipv4", "ipv6", "datetime":
+		return fmt.Sprintf("%s must be a valid %s", field, fe.Tag())
+	default:
+		return fmt.Sprintf("%s failed on the '%s' rule", field, fe.Tag())
+	}
+}
+
+// jsonFieldName reports fields by their JSON name, then by their path, query
+// or header tag for fields not in the body, falling back to the Go name.
+func jsonFieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name != "" && name != "-" {
+		return name
+	}
+	for _, src := range tagSources {
+		if tagName := field.Tag.Get(src.tag); tagName != "" && tagName != "-" {
+			return tagName
+		}
+	}
+	return field.Name
+}
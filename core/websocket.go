@@ -0,0 +1,349 @@
+package core
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MethodWS is the method of WebSocket routes (@Api WS /path). They are
+// served as GET routes that upgrade the connection.
+const MethodWS = "WS"
+
+const (
+	DefaultWSMaxMessageSize = 1 << 20
+	DefaultWSPingInterval   = 30 * time.Second
+	DefaultWSPongTimeout    = 10 * time.Second
+	DefaultWSWriteTimeout   = 10 * time.Second
+)
+
+// wsGUID là hằng số của RFC 6455 dùng để tính Sec-WebSocket-Accept
+const wsGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// WSHandler serves one WebSocket connection; the connection is closed when
+// it returns.
+type WSHandler func(conn WSConn)
+
+// WSOptions configures a WebSocket endpoint; zero values use the defaults.
+type WSOptions struct {
+	// Subprotocols the endpoint speaks, in order of preference. The first one
+	// the client offers in Sec-WebSocket-Protocol is chosen; without a match
+	// the connection has no subprotocol.
+	Subprotocols []string
+	// Origins allowed besides the host of the request, e.g.
+	// https://app.example.com, or "*" for any. Requests without Origin, from
+	// non-browser clients, are always allowed.
+	Origins []string
+	// CheckOrigin replaces the Origins check when set.
+	CheckOrigin func(r *http.Request) bool
+	// MaxMessageSize bounds a message, fragments included (1009 Message Too
+	// Big); 0 or less means DefaultWSMaxMessageSize. There is always a
+	// limit, since the payload of a frame is allocated from its header.
+	MaxMessageSize int64
+	// PingInterval is how often a ping is sent. The connection is closed when
+	// nothing, pong included, arrives within PingInterval + PongTimeout. 0
+	// means the defaults; a negative PingInterval turns keepalive off.
+	PingInterval time.Duration
+	PongTimeout  time.Duration
+	// WriteTimeout bounds writing one message; 0 means DefaultWSWriteTimeout.
+	WriteTimeout time.Duration
+}
+
+func (o WSOptions) withDefaults() WSOptions {
+	if o.MaxMessageSize <= 0 {
+		o.MaxMessageSize = DefaultWSMaxMessageSize
+	}
+	if o.PingInterval == 0 {
+		o.PingInterval = DefaultWSPingInterval
+	}
+	if o.PongTimeout <= 0 {
+		o.PongTimeout = DefaultWSPongTimeout
+	}
+	if o.WriteTimeout <= 0 {
+		o.WriteTimeout = DefaultWSWriteTimeout
+	}
+	return o
+}
+
+// Hijacker is implemented by the Contexts of the adapters, so WebSocket can
+// take over the connection of a request.
+type Hijacker interface {
+	// Request returns the request as an *http.Request that stays valid after
+	// the handler returns.
+	Request() (*http.Request, error)
+	// Hijack hands the connection to fn, with a reader holding the bytes the
+	// server has already read from it. Nothing is written on the connection;
+	// fiber runs fn after the handler returns.
+	Hijack(fn func(conn net.Conn, r *bufio.Reader)) error
+}
+
+// HijackHTTP implements Hijacker.Hijack for the net/http adapters. fn runs
+// before it returns, while the handler still holds the request.
+func HijackHTTP(w http.ResponseWriter, fn func(net.Conn, *bufio.Reader)) error {
+	conn, rw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		return err
+	}
+	fn(conn, rw.Reader)
+	return nil
+}
+
+// WebSocketRoute turns a MethodWS route into the GET route of its endpoint
+// and registers the hook closing its connections with onShutdown; other
+// routes are returned as is. Adapters call it from Routes.
+func WebSocketRoute(r RouteConfig, onShutdown func(hooks ...Hook)) RouteConfig {
+	if r.Method != MethodWS {
+		return r
+	}
+	handler, shutdown := NewWebSocket(r.WebSocket, r.WSOptions)
+	onShutdown(shutdown)
+	r.Method, r.Handler = MethodGet, handler
+	return r
+}
+
+// NewWebSocket returns the Handler of a WebSocket endpoint, which checks the
+// handshake and runs handler on the upgraded connection, and the Hook that
+// closes its connections with 1001 Going Away on shutdown.
+func NewWebSocket(handler WSHandler, opts ...WSOptions) (Handler, Hook) {
+	var opt WSOptions
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+	e := &wsEndpoint{handler: handler, opts: opt.withDefaults(), conns: make(map[*wsConn]struct{})}
+	return e.serve, e.shutdown
+}
+
+// wsEndpoint giữ các kết nối đang mở của một route để đóng khi shutdown
+type wsEndpoint struct {
+	handler WSHandler
+	opts    WSOptions
+	mu      sync.Mutex
+	conns   map[*wsConn]struct{}
+	closed  bool
+	wg      sync.WaitGroup
+}
+
+func (e *wsEndpoint) serve(c Context) {
+	h, ok := c.(Hijacker)
+	if !ok {
+		c.Error(errors.New("websocket: the engine cannot hijack connections"))
+		return
+	}
+	r, err := h.Request()
+	if err != nil {
+		c.Error(NewHTTPError(StatusBadRequest, "").Wrap(err))
+		return
+	}
+	protocol, err := e.handshake(r)
+	if err != nil {
+		if ToHTTPError(err).Status == StatusUpgradeRequired {
+			c.SetHeader(HeaderUpgrade, "websocket")
+			c.SetHeader(HeaderSecWebSocketVersion, "13")
+		}
+		c.Error(err)
+		return
+	}
+	e.mu.Lock()
+	closed := e.closed
+	e.mu.Unlock()
+	if closed {
+		c.Error(NewHTTPError(StatusServiceUnavailable, "server is shutting down"))
+		return
+	}
+
+	// Snapshot tham số path vì fiber chạy handler sau khi Context được trả lại
+	params := make(map[string]string)
+	for _, segment := range strings.Split(c.Path(), "/") {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			name := segment[1:]
+			params[name] = c.Param(name)
+		}
+	}
+	ctx := context.WithoutCancel(c.Context())
+	route := c.Method() + " " + c.Path()
+
+	response := "HTTP/1.1 101 Switching Protocols\r\n" +
+		HeaderUpgrade + ": websocket\r\n" +
+		HeaderConnection + ": Upgrade\r\n" +
+		HeaderSecWebSocketAccept + ": " + wsAccept(r.Header.Get(HeaderSecWebSocketKey)) + "\r\n"
+	if protocol != "" {
+		response += HeaderSecWebSocketProtocol + ": " + protocol + "\r\n"
+	}
+	response += "\r\n"
+
+	err = h.Hijack(func(netConn net.Conn, br *bufio.Reader) {
+		// Bỏ deadline của server HTTP, kết nối tự quản lý deadline
+		_ = netConn.SetDeadline(time.Time{})
+		if _, err := netConn.Write([]byte(response)); err != nil {
+			netConn.Close()
+			return
+		}
+		conn := newWSConn(ctx, netConn, br, e.opts, protocol, r, params)
+		if !e.track(conn) {
+			conn.closeWith(WSCloseGoingAway, "server is shutting down")
+			return
+		}
+		defer e.untrack(conn)
+		e.run(conn, route)
+	})
+	if err != nil {
+		c.Error(err)
+	}
+}
+
+// handshake kiểm tra request upgrade và chọn subprotocol
+func (e *wsEndpoint) handshake(r *http.Request) (string, error) {
+	if !headerHasToken(r.Header, HeaderConnection, "upgrade") || !headerHasToken(r.Header, HeaderUpgrade, "websocket") {
+		return "", wsUpgradeRequired("not a websocket handshake")
+	}
+	if r.Header.Get(HeaderSecWebSocketVersion) != "13" {
+		return "", wsUpgradeRequired("unsupported websocket version")
+	}
+	if key, err := base64.StdEncoding.DecodeString(r.Header.Get(HeaderSecWebSocketKey)); err != nil || len(key) != 16 {
+		return "", NewHTTPError(StatusBadRequest, "invalid Sec-WebSocket-Key")
+	}
+	check := e.opts.CheckOrigin
+	if check == nil {
+		check = e.checkOrigin
+	}
+	if !check(r) {
+		return "", NewHTTPError(StatusForbidden, "origin not allowed")
+	}
+
+	var offered []string
+	for _, value := range r.Header.Values(HeaderSecWebSocketProtocol) {
+		for _, p := range strings.Split(value, ",") {
+			offered = append(offered, strings.TrimSpace(p))
+		}
+	}
+	for _, p := range e.opts.Subprotocols {
+		for _, o := range offered {
+			if p == o {
+				return p, nil
+			}
+		}
+	}
+	return "", nil
+}
+
+func (e *wsEndpoint) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get(HeaderOrigin)
+	if origin == "" {
+		return true
+	}
+	for _, allowed := range e.opts.Origins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
+
+func (e *wsEndpoint) track(conn *wsConn) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.closed {
+		return false
+	}
+	e.conns[conn] = struct{}{}
+	e.wg.Add(1)
+	return true
+}
+
+func (e *wsEndpoint) untrack(conn *wsConn) {
+	e.mu.Lock()
+	delete(e.conns, conn)
+	e.mu.Unlock()
+	e.wg.Done()
+}
+
+// run chạy handler; panic được log và đóng kết nối với 1011
+func (e *wsEndpoint) run(conn *wsConn, route string) {
+	defer func() {
+		if v := recover(); v != nil {
+			log.Printf("%s: websocket handler panic: %v", route, v)
+			conn.closeWith(WSCloseInternalError, "")
+			return
+		}
+		_ = conn.Close(WSCloseNormal, "")
+		conn.netConn.Close()
+	}()
+	go conn.keepAlive()
+	e.handler(conn)
+}
+
+// shutdown gửi 1001 Going Away cho mọi kết nối, chờ handler trả về tới hết
+// ctx rồi cắt các kết nối còn lại
+func (e *wsEndpoint) shutdown(ctx context.Context) error {
+	e.mu.Lock()
+	e.closed = true
+	conns := make([]*wsConn, 0, len(e.conns))
+	for conn := range e.conns {
+		conns = append(conns, conn)
+	}
+	e.mu.Unlock()
+	for _, conn := range conns {
+		conn.goAway()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		e.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		for _, conn := range conns {
+			conn.netConn.Close()
+		}
+		return ctx.Err()
+	}
+}
+
+func wsUpgradeRequired(detail string) error {
+	return NewHTTPError(StatusUpgradeRequired, detail)
+}
+
+func wsAccept(key string) string {
+	sum := sha1.Sum([]byte(key + wsGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// headerHasToken báo header (danh sách phân cách bởi dấu phẩy) có token, không phân biệt hoa thường
+func headerHasToken(h http.Header, name, token string) bool {
+	for _, value := range h.Values(name) {
+		for _, t := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// WSCloseError is returned by WSConn reads once the connection is closed,
+// with the code and reason of the close frame.
+type WSCloseError struct {
+	Code   int
+	Reason string
+}
+
+func (e *WSCloseError) Error() string {
+	if e.Reason == "" {
+		return fmt.Sprintf("websocket: closed with %d", e.Code)
+	}
+	return fmt.Sprintf("websocket: closed with %d %s", e.Code, e.Reason)
+}
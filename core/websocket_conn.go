@@ -0,0 +1,425 @@
+package core
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+)
+
+// WSMessageType is the type of a WebSocket data message.
+type WSMessageType int
+
+const (
+	WSText   WSMessageType = 1
+	WSBinary WSMessageType = 2
+)
+
+// Close codes of RFC 6455, 7.4.1.
+const (
+	WSCloseNormal          = 1000
+	WSCloseGoingAway       = 1001
+	WSCloseProtocolError   = 1002
+	WSCloseUnsupportedData = 1003
+	WSCloseNoStatus        = 1005
+	WSCloseAbnormal        = 1006
+	WSCloseInvalidPayload  = 1007
+	WSClosePolicyViolation = 1008
+	WSCloseTooBig          = 1009
+	WSCloseInternalError   = 1011
+)
+
+// ErrWSClosed is returned by writes after the close frame has been sent.
+var ErrWSClosed = errors.New("websocket: connection closed")
+
+const (
+	wsOpContinuation = 0x0
+	wsOpText         = 0x1
+	wsOpBinary       = 0x2
+	wsOpClose        = 0x8
+	wsOpPing         = 0x9
+	wsOpPong         = 0xA
+)
+
+// WSConn is an upgraded WebSocket connection. Pings are answered and pongs
+// handled while reading, so keep one goroutine reading; writes are safe for
+// concurrent use.
+type WSConn interface {
+	// ReadMessage returns the next text or binary message. Once the
+	// connection is closed it returns a *WSCloseError.
+	ReadMessage() (WSMessageType, []byte, error)
+	// ReadJSON decodes the next message as JSON into v.
+	ReadJSON(v interface{}) error
+	WriteMessage(typ WSMessageType, data []byte) error
+	WriteText(text string) error
+	WriteBinary(data []byte) error
+	// WriteJSON sends v as a JSON text message.
+	WriteJSON(v interface{}) error
+	// Close sends a close frame and waits briefly for the client's one.
+	Close(code int, reason string) error
+	// Subprotocol is the subprotocol chosen in the handshake, or "".
+	Subprotocol() string
+	// Param, Query and Header read the upgrade request.
+	Param(name string) string
+	Query(name string) string
+	Header(name string) string
+	RemoteAddr() string
+	// Context keeps the values of the request context and is done once the
+	// connection is closed.
+	Context() context.Context
+}
+
+type wsConn struct {
+	netConn  net.Conn
+	br       *bufio.Reader
+	opts     WSOptions
+	protocol string
+	request  *http.Request
+	params   map[string]string
+	ctx      context.Context
+	cancel   context.CancelFunc
+
+	writeMu   sync.Mutex
+	closeSent bool
+
+	// reading đảm bảo chỉ một goroutine đọc, kể cả Close khi chờ close frame
+	reading atomic.Bool
+	readErr error
+}
+
+func newWSConn(ctx context.Context, netConn net.Conn, br *bufio.Reader, opts WSOptions, protocol string, r *http.Request, params map[string]string) *wsConn {
+	ctx, cancel := context.WithCancel(ctx)
+	return &wsConn{
+		netConn:  netConn,
+		br:       br,
+		opts:     opts,
+		protocol: protocol,
+		request:  r,
+		params:   params,
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+func (c *wsConn) ReadMessage() (WSMessageType, []byte, error) {
+	if !c.reading.CompareAndSwap(false, true) {
+		return 0, nil, errors.New("websocket: concurrent reads")
+	}
+	defer c.reading.Store(false)
+	if c.readErr != nil {
+		return 0, nil, c.readErr
+	}
+	typ, data, err := c.readMessage()
+	if err != nil {
+		c.readErr = err
+		c.cancel()
+	}
+	return typ, data, err
+}
+
+func (c *wsConn) readMessage() (WSMessageType, []byte, error) {
+	var typ WSMessageType
+	var message []byte
+	for {
+		// Mọi frame, kể cả pong, gia hạn deadline đọc
+		if c.opts.PingInterval > 0 {
+			_ = c.netConn.SetReadDeadline(time.Now().Add(c.opts.PingInterval + c.opts.PongTimeout))
+		}
+		fin, op, payload, err := c.readFrame(int64(len(message)))
+		if err != nil {
+			return 0, nil, err
+		}
+		switch op {
+		case wsOpPing:
+			if err := c.writeFrame(wsOpPong, payload); err != nil && !errors.Is(err, ErrWSClosed) {
+				return 0, nil, err
+			}
+			continue
+		case wsOpPong:
+			continue
+		case wsOpClose:
+			return 0, nil, c.closeReceived(payload)
+		case wsOpContinuation:
+			if typ == 0 {
+				return 0, nil, c.closeWith(WSCloseProtocolError, "unexpected continuation frame")
+			}
+		default:
+			if typ != 0 {
+				return 0, nil, c.closeWith(WSCloseProtocolError, "expected continuation frame")
+			}
+			typ = WSMessageType(op)
+		}
+		message = append(message, payload...)
+		if !fin {
+			continue
+		}
+		if typ == WSText && !utf8.Valid(message) {
+			return 0, nil, c.closeWith(WSCloseInvalidPayload, "invalid UTF-8")
+		}
+		if message == nil {
+			message = []byte{}
+		}
+		return typ, message, nil
+	}
+}
+
+// readFrame đọc một frame của client; size là số byte của message đã đọc,
+// để chặn message quá MaxMessageSize trước khi đọc payload
+func (c *wsConn) readFrame(size int64) (fin bool, op byte, payload []byte, err error) {
+	var header [2]byte
+	if _, err := io.ReadFull(c.br, header[:]); err != nil {
+		return false, 0, nil, err
+	}
+	fin = header[0]&0x80 != 0
+	op = header[0] & 0x0f
+	masked := header[1]&0x80 != 0
+	length := int64(header[1] & 0x7f)
+
+	switch {
+	case header[0]&0x70 != 0:
+		return false, 0, nil, c.closeWith(WSCloseProtocolError, "reserved bits set")
+	case !masked:
+		return false, 0, nil, c.closeWith(WSCloseProtocolError, "client frames must be masked")
+	case op >= wsOpClose && op <= wsOpPong:
+		if !fin || length > 125 {
+			return false, 0, nil, c.closeWith(WSCloseProtocolError, "invalid control frame")
+		}
+	case op > wsOpBinary:
+		return false, 0, nil, c.closeWith(WSCloseProtocolError, fmt.Sprintf("unknown opcode %d", op))
+	}
+
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = int64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		n := binary.BigEndian.Uint64(ext[:])
+		if n > 1<<63-1 {
+			return false, 0, nil, c.closeWith(WSCloseProtocolError, "invalid payload length")
+		}
+		length = int64(n)
+	}
+	// So sánh không cộng để length gần 2^63 không tràn số
+	if op < wsOpClose && length > c.opts.MaxMessageSize-size {
+		return false, 0, nil, c.closeWith(WSCloseTooBig, "message too big")
+	}
+
+	var mask [4]byte
+	if _, err := io.ReadFull(c.br, mask[:]); err != nil {
+		return false, 0, nil, err
+	}
+	payload = make([]byte, length)
+	if _, err := io.ReadFull(c.br, payload); err != nil {
+		return false, 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return fin, op, payload, nil
+}
+
+// closeReceived trả lời close frame của client bằng cùng mã
+func (c *wsConn) closeReceived(payload []byte) error {
+	code, reason := WSCloseNoStatus, ""
+	switch {
+	case len(payload) == 1:
+		return c.closeWith(WSCloseProtocolError, "invalid close frame")
+	case len(payload) >= 2:
+		code = int(binary.BigEndian.Uint16(payload))
+		reason = string(payload[2:])
+		if !validCloseCode(code) || !utf8.ValidString(reason) {
+			return c.closeWith(WSCloseProtocolError, "invalid close frame")
+		}
+	}
+	reply := code
+	if reply == WSCloseNoStatus {
+		reply = WSCloseNormal
+	}
+	_ = c.sendClose(reply, "")
+	return &WSCloseError{Code: code, Reason: reason}
+}
+
+func validCloseCode(code int) bool {
+	switch {
+	case code >= 1000 && code <= 1003, code >= 1007 && code <= 1011:
+		return true
+	case code >= 3000 && code <= 4999:
+		return true
+	}
+	return false
+}
+
+func (c *wsConn) ReadJSON(v interface{}) error {
+	_, data, err := c.ReadMessage()
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func (c *wsConn) WriteMessage(typ WSMessageType, data []byte) error {
+	if typ != WSText && typ != WSBinary {
+		return fmt.Errorf("websocket: invalid message type %d", typ)
+	}
+	return c.writeFrame(byte(typ), data)
+}
+
+func (c *wsConn) WriteText(text string) error {
+	return c.writeFrame(wsOpText, []byte(text))
+}
+
+func (c *wsConn) WriteBinary(data []byte) error {
+	return c.writeFrame(wsOpBinary, data)
+}
+
+func (c *wsConn) WriteJSON(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.writeFrame(wsOpText, data)
+}
+
+// writeFrame ghi một frame không mask (server không mask) với WriteTimeout
+func (c *wsConn) writeFrame(op byte, data []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.closeSent {
+		return ErrWSClosed
+	}
+	if op == wsOpClose {
+		c.closeSent = true
+	}
+
+	header := make([]byte, 2, 10)
+	header[0] = 0x80 | op
+	switch n := len(data); {
+	case n <= 125:
+		header[1] = byte(n)
+	case n <= 0xffff:
+		header[1] = 126
+		header = binary.BigEndian.AppendUint16(header, uint16(n))
+	default:
+		header[1] = 127
+		header = binary.BigEndian.AppendUint64(header, uint64(n))
+	}
+	_ = c.netConn.SetWriteDeadline(time.Now().Add(c.opts.WriteTimeout))
+	buffers := net.Buffers{header, data}
+	if _, err := buffers.WriteTo(c.netConn); err != nil {
+		c.cancel()
+		return err
+	}
+	return nil
+}
+
+// sendClose gửi close frame một lần; lần sau trả ErrWSClosed
+func (c *wsConn) sendClose(code int, reason string) error {
+	if len(reason) > 123 {
+		reason = reason[:123]
+	}
+	payload := binary.BigEndian.AppendUint16(nil, uint16(code))
+	return c.writeFrame(wsOpClose, append(payload, reason...))
+}
+
+func (c *wsConn) Close(code int, reason string) error {
+	if err := c.sendClose(code, reason); err != nil {
+		if errors.Is(err, ErrWSClosed) {
+			return nil
+		}
+		return err
+	}
+	defer c.cancel()
+	// Chờ close frame của client, trừ khi đã có goroutine khác đang đọc
+	if !c.reading.CompareAndSwap(false, true) {
+		return nil
+	}
+	defer c.reading.Store(false)
+	if c.readErr != nil {
+		return nil
+	}
+	_ = c.netConn.SetReadDeadline(time.Now().Add(time.Second))
+	for {
+		_, op, payload, err := c.readFrame(0)
+		if err != nil {
+			c.readErr = err
+			return nil
+		}
+		if op == wsOpClose {
+			c.readErr = c.closeReceived(payload)
+			return nil
+		}
+	}
+}
+
+// closeWith đóng kết nối ngay với mã lỗi, dùng cho lỗi protocol và panic
+func (c *wsConn) closeWith(code int, reason string) error {
+	_ = c.sendClose(code, reason)
+	c.cancel()
+	c.netConn.Close()
+	return &WSCloseError{Code: code, Reason: reason}
+}
+
+// goAway báo client server đang tắt; handler thấy Context() done và lần
+// đọc tiếp theo nhận close frame trả lời
+func (c *wsConn) goAway() {
+	_ = c.sendClose(WSCloseGoingAway, "server is shutting down")
+	c.cancel()
+}
+
+func (c *wsConn) keepAlive() {
+	if c.opts.PingInterval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(c.opts.PingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-ticker.C:
+			if c.writeFrame(wsOpPing, nil) != nil {
+				return
+			}
+		}
+	}
+}
+
+func (c *wsConn) Subprotocol() string {
+	return c.protocol
+}
+
+func (c *wsConn) Param(name string) string {
+	return c.params[name]
+}
+
+func (c *wsConn) Query(name string) string {
+	return c.request.URL.Query().Get(name)
+}
+
+func (c *wsConn) Header(name string) string {
+	return c.request.Header.Get(name)
+}
+
+func (c *wsConn) RemoteAddr() string {
+	return c.netConn.RemoteAddr().String()
+}
+
+func (c *wsConn) Context() context.Context {
+	return c.ctx
+}
@@ -1,19 +1,35 @@
 package echo
 
 import (
+	"bufio"
 	"context"
+	"crypto/x509"
 	"github.com/kimxuanhong/go-server/core"
 	"github.com/labstack/echo/v4"
+	"io"
+	"mime/multipart"
+	"net"
+	"net/http"
 )
 
+// abortKey marks the echo.Context as aborted so the rest of the chain is skipped.
+const abortKey = "abort"
+
 type echoContext struct {
-	ctx echo.Context
+	ctx    echo.Context
+	next   echo.HandlerFunc
+	nexted bool
+	err    error
 }
 
 func (e *echoContext) Context() context.Context {
 	return e.ctx.Request().Context()
 }
 
+func (e *echoContext) SetContext(ctx context.Context) {
+	e.ctx.SetRequest(e.ctx.Request().WithContext(ctx))
+}
+
 func (e *echoContext) Param(name string) string {
 	return e.ctx.Param(name)
 }
@@ -26,8 +42,41 @@ func (e *echoContext) Header(name string) string {
 	return e.ctx.Request().Header.Get(name)
 }
 
+// Bind decodes the request by Content-Type, then validates obj with core.Validate.
 func (e *echoContext) Bind(obj interface{}) error {
-	return e.ctx.Bind(obj)
+	if handled, err := core.BindBody(e, obj); handled {
+		return err
+	}
+	core.LimitRequestBody(e, e.ctx.Response(), e.ctx.Request())
+	if err := e.ctx.Bind(obj); err != nil {
+		return err
+	}
+	return core.Validate(obj)
+}
+
+func (e *echoContext) Body() io.Reader {
+	core.LimitRequestBody(e, e.ctx.Response(), e.ctx.Request())
+	return e.ctx.Request().Body
+}
+
+func (e *echoContext) BodyBytes() ([]byte, error) {
+	return io.ReadAll(e.Body())
+}
+
+func (e *echoContext) FormValue(name string) string {
+	return core.FormValue(e, name)
+}
+
+func (e *echoContext) FormFile(name string) (*multipart.FileHeader, error) {
+	return core.FormFile(e, name)
+}
+
+func (e *echoContext) MultipartForm() (*multipart.Form, error) {
+	return core.ParseMultipartForm(e)
+}
+
+func (e *echoContext) SaveUploadedFile(file *multipart.FileHeader, dst string) error {
+	return core.SaveUploadedFile(e, file, dst)
 }
 
 func (e *echoContext) JSON(code int, obj interface{}) {
@@ -35,38 +84,148 @@ func (e *echoContext) JSON(code int, obj interface{}) {
 }
 
 func (e *echoContext) Abort() {
-	e.ctx.Set("abort", true)
+	e.ctx.Set(abortKey, true)
+}
+
+func (e *echoContext) isAborted() bool {
+	return e.ctx.Get(abortKey) == true
 }
 
 func (e *echoContext) AbortWithStatusJSON(code int, obj interface{}) {
-	e.ctx.Response().WriteHeader(code)
 	_ = e.ctx.JSON(code, obj)
-	e.ctx.Set("abort", true)
+	e.Abort()
 }
 
 func (e *echoContext) String(code int, msg string) error {
 	return e.ctx.String(code, msg)
 }
 
+func (e *echoContext) XML(code int, obj interface{}) error {
+	return core.XML(e, code, obj)
+}
+
+func (e *echoContext) HTML(code int, name string, data interface{}) error {
+	return core.HTML(e, code, name, data)
+}
+
+func (e *echoContext) Blob(code int, contentType string, data []byte) error {
+	res := e.ctx.Response()
+	if contentType != "" {
+		res.Header().Set(core.HeaderContentType, contentType)
+	}
+	res.WriteHeader(code)
+	_, err := res.Write(data)
+	return err
+}
+
+func (e *echoContext) Stream(code int, contentType string, r io.Reader) error {
+	res := e.ctx.Response()
+	if contentType != "" {
+		res.Header().Set(core.HeaderContentType, contentType)
+	}
+	res.WriteHeader(code)
+	return core.CopyFlush(res, r)
+}
+
+func (e *echoContext) SSE(fn func(core.EventStream) error) error {
+	return core.SSE(e, e.ctx.Response(), fn)
+}
+
+func (e *echoContext) Request() (*http.Request, error) {
+	return e.ctx.Request(), nil
+}
+
+func (e *echoContext) Hijack(fn func(net.Conn, *bufio.Reader)) error {
+	return core.HijackHTTP(e.ctx.Response(), fn)
+}
+
+// CompressResponse puts a core.CompressWriter in front of the writer of
+// echo's Response until finish.
+func (e *echoContext) CompressResponse(z *core.Compression) func() {
+	res := e.ctx.Response()
+	orig := res.Writer
+	cw := core.NewCompressWriter(orig, z)
+	res.Writer = cw
+	return func() {
+		_ = cw.Close()
+		res.Writer = orig
+	}
+}
+
+func (e *echoContext) File(path string) error {
+	return core.ServeFile(e.ctx.Response(), e.ctx.Request(), path)
+}
+
+func (e *echoContext) Attachment(path, filename string) error {
+	return core.Attachment(e, path, filename)
+}
+
+func (e *echoContext) Redirect(code int, location string) error {
+	return core.Redirect(e, code, location)
+}
+
+func (e *echoContext) NoContent(code int) error {
+	return e.Blob(code, "", nil)
+}
+
+func (e *echoContext) Negotiate(code int, offers map[string]interface{}) error {
+	return core.Negotiate(e, code, offers)
+}
+
+func (e *echoContext) Error(err error) {
+	core.HandleError(e, err)
+}
+
+// Status sets the response status; it is written when the handler returns
+// without a body, like gin does.
 func (e *echoContext) Status(code int) core.Context {
-	e.ctx.Response().WriteHeader(code)
+	if !e.ctx.Response().Committed {
+		e.ctx.Response().Status = code
+	}
 	return e
 }
 
+func (e *echoContext) Written() bool {
+	return e.ctx.Response().Committed
+}
+
 func (e *echoContext) SetHeader(key, value string) {
 	e.ctx.Response().Header().Set(key, value)
 }
 
+func (e *echoContext) AddHeader(key, value string) {
+	e.ctx.Response().Header().Add(key, value)
+}
+
 func (e *echoContext) Method() string {
 	return e.ctx.Request().Method
 }
 
+func (e *echoContext) ClientCertificate() *x509.Certificate {
+	return core.VerifiedClientCertificate(e.ctx.Request().TLS)
+}
+
+func (e *echoContext) Protocol() string {
+	return e.ctx.Request().Proto
+}
+
+func (e *echoContext) RemoteAddr() string {
+	return e.ctx.Request().RemoteAddr
+}
+
+// Path returns the matched route pattern.
 func (e *echoContext) Path() string {
 	return e.ctx.Path()
 }
 
+// Next calls the next handler in the chain. Echo has no Next() like Gin,
+// so the middleware wrapper hands us the next echo.HandlerFunc.
 func (e *echoContext) Next() {
-	// Echo không có `Next()` như Gin; nếu bạn cần chain middleware, hãy dùng echo.MiddlewareFunc
+	if e.next == nil || e.nexted || e.isAborted() {
+		return
+	}
+	e.nexted = true
+	e.err = e.next(e.ctx)
 }
 
 func (e *echoContext) Raw() interface{} {
@@ -2,22 +2,37 @@ package echo
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"github.com/kimxuanhong/go-server/core"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
+	"html/template"
 	"log"
+	"net"
 	"net/http"
-	"time"
+	"strings"
+	"sync"
 )
 
 // Server implements core.Server for Echo.
 type Server struct {
 	*core.DynamicRouter
 	*core.ProviderRouter
-	engine     *echo.Echo
-	rootGroup  *echo.Group
-	config     *core.Config
-	httpServer *http.Server
+	*core.HealthChecks
+	*core.Lifecycle
+	engine       *echo.Echo
+	rootGroup    *echo.Group
+	config       *core.Config
+	httpServer   *http.Server
+	mountOnce    sync.Once
+	errorHandler core.ErrorHandler
+	htmlTemplate *template.Template
+	adminAuth    []core.Handler
+	admin        *Server
+	adminOnce    sync.Once
+	// corsErr là lỗi của Config.CORS, Start trả về thay vì panic trong NewServer
+	corsErr error
 }
 
 func NewServer(configs ...*core.Config) core.Server {
@@ -29,44 +44,127 @@ func NewServer(configs ...*core.Config) core.Server {
 	engine.Use(middleware.Recover())
 	engine.Pre(middleware.RemoveTrailingSlash()) // Remove trailing /
 
-	rootGroup := engine.Group(cfg.RootPath)
+	// echo nối prefix và path trực tiếp, nên bỏ dấu / ở cuối root path
+	rootGroup := engine.Group(strings.TrimRight(cfg.RootPath, "/"))
 
-	return &Server{
-		DynamicRouter:  &core.DynamicRouter{},
+	s := &Server{
+		DynamicRouter:  &core.DynamicRouter{DevMode: cfg.Mode == "debug", RateLimit: cfg.RateLimit},
 		ProviderRouter: &core.ProviderRouter{},
+		HealthChecks:   &core.HealthChecks{},
+		Lifecycle:      &core.Lifecycle{},
 		engine:         engine,
 		rootGroup:      rootGroup,
 		config:         cfg,
+		// Tạo sẵn để Shutdown gọi trước khi Start listen vẫn dừng được server
+		httpServer: &http.Server{Addr: cfg.GetAddr()},
 	}
+	engine.Pre(s.prepareRequest)
+	// Pre chạy trước router, nên preflight của mọi path được trả lời
+	if cfg.CORS.Enabled() {
+		if cors, err := core.NewCORS(cfg.CORS); err != nil {
+			log.Printf("CORS disabled: %v", err)
+			s.corsErr = err
+		} else {
+			engine.Pre(transferMiddleware(cors))
+		}
+	}
+	engine.HTTPErrorHandler = s.handleError
+	return s
 }
 
 func (s *Server) Start() error {
-	addr := s.config.GetAddr()
-	s.httpServer = &http.Server{
-		Addr:    addr,
-		Handler: s.engine,
+	listeners, err := s.config.Listen()
+	if err != nil {
+		return err
+	}
+	return s.serve(listeners)
+}
+
+// Serve serves on ln instead of the configured listeners, e.g. a socket
+// inherited from a parent process.
+func (s *Server) Serve(ln net.Listener) error {
+	return s.serve([]net.Listener{ln})
+}
+
+func (s *Server) serve(listeners []net.Listener) error {
+	if err := s.prepare(); err != nil {
+		core.CloseListeners(listeners)
+		return err
 	}
+	listeners = core.LimitListeners(listeners, s.config.Limits.MaxConnections)
+	scheme, serve := "http", s.httpServer.Serve
+	if s.config.TLS.Enabled() {
+		// Certificate lấy từ TLSConfig.GetCertificate nên không truyền file
+		scheme, serve = "https", func(ln net.Listener) error {
+			return s.httpServer.ServeTLS(ln, "", "")
+		}
+	}
+	return core.ServeListeners(scheme, listeners, serve)
+}
 
-	// Add APIs from @Api and provider
-	s.LoadRouter()
-	s.Routes(s.DynamicRouter.Routes)
-	s.Routes(s.ProviderRouter.Routes)
+// prepare runs the start hooks, mounts the routes, applies TLS and the
+// protocols and starts the admin listener.
+func (s *Server) prepare() error {
+	if s.corsErr != nil {
+		return s.corsErr
+	}
+	protocols := s.config.GetProtocols()
+	if err := core.CheckProtocols("echo", protocols, core.NetHTTPProtocols...); err != nil {
+		return err
+	}
+	if err := s.RunStartHooks(context.Background()); err != nil {
+		return err
+	}
+	s.httpServer.Handler = s.Handler()
+	core.ApplyLimits(s.httpServer, s.config)
 
 	// Debug: Print registered routes
 	for _, r := range s.engine.Routes() {
 		log.Printf("Route: %s %s -> %s", r.Method, r.Path, r.Name)
 	}
 
-	log.Printf("Server is running at %s", addr)
-	return s.httpServer.ListenAndServe()
+	if s.config.TLS.Enabled() {
+		tlsConfig, err := core.NewTLSConfig(s.config.TLS)
+		if err != nil {
+			return err
+		}
+		s.httpServer.TLSConfig = tlsConfig
+	}
+	if err := core.ConfigureProtocols(s.httpServer, protocols); err != nil {
+		return err
+	}
+
+	if s.config.Admin.Enabled() {
+		s.AdminHandler()
+		go func() {
+			if err := s.admin.Start(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Printf("Admin server error: %v", err)
+			}
+		}()
+	}
+	return nil
+}
+
+// Handler loads the registered routes once and returns the echo engine.
+func (s *Server) Handler() http.Handler {
+	s.mountOnce.Do(func() {
+		// Add APIs from @Api and provider
+		s.LoadRouter()
+		s.Routes(s.DynamicRouter.Routes)
+		s.Routes(s.ProviderRouter.Routes)
+	})
+	return s.engine
 }
 
+// Shutdown drains the in-flight requests within ctx, stops the admin
+// listener and runs the OnShutdown hooks.
 func (s *Server) Shutdown(ctx context.Context) error {
 	log.Println("Shutting down server...")
-	if s.httpServer == nil {
-		return nil
+	err := s.httpServer.Shutdown(ctx)
+	if s.admin != nil {
+		err = errors.Join(err, s.admin.Shutdown(ctx))
 	}
-	return s.httpServer.Shutdown(ctx)
+	return errors.Join(err, s.RunShutdownHooks(ctx))
 }
 
 func (s *Server) Use(middleware ...core.Handler) {
@@ -84,44 +182,145 @@ func (s *Server) AddGroup(relativePath string, register func(rg core.RouterGroup
 }
 
 func (s *Server) Add(method, relativePath string, handler core.Handler, middleware ...core.Handler) {
-	handlers := make([]echo.MiddlewareFunc, 0, len(middleware))
-	for _, m := range middleware {
-		handlers = append(handlers, transferMiddleware(m))
-	}
-	s.rootGroup.Add(method, relativePath, transfer(handler))
+	s.rootGroup.Add(method, relativePath, transfer(handler), middlewares(middleware)...)
 }
 
 func (s *Server) Routes(routes []core.RouteConfig) {
 	for _, r := range routes {
+		r = core.WebSocketRoute(r, s.OnShutdown)
 		s.Add(r.Method, r.Path, r.Handler, r.Middleware...)
 	}
 }
 
+func (s *Server) WebSocket(relativePath string, handler core.WSHandler, opts ...core.WSOptions) {
+	route := core.RouteConfig{Method: core.MethodWS, Path: relativePath, WebSocket: handler}
+	if len(opts) > 0 {
+		route.WSOptions = opts[0]
+	}
+	s.Routes([]core.RouteConfig{route})
+}
+
 func (s *Server) Static(relativePath, root string) {
 	s.engine.Static(relativePath, root)
 }
 
 func (s *Server) HealthCheck() {
-	s.engine.GET("/ping", func(c echo.Context) error {
-		return c.JSON(http.StatusOK, map[string]string{"message": "pong"})
-	})
+	if s.config.Admin.DisablePublic {
+		return
+	}
+	admin := s.adminEndpoints()
+	s.rootRoutes(admin.ProbeRoutes())
+	if !s.config.Admin.Enabled() {
+		s.rootRoutes(admin.PublicControlRoutes())
+	}
+}
 
-	s.engine.GET("/liveness", func(c echo.Context) error {
-		return c.JSON(core.StatusOK, map[string]string{"status": "alive"})
-	})
+// UseAdmin adds auth middleware to the admin control routes. Call it before
+// HealthCheck and Start.
+func (s *Server) UseAdmin(middleware ...core.Handler) {
+	s.adminAuth = append(s.adminAuth, middleware...)
+}
 
-	s.engine.GET("/readiness", func(c echo.Context) error {
-		// Kiểm tra DB, Redis, etc.
-		return c.JSON(core.StatusOK, map[string]string{"status": "ready"})
+// AdminHandler builds the admin listener once, as a separate echo instance
+// serving the probes and control routes.
+func (s *Server) AdminHandler() http.Handler {
+	if !s.config.Admin.Enabled() {
+		return nil
+	}
+	s.adminOnce.Do(func() {
+		s.admin = NewServer(&core.Config{
+			Host: s.config.Admin.Host,
+			Port: s.config.Admin.Port,
+			Mode: s.config.Mode,
+		}).(*Server)
+		s.admin.errorHandler = s.errorHandler
+		admin := s.adminEndpoints()
+		s.admin.rootRoutes(admin.ProbeRoutes())
+		s.admin.rootRoutes(admin.ControlRoutes())
 	})
+	return s.admin.Handler()
+}
 
-	s.engine.POST("/terminate", func(c echo.Context) error {
-		go func() {
-			time.Sleep(1 * time.Second)
-			_ = s.Shutdown(context.Background())
+func (s *Server) adminEndpoints() *core.AdminEndpoints {
+	return &core.AdminEndpoints{
+		Health:      s.HealthChecks,
+		Routes:      s.routeInfos,
+		Terminate:   func() { _ = s.Shutdown(context.Background()) },
+		Auth:        core.AdminAuth(s.config.Admin, s.adminAuth...),
+		Credentials: s.config.Admin.Token != "" || len(s.adminAuth) > 0,
+	}
+}
+
+// rootRoutes registers routes on the echo instance, outside the root path.
+func (s *Server) rootRoutes(routes []core.RouteConfig) {
+	for _, r := range routes {
+		s.engine.Add(r.Method, r.Path, transfer(r.Handler), middlewares(r.Middleware)...)
+	}
+}
+
+func (s *Server) routeInfos() []core.RouteInfo {
+	var routes []core.RouteInfo
+	for _, r := range s.engine.Routes() {
+		routes = append(routes, core.RouteInfo{Method: r.Method, Path: r.Path})
+	}
+	return routes
+}
+
+func (s *Server) OpenAPI(info core.OpenAPIInfo) {
+	s.engine.GET(core.OpenAPIPath, transfer(s.OpenAPIHandler(info, s.config.RootPath)))
+}
+
+func (s *Server) SetErrorHandler(h core.ErrorHandler) {
+	s.errorHandler = h
+}
+
+func (s *Server) SetHTMLTemplate(t *template.Template) {
+	s.htmlTemplate = t
+}
+
+// prepareRequest stores the server's ErrorHandler, its HTML templates and the
+// global body and multipart limits, and removes the temporary files of the
+// multipart form once the request is served.
+func (s *Server) prepareRequest(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if s.errorHandler != nil {
+			c.Set(core.ErrorHandlerKey, s.errorHandler)
+		}
+		if s.htmlTemplate != nil {
+			c.Set(core.HTMLTemplateKey, s.htmlTemplate)
+		}
+		if n := s.config.Limits.MaxBodyBytes; n > 0 {
+			c.Set(core.BodyLimitKey, n)
+		}
+		c.Set(core.MultipartKey, s.config.Multipart)
+		defer func() {
+			core.RemoveMultipartForm(c.Get(core.MultipartFormKey))
 		}()
-		return c.JSON(core.StatusOK, map[string]string{"status": "terminating"})
-	})
+		// Status chỉ được ghi khi cả chuỗi đã chạy xong, để middleware sau
+		// handler (ví dụ Timeout) còn biết response chưa được ghi
+		if err := next(c); err != nil {
+			return err
+		}
+		writeStatus(c)
+		return nil
+	}
+}
+
+// handleError renders the errors echo produces itself (404/405, panics
+// caught by Recover) through Context.Error.
+func (s *Server) handleError(err error, c echo.Context) {
+	if c.Response().Committed {
+		return
+	}
+	var he *echo.HTTPError
+	if errors.As(err, &he) {
+		detail := fmt.Sprint(he.Message)
+		if detail == http.StatusText(he.Code) {
+			detail = ""
+		}
+		err = core.NewHTTPError(he.Code, detail).Wrap(err)
+	}
+	(&echoContext{ctx: c}).Error(err)
 }
 
 type RouterGroup struct {
@@ -129,10 +328,7 @@ type RouterGroup struct {
 }
 
 func (g *RouterGroup) Add(method, path string, handler core.Handler, middleware ...core.Handler) {
-	for _, m := range middleware {
-		g.group.Use(transferMiddleware(m))
-	}
-	g.group.Add(method, path, transfer(handler))
+	g.group.Add(method, path, transfer(handler), middlewares(middleware)...)
 }
 
 func transfer(h core.Handler) echo.HandlerFunc {
@@ -142,20 +338,36 @@ func transfer(h core.Handler) echo.HandlerFunc {
 	}
 }
 
+// writeStatus writes the status set by Status() when the handler returned
+// without a body, like gin does.
+func writeStatus(c echo.Context) {
+	if res := c.Response(); !res.Committed {
+		res.WriteHeader(res.Status)
+	}
+}
+
+func middlewares(middleware []core.Handler) []echo.MiddlewareFunc {
+	chain := make([]echo.MiddlewareFunc, 0, len(middleware))
+	for _, m := range middleware {
+		chain = append(chain, transferMiddleware(m))
+	}
+	return chain
+}
+
 func transferMiddleware(h core.Handler) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
 			// wrap echo.Context thành core.Context
-			ctx := &echoContext{ctx: c}
+			ctx := &echoContext{ctx: c, next: next}
 
 			// Call your handler
 			h(ctx)
 
-			// Kiểm tra nếu middleware không gọi abort, thì tiếp tục chuỗi xử lý
-			if c.Get("abort") != true {
+			// Middleware không gọi Next() và không Abort() thì tiếp tục chuỗi xử lý
+			if !ctx.nexted && !ctx.isAborted() {
 				return next(c)
 			}
-			return nil
+			return ctx.err
 		}
 	}
 }
@@ -10,6 +10,7 @@ import (
 	"github.com/kimxuanhong/go-utils/safe"
 	"log"
 	"net/http"
+	"time"
 )
 
 func main() {
@@ -88,8 +89,13 @@ func main() {
 	server.RegisterHandlersWithTags(&api.MyApiHandler{})
 	server.HealthCheck()
 
-	// Bắt đầu chạy server
-	if err := server.Start(); err != nil {
-		log.Fatalf("Error starting server: %v", err)
+	server.OnShutdown(func(ctx context.Context) error {
+		log.Println("Đóng kết nối DB, flush log, ...")
+		return nil
+	})
+
+	// Chạy server, dừng êm khi nhận SIGINT/SIGTERM
+	if err := core.Run(server, core.RunOptions{PreStopDelay: 2 * time.Second}); err != nil {
+		log.Fatalf("Error running server: %v", err)
 	}
 }
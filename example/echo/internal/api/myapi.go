@@ -1,5 +1,7 @@
 package api
 
+//go:generate go run github.com/kimxuanhong/go-server/cmd/go-server-gen
+
 import (
 	"github.com/kimxuanhong/go-server/core"
 )
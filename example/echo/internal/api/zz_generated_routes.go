@@ -0,0 +1,35 @@
+// Code generated by go-server-gen. DO NOT EDIT.
+
+package api
+
+import "github.com/kimxuanhong/go-server/core"
+
+// ApiRoutes returns the routes declared by the @Api annotations of MyApiHandler.
+func (h *MyApiHandler) ApiRoutes() []core.RouteConfig {
+	return []core.RouteConfig{
+		{
+			Method:  "GET",
+			Path:    "/print/5555",
+			Handler: core.MustHandler(h.Print555),
+			Doc: &core.ParseRoute{
+				Path:     "/print/5555",
+				Method:   "GET",
+				Name:     "Print555",
+				Receiver: "*MyApiHandler",
+				Schemas:  map[string]*core.Schema{},
+			},
+		},
+		{
+			Method:  "GET",
+			Path:    "/say-hii",
+			Handler: core.MustHandler(h.SayHi),
+			Doc: &core.ParseRoute{
+				Path:     "/say-hii",
+				Method:   "GET",
+				Name:     "SayHi",
+				Receiver: "*MyApiHandler",
+				Schemas:  map[string]*core.Schema{},
+			},
+		},
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/kimxuanhong/go-server/jwt"
 	"log"
 	"net/http"
+	"time"
 )
 
 func main() {
@@ -88,8 +89,13 @@ func main() {
 	server.Routes(funcHandler)
 	server.HealthCheck()
 
-	// Bắt đầu chạy server
-	if err := server.Start(); err != nil {
-		log.Fatalf("Error starting server: %v", err)
+	server.OnShutdown(func(ctx context.Context) error {
+		log.Println("Đóng kết nối DB, flush log, ...")
+		return nil
+	})
+
+	// Chạy server, dừng êm khi nhận SIGINT/SIGTERM
+	if err := core.Run(server, core.RunOptions{PreStopDelay: 2 * time.Second}); err != nil {
+		log.Fatalf("Error running server: %v", err)
 	}
 }
@@ -9,6 +9,7 @@ import (
 	"github.com/kimxuanhong/go-server/jwt"
 	"log"
 	"net/http"
+	"time"
 )
 
 func main() {
@@ -85,9 +86,15 @@ func main() {
 
 	server.RegisterHandlersWithTags(&api.MyApiHandler{})
 	server.HealthCheck()
+	server.OpenAPI(core.OpenAPIInfo{Title: "Example API", Version: "1.0.0"})
 
-	// Bắt đầu chạy server
-	if err := server.Start(); err != nil {
-		log.Fatalf("Error starting server: %v", err)
+	server.OnShutdown(func(ctx context.Context) error {
+		log.Println("Đóng kết nối DB, flush log, ...")
+		return nil
+	})
+
+	// Chạy server, dừng êm khi nhận SIGINT/SIGTERM; SIGHUP nâng cấp binary không downtime
+	if err := core.Run(server, core.RunOptions{PreStopDelay: 2 * time.Second, Upgrade: true}); err != nil {
+		log.Fatalf("Error running server: %v", err)
 	}
 }
@@ -1,16 +1,26 @@
 package api
 
+//go:generate go run github.com/kimxuanhong/go-server/cmd/go-server-gen
+
 import (
 	"github.com/kimxuanhong/go-server/core"
 )
 
 // MyApiHandler
 // @BaseUrl /iloveu/
+// @Tag iloveu
 type MyApiHandler struct {
 }
 
+// Message is the response of the example APIs.
+type Message struct {
+	Message string `json:"message"`
+}
+
 // SayHi API
 // @Api GET /say-hi
+// @Summary Say hi
+// @Success 200 Message
 func (h *MyApiHandler) SayHi(c core.Context) {
 	c.JSON(200, map[string]string{
 		"message": "SayHi Api",
@@ -19,6 +29,8 @@ func (h *MyApiHandler) SayHi(c core.Context) {
 
 // Print555 API
 // @Api GET /print/555
+// @Summary Print 555
+// @Success 200 Message
 func (h *MyApiHandler) Print555(c core.Context) {
 	c.JSON(200, map[string]string{
 		"message": "Print555 Api",
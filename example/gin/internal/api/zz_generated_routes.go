@@ -0,0 +1,53 @@
+// Code generated by go-server-gen. DO NOT EDIT.
+
+package api
+
+import "github.com/kimxuanhong/go-server/core"
+
+// ApiRoutes returns the routes declared by the @Api annotations of MyApiHandler.
+func (h *MyApiHandler) ApiRoutes() []core.RouteConfig {
+	return []core.RouteConfig{
+		{
+			Method:  "GET",
+			Path:    "/iloveu/print/555",
+			Handler: core.MustHandler(h.Print555),
+			Doc: &core.ParseRoute{
+				Path:     "/iloveu/print/555",
+				Method:   "GET",
+				Name:     "Print555",
+				Receiver: "*MyApiHandler",
+				Summary:  "Print 555",
+				Tags:     []string{"iloveu"},
+				Response: []core.ParseResponse{
+					{Code: "200", Type: "Message", Description: ""},
+				},
+				Schemas: map[string]*core.Schema{
+					"Message": {Type: "object", Properties: map[string]*core.Schema{
+						"message": {Type: "string"},
+					}},
+				},
+			},
+		},
+		{
+			Method:  "GET",
+			Path:    "/iloveu/say-hi",
+			Handler: core.MustHandler(h.SayHi),
+			Doc: &core.ParseRoute{
+				Path:     "/iloveu/say-hi",
+				Method:   "GET",
+				Name:     "SayHi",
+				Receiver: "*MyApiHandler",
+				Summary:  "Say hi",
+				Tags:     []string{"iloveu"},
+				Response: []core.ParseResponse{
+					{Code: "200", Type: "Message", Description: ""},
+				},
+				Schemas: map[string]*core.Schema{
+					"Message": {Type: "object", Properties: map[string]*core.Schema{
+						"message": {Type: "string"},
+					}},
+				},
+			},
+		},
+	}
+}
@@ -1,18 +1,44 @@
 package fiber
 
 import (
+	"bufio"
+	"bytes"
 	"context"
+	"crypto/x509"
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
 	"github.com/kimxuanhong/go-server/core"
+	"github.com/valyala/fasthttp"
+	"io"
 	"log"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"strings"
+	"time"
 )
 
+// abortKey marks the request as aborted so the rest of the chain is skipped.
+const abortKey = "abort"
+
+// writtenKey marks that a handler wrote the response; each middleware gets
+// its own fiberContext, so the flag lives in the request locals.
+const writtenKey = "written"
+
 type fiberContext struct {
-	ctx *fiber.Ctx
+	ctx    *fiber.Ctx
+	nexted bool
+	err    error
 }
 
+// Context returns the user context of the request, not the fasthttp
+// RequestCtx; fasthttp does not cancel it when the client goes away.
 func (f *fiberContext) Context() context.Context {
-	return f.ctx.Context()
+	return f.ctx.UserContext()
+}
+
+func (f *fiberContext) SetContext(ctx context.Context) {
+	f.ctx.SetUserContext(ctx)
 }
 
 func (f *fiberContext) Param(name string) string {
@@ -27,26 +53,102 @@ func (f *fiberContext) Header(name string) string {
 	return f.ctx.Get(name)
 }
 
+// Bind decodes the request by Content-Type, then validates obj with core.Validate.
 func (f *fiberContext) Bind(obj interface{}) error {
-	return f.ctx.BodyParser(obj)
+	if handled, err := core.BindBody(f, obj); handled {
+		return err
+	}
+	// Không có Content-Type (ví dụ GET): bind query như gin và echo, vì
+	// BodyParser từ chối body không có Content-Type
+	if len(f.ctx.Request().Header.ContentType()) == 0 {
+		if err := f.ctx.QueryParser(obj); err != nil {
+			return err
+		}
+		return core.Validate(obj)
+	}
+	// BodyParser đọc hết stream không giới hạn, nên đọc qua BodyBytes trước
+	if _, err := f.BodyBytes(); err != nil {
+		return err
+	}
+	if err := f.ctx.BodyParser(obj); err != nil {
+		return err
+	}
+	return core.Validate(obj)
+}
+
+// Body streams the request body: the server runs fasthttp with
+// StreamRequestBody, so only the first few KB are read before the handler.
+func (f *fiberContext) Body() io.Reader {
+	req := f.ctx.Request()
+	if stream := req.BodyStream(); stream != nil {
+		return core.LimitReader(f, stream, int64(req.Header.ContentLength()))
+	}
+	return core.LimitReader(f, bytes.NewReader(req.Body()), int64(len(req.Body())))
+}
+
+// BodyBytes reads the body and keeps it on the request, so fiber's own
+// readers (BodyParser, Raw().(*fiber.Ctx).Body()) still see it.
+func (f *fiberContext) BodyBytes() ([]byte, error) {
+	req := f.ctx.Request()
+	if req.BodyStream() == nil {
+		return io.ReadAll(f.Body())
+	}
+	body, err := io.ReadAll(f.Body())
+	if err != nil {
+		return nil, err
+	}
+	_ = req.CloseBodyStream()
+	req.SetBody(body)
+	return body, nil
+}
+
+func (f *fiberContext) FormValue(name string) string {
+	return core.FormValue(f, name)
+}
+
+func (f *fiberContext) FormFile(name string) (*multipart.FileHeader, error) {
+	return core.FormFile(f, name)
+}
+
+func (f *fiberContext) MultipartForm() (*multipart.Form, error) {
+	return core.ParseMultipartForm(f)
+}
+
+func (f *fiberContext) SaveUploadedFile(file *multipart.FileHeader, dst string) error {
+	return core.SaveUploadedFile(f, file, dst)
 }
 
+// JSON writes obj as JSON, keeping a JSON content type set before (e.g.
+// application/problem+json) like gin and echo do.
 func (f *fiberContext) JSON(code int, obj interface{}) {
-	if err := f.ctx.Status(code).JSON(obj); err != nil {
+	ctype := core.MIMEApplicationJSON
+	if current := string(f.ctx.Response().Header.ContentType()); strings.Contains(current, "json") {
+		ctype = current
+	}
+	f.markWritten()
+	if err := f.ctx.Status(code).JSON(obj, ctype); err != nil {
 		// Handle the error, for example by logging it
 		_ = f.ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to send JSON response"})
 	}
 }
 
+// Abort stops the chain. Fiber has no Abort, so we mark the request and the
+// middleware wrapper stops calling Next().
 func (f *fiberContext) Abort() {
-	// Fiber không có Abort, nên ta dừng luồng bằng cách không gọi next()
+	f.ctx.Locals(abortKey, true)
+}
+
+func (f *fiberContext) isAborted() bool {
+	return f.ctx.Locals(abortKey) == true
 }
 
 func (f *fiberContext) AbortWithStatusJSON(code int, obj interface{}) {
 	f.JSON(code, obj)
+	f.Abort()
 }
 
 func (f *fiberContext) String(code int, msg string) error {
+	f.markWritten()
 	return f.ctx.Status(code).SendString(msg)
 }
 
@@ -55,24 +157,222 @@ func (f *fiberContext) Status(code int) core.Context {
 	return f
 }
 
+// Written reports whether a response was written (JSON, String, Blob,
+// Stream, SSE or File); a status set with Status alone does not count.
+// fasthttp buffers the response, so nothing reaches the client before the
+// chain ends.
+func (f *fiberContext) Written() bool {
+	return f.ctx.Locals(writtenKey) == true
+}
+
+func (f *fiberContext) markWritten() {
+	f.ctx.Locals(writtenKey, true)
+}
+
 func (f *fiberContext) SetHeader(key, value string) {
 	f.ctx.Set(key, value)
 }
 
+// AddHeader appends value to the comma-separated header, once.
+func (f *fiberContext) AddHeader(key, value string) {
+	f.ctx.Append(key, value)
+}
+
+func (f *fiberContext) XML(code int, obj interface{}) error {
+	return core.XML(f, code, obj)
+}
+
+func (f *fiberContext) HTML(code int, name string, data interface{}) error {
+	return core.HTML(f, code, name, data)
+}
+
+func (f *fiberContext) Blob(code int, contentType string, data []byte) error {
+	if contentType != "" {
+		f.ctx.Set(core.HeaderContentType, contentType)
+	}
+	f.markWritten()
+	return f.ctx.Status(code).Send(data)
+}
+
+// Stream hands r to fasthttp, which reads it once the handler has returned.
+func (f *fiberContext) Stream(code int, contentType string, r io.Reader) error {
+	if contentType != "" {
+		f.ctx.Set(core.HeaderContentType, contentType)
+	}
+	f.markWritten()
+	f.ctx.Status(code).Context().SetBodyStream(r, -1)
+	return nil
+}
+
+// SSE streams with fasthttp's body stream writer, which runs fn after the
+// handler returns, so the fiber.Ctx must not be used inside fn. The stream
+// context keeps the values of Context() but not its deadline, and is
+// cancelled when a write fails. fasthttp applies Timeouts.Write to the
+// whole response, so every flush lifts it again: the first event or
+// heartbeat must come within it.
+func (f *fiberContext) SSE(fn func(core.EventStream) error) error {
+	core.SetSSEHeaders(f)
+	f.markWritten()
+	ctx := context.WithoutCancel(f.Context())
+	lastEventID := f.Header(core.HeaderLastEventID)
+	method, path := f.Method(), f.Path()
+	var conn net.Conn
+	if f.ctx.App().Config().WriteTimeout > 0 {
+		conn = f.ctx.Context().Conn()
+	}
+	f.ctx.Status(core.StatusOK).Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		flush := func() error {
+			if conn != nil {
+				_ = conn.SetWriteDeadline(time.Time{})
+			}
+			return w.Flush()
+		}
+		if err := core.ServeSSE(ctx, w, flush, lastEventID, fn); err != nil {
+			log.Printf("%s %s: %v", method, path, err)
+		}
+	})
+	return nil
+}
+
+// Request converts the fasthttp request, so it outlives the fiber.Ctx.
+func (f *fiberContext) Request() (*http.Request, error) {
+	return adaptor.ConvertRequest(f.ctx, true)
+}
+
+// Hijack runs fn with fasthttp's Hijack once the handler returns; the
+// response is not written.
+func (f *fiberContext) Hijack(fn func(net.Conn, *bufio.Reader)) error {
+	ctx := f.ctx.Context()
+	ctx.HijackSetNoResponse(true)
+	ctx.Hijack(func(conn net.Conn) {
+		fn(conn, bufio.NewReader(conn))
+	})
+	return nil
+}
+
+// maxCompressStream is the largest body stream CompressResponse encodes; the
+// encoded body is buffered, so larger files are sent as is.
+const maxCompressStream = 8 << 20
+
+// CompressResponse encodes the body fasthttp holds once the chain is done.
+// A body stream is only encoded when its length is known (File) and at most
+// maxCompressStream, and is read then; Stream and SSE are sent as is.
+func (f *fiberContext) CompressResponse(z *core.Compression) func() {
+	return func() {
+		res := f.ctx.Response()
+		size := res.Header.ContentLength()
+		if !res.IsBodyStream() {
+			size = len(res.Body())
+		} else if size < 0 || size > maxCompressStream {
+			return
+		}
+		h := responseHeader{&res.Header}
+		encoding := z.Decide(h, res.StatusCode(), size)
+		if encoding == "" {
+			return
+		}
+		var buf bytes.Buffer
+		w := z.NewWriter(encoding, &buf)
+		var err error
+		if res.IsBodyStream() {
+			_, err = io.Copy(w, res.BodyStream())
+		} else {
+			_, err = w.Write(res.Body())
+		}
+		if err == nil {
+			err = w.Close()
+		}
+		if err != nil {
+			f.Error(err)
+			return
+		}
+		z.SetHeaders(h, encoding)
+		res.SetBodyRaw(buf.Bytes())
+	}
+}
+
+// responseHeader cho core.Compress sửa header của fasthttp như http.Header
+type responseHeader struct {
+	h *fasthttp.ResponseHeader
+}
+
+func (r responseHeader) Get(key string) string {
+	return string(r.h.Peek(key))
+}
+
+func (r responseHeader) Set(key, value string) {
+	r.h.Set(key, value)
+}
+
+func (r responseHeader) Add(key, value string) {
+	r.h.Add(key, value)
+}
+
+func (r responseHeader) Del(key string) {
+	r.h.Del(key)
+}
+
+func (f *fiberContext) File(path string) error {
+	// SendFile không phân biệt file thiếu với lỗi khác, nên kiểm tra trước
+	file, _, err := core.OpenFile(path)
+	if err != nil {
+		return err
+	}
+	file.Close()
+	f.markWritten()
+	return f.ctx.SendFile(path)
+}
+
+func (f *fiberContext) Attachment(path, filename string) error {
+	return core.Attachment(f, path, filename)
+}
+
+func (f *fiberContext) Redirect(code int, location string) error {
+	return core.Redirect(f, code, location)
+}
+
+func (f *fiberContext) NoContent(code int) error {
+	return f.Blob(code, "", nil)
+}
+
+func (f *fiberContext) Negotiate(code int, offers map[string]interface{}) error {
+	return core.Negotiate(f, code, offers)
+}
+
+func (f *fiberContext) Error(err error) {
+	core.HandleError(f, err)
+}
+
+func (f *fiberContext) ClientCertificate() *x509.Certificate {
+	return core.VerifiedClientCertificate(f.ctx.Context().TLSConnectionState())
+}
+
+func (f *fiberContext) Protocol() string {
+	return string(f.ctx.Context().Request.Header.Protocol())
+}
+
+func (f *fiberContext) RemoteAddr() string {
+	return f.ctx.Context().RemoteAddr().String()
+}
+
 // Method returns the HTTP method of the request.
 func (f *fiberContext) Method() string {
 	return f.ctx.Method()
 }
 
-// Path returns the request path.
+// Path returns the matched route pattern.
 func (f *fiberContext) Path() string {
-	return f.ctx.Path()
+	return f.ctx.Route().Path
 }
 
 // Next calls the next middleware in the chain.
 func (f *fiberContext) Next() {
-	if err := f.ctx.Next(); err != nil {
-		log.Printf("Next() error: %v", err)
+	if f.nexted || f.isAborted() {
+		return
+	}
+	f.nexted = true
+	if f.err = f.ctx.Next(); f.err != nil {
+		log.Printf("Next() error: %v", f.err)
 	}
 }
 
@@ -82,7 +382,7 @@ func (f *fiberContext) Raw() interface{} {
 
 func (f *fiberContext) Set(key string, value interface{}) {
 	f.ctx.Locals(key, value)
-	ctx := context.WithValue(f.ctx.Context(), key, value)
+	ctx := context.WithValue(f.ctx.UserContext(), key, value)
 	f.ctx.SetUserContext(ctx)
 }
 
@@ -2,139 +2,372 @@ package fiber
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/gofiber/fiber/v2/middleware/recover"
+	"github.com/gofiber/fiber/v2/utils"
 	"github.com/kimxuanhong/go-server/core"
+	"html/template"
 	"log"
-	"time"
+	"net"
+	"net/http"
+	"sync"
 )
 
 // Server implements core.Server for Fiber.
 type Server struct {
 	*core.DynamicRouter
 	*core.ProviderRouter
-	app       *fiber.App
-	rootGroup fiber.Router
-	config    *core.Config
+	*core.HealthChecks
+	*core.Lifecycle
+	app          *fiber.App
+	rootGroup    fiber.Router
+	config       *core.Config
+	middleware   []core.Handler
+	mountOnce    sync.Once
+	errorHandler core.ErrorHandler
+	htmlTemplate *template.Template
+	adminAuth    []core.Handler
+	admin        *Server
+	adminOnce    sync.Once
+	// corsErr là lỗi của Config.CORS, Start trả về thay vì panic trong NewServer
+	corsErr error
 }
 
 func NewServer(configs ...*core.Config) core.Server {
 	cfg := core.GetConfig(configs...)
-	app := fiber.New()
-	rootGroup := app.Group(cfg.RootPath)
-	app.Use(func(c *fiber.Ctx) error {
+	s := &Server{
+		DynamicRouter:  &core.DynamicRouter{DevMode: cfg.Mode == "debug", RateLimit: cfg.RateLimit},
+		ProviderRouter: &core.ProviderRouter{},
+		HealthChecks:   &core.HealthChecks{},
+		Lifecycle:      &core.Lifecycle{},
+		config:         cfg,
+	}
+	// fasthttp không có timeout riêng cho header, ReadHeader áp cho cả request khi Read trống
+	readTimeout := cfg.Timeouts.Read
+	if readTimeout <= 0 {
+		readTimeout = cfg.Timeouts.GetReadHeader()
+	}
+	s.app = fiber.New(fiber.Config{
+		ErrorHandler: s.handleError,
+		ReadTimeout:  readTimeout,
+		WriteTimeout: cfg.Timeouts.Write,
+		IdleTimeout:  cfg.Timeouts.GetIdle(),
+		// 0 giữ mặc định của fasthttp (4 KB header, 256K connection)
+		ReadBufferSize: cfg.Limits.MaxHeaderBytes,
+		Concurrency:    cfg.Limits.MaxConnections,
+		// Body đọc dần qua Context.Body, giới hạn bởi Limits.MaxBodyBytes/BodyLimit
+		StreamRequestBody:            true,
+		DisablePreParseMultipartForm: true,
+	})
+	s.rootGroup = s.app.Group(cfg.RootPath)
+	s.app.Use(s.prepareRequest)
+	s.app.Use(func(c *fiber.Ctx) error {
 		log.Printf("Request: %s %s", c.Method(), c.Path())
 		return c.Next()
 	})
-
-	return &Server{
-		DynamicRouter:  &core.DynamicRouter{},
-		ProviderRouter: &core.ProviderRouter{},
-		app:            app,
-		rootGroup:      rootGroup,
-		config:         cfg,
+	s.app.Use(recover.New())
+	// app.Use chạy cho mọi path, kể cả path không có route
+	if cfg.CORS.Enabled() {
+		if cors, err := core.NewCORS(cfg.CORS); err != nil {
+			log.Printf("CORS disabled: %v", err)
+			s.corsErr = err
+		} else {
+			s.app.Use(transferMiddleware(cors))
+		}
 	}
+	return s
 }
 
 func (s *Server) Start() error {
-	addr := s.config.GetAddr()
+	listeners, err := s.config.Listen()
+	if err != nil {
+		return err
+	}
+	return s.serve(listeners)
+}
 
-	//add api from @Api tag
-	s.LoadRouter()
-	s.Routes(s.DynamicRouter.Routes)
+// Serve serves on ln instead of the configured listeners, e.g. a socket
+// inherited from a parent process.
+func (s *Server) Serve(ln net.Listener) error {
+	return s.serve([]net.Listener{ln})
+}
 
-	//add api from provider route
-	s.Routes(s.ProviderRouter.Routes)
+func (s *Server) serve(listeners []net.Listener) error {
+	tlsConfig, err := s.prepare()
+	if err != nil {
+		core.CloseListeners(listeners)
+		return err
+	}
+	scheme := "http"
+	if tlsConfig != nil {
+		scheme = "https"
+		for i, ln := range listeners {
+			listeners[i] = tls.NewListener(ln, tlsConfig)
+		}
+	}
+	return core.ServeListeners(scheme, listeners, s.app.Listener)
+}
+
+// prepare runs the start hooks, mounts the routes, builds the TLS config (nil
+// without TLS) and starts the admin listener.
+func (s *Server) prepare() (*tls.Config, error) {
+	if s.corsErr != nil {
+		return nil, s.corsErr
+	}
+	// fasthttp chỉ nói HTTP/1.1, h2/h2c được coi là lỗi cấu hình thay vì bỏ qua
+	if len(s.config.Protocols) > 0 {
+		if err := core.CheckProtocols("fiber", s.config.Protocols, core.ProtocolHTTP1); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.RunStartHooks(context.Background()); err != nil {
+		return nil, err
+	}
+	s.mount()
 
 	// Debug: Print registered routes
 	for _, route := range s.app.GetRoutes(true) {
 		log.Printf("Route: %s %s -> %s", route.Method, route.Path, route.Name)
 	}
 
-	log.Printf("Server is running at %s", addr)
-	return s.app.Listen(addr)
+	var tlsConfig *tls.Config
+	if s.config.TLS.Enabled() {
+		var err error
+		if tlsConfig, err = core.NewTLSConfig(s.config.TLS); err != nil {
+			return nil, err
+		}
+		tlsConfig.NextProtos = []string{"http/1.1"}
+	}
+
+	if s.config.Admin.Enabled() {
+		s.AdminHandler()
+		go func() {
+			if err := s.admin.Start(); err != nil {
+				log.Printf("Admin server error: %v", err)
+			}
+		}()
+	}
+	return tlsConfig, nil
+}
+
+func (s *Server) mount() {
+	s.mountOnce.Do(func() {
+		//add api from @Api tag
+		s.LoadRouter()
+		s.Routes(s.DynamicRouter.Routes)
+
+		//add api from provider route
+		s.Routes(s.ProviderRouter.Routes)
+	})
 }
 
+// Handler loads the registered routes once and adapts the fiber app to net/http.
+func (s *Server) Handler() http.Handler {
+	s.mount()
+	return adaptor.FiberApp(s.app)
+}
+
+// Shutdown drains the in-flight requests within ctx, stops the admin
+// listener and runs the OnShutdown hooks.
 func (s *Server) Shutdown(ctx context.Context) error {
 	log.Println("Shutting down server...")
-	return s.app.Shutdown()
+	err := s.app.ShutdownWithContext(ctx)
+	if s.admin != nil {
+		err = errors.Join(err, s.admin.Shutdown(ctx))
+	}
+	return errors.Join(err, s.RunShutdownHooks(ctx))
 }
 
+// Use registers global middleware for the routes added afterwards. Middleware
+// is chained into each route instead of app.Use, so Path() sees the route
+// pattern and unmatched requests are not affected, like gin and echo.
 func (s *Server) Use(middleware ...core.Handler) {
-	for _, m := range middleware {
-		s.rootGroup.Use(transfer(m))
-	}
+	s.middleware = append(s.middleware, middleware...)
 }
 
 func (s *Server) AddGroup(relativePath string, register func(rg core.RouterGroup), middleware ...core.Handler) {
 	group := s.rootGroup.Group(relativePath)
-	for _, m := range middleware {
-		group.Use(transfer(m))
-	}
-	register(&RouterGroup{group: group})
+	register(&RouterGroup{group: group, middleware: chain(s.middleware, middleware)})
 }
 
 func (s *Server) Add(method, relativePath string, handler core.Handler, middleware ...core.Handler) {
-	for _, m := range middleware {
-		s.rootGroup.Use(relativePath, transfer(m))
-	}
-	s.app.Add(method, relativePath, transfer(handler))
+	s.rootGroup.Add(method, relativePath, handlers(handler, chain(s.middleware, middleware))...)
 }
 
 func (s *Server) Routes(routes []core.RouteConfig) {
 	for _, r := range routes {
+		r = core.WebSocketRoute(r, s.OnShutdown)
 		s.Add(r.Method, r.Path, r.Handler, r.Middleware...)
 	}
 }
 
+func (s *Server) WebSocket(relativePath string, handler core.WSHandler, opts ...core.WSOptions) {
+	route := core.RouteConfig{Method: core.MethodWS, Path: relativePath, WebSocket: handler}
+	if len(opts) > 0 {
+		route.WSOptions = opts[0]
+	}
+	s.Routes([]core.RouteConfig{route})
+}
+
 func (s *Server) Static(relativePath, root string) {
 	s.app.Static(relativePath, root)
 }
 
 func (s *Server) HealthCheck() {
-	s.app.Add("GET", "/ping", func(c *fiber.Ctx) error {
-		return c.Status(core.StatusOK).JSON(fiber.Map{
-			"message": "pong",
-		})
-	})
+	if s.config.Admin.DisablePublic {
+		return
+	}
+	admin := s.adminEndpoints()
+	s.rootRoutes(admin.ProbeRoutes())
+	if !s.config.Admin.Enabled() {
+		s.rootRoutes(admin.PublicControlRoutes())
+	}
+}
 
-	s.app.Add("GET", "/liveness", func(c *fiber.Ctx) error {
-		return c.Status(core.StatusOK).JSON(fiber.Map{
-			"status": "alive",
-		})
-	})
+// UseAdmin adds auth middleware to the admin control routes. Call it before
+// HealthCheck and Start.
+func (s *Server) UseAdmin(middleware ...core.Handler) {
+	s.adminAuth = append(s.adminAuth, middleware...)
+}
 
-	s.app.Add("GET", "/readiness", func(c *fiber.Ctx) error {
-		// Thêm logic kiểm tra database, cache, v.v. nếu cần
-		return c.Status(core.StatusOK).JSON(fiber.Map{
-			"status": "ready",
-		})
+// AdminHandler builds the admin listener once, as a separate fiber app
+// serving the probes and control routes.
+func (s *Server) AdminHandler() http.Handler {
+	if !s.config.Admin.Enabled() {
+		return nil
+	}
+	s.adminOnce.Do(func() {
+		s.admin = NewServer(&core.Config{
+			Host: s.config.Admin.Host,
+			Port: s.config.Admin.Port,
+			Mode: s.config.Mode,
+		}).(*Server)
+		s.admin.errorHandler = s.errorHandler
+		admin := s.adminEndpoints()
+		s.admin.rootRoutes(admin.ProbeRoutes())
+		s.admin.rootRoutes(admin.ControlRoutes())
 	})
+	return s.admin.Handler()
+}
 
-	s.app.Post("/terminate", func(c *fiber.Ctx) error {
-		go func() {
-			time.Sleep(1 * time.Second)
-			_ = s.Shutdown(context.Background())
-		}()
-		return c.Status(core.StatusOK).JSON(fiber.Map{
-			"status": "terminating",
-		})
-	})
+func (s *Server) adminEndpoints() *core.AdminEndpoints {
+	return &core.AdminEndpoints{
+		Health:      s.HealthChecks,
+		Routes:      s.routeInfos,
+		Terminate:   func() { _ = s.Shutdown(context.Background()) },
+		Auth:        core.AdminAuth(s.config.Admin, s.adminAuth...),
+		Credentials: s.config.Admin.Token != "" || len(s.adminAuth) > 0,
+	}
+}
+
+// rootRoutes registers routes on the app, outside the root path.
+func (s *Server) rootRoutes(routes []core.RouteConfig) {
+	for _, r := range routes {
+		s.app.Add(r.Method, r.Path, handlers(r.Handler, r.Middleware)...)
+	}
+}
+
+func (s *Server) routeInfos() []core.RouteInfo {
+	var routes []core.RouteInfo
+	for _, r := range s.app.GetRoutes(true) {
+		routes = append(routes, core.RouteInfo{Method: r.Method, Path: r.Path})
+	}
+	return routes
+}
+
+func (s *Server) OpenAPI(info core.OpenAPIInfo) {
+	s.app.Get(core.OpenAPIPath, transfer(s.OpenAPIHandler(info, s.config.RootPath)))
+}
+
+func (s *Server) SetErrorHandler(h core.ErrorHandler) {
+	s.errorHandler = h
+}
+
+func (s *Server) SetHTMLTemplate(t *template.Template) {
+	s.htmlTemplate = t
+}
+
+// prepareRequest stores the server's ErrorHandler, its HTML templates and the
+// global body and multipart limits, and removes the temporary files of the
+// multipart form once the request is served.
+func (s *Server) prepareRequest(c *fiber.Ctx) error {
+	if s.errorHandler != nil {
+		c.Locals(core.ErrorHandlerKey, s.errorHandler)
+	}
+	if s.htmlTemplate != nil {
+		c.Locals(core.HTMLTemplateKey, s.htmlTemplate)
+	}
+	if n := s.config.Limits.MaxBodyBytes; n > 0 {
+		c.Locals(core.BodyLimitKey, n)
+	}
+	c.Locals(core.MultipartKey, s.config.Multipart)
+	err := c.Next()
+	core.RemoveMultipartForm(c.Locals(core.MultipartFormKey))
+	return err
+}
+
+// handleError renders the errors fiber produces itself (404/405, panics
+// caught by recover) through Context.Error.
+func (s *Server) handleError(c *fiber.Ctx, err error) error {
+	var fe *fiber.Error
+	if errors.As(err, &fe) {
+		detail := fe.Message
+		// "Cannot GET /x" của router fiber không có ở gin, echo nên bỏ đi
+		if detail == utils.StatusMessage(fe.Code) || fe.Code == core.StatusNotFound || fe.Code == core.StatusMethodNotAllowed {
+			detail = ""
+		}
+		err = core.NewHTTPError(fe.Code, detail).Wrap(err)
+	}
+	(&fiberContext{ctx: c, nexted: true}).Error(err)
+	return nil
 }
 
 type RouterGroup struct {
-	group fiber.Router
+	group      fiber.Router
+	middleware []core.Handler
 }
 
 func (g *RouterGroup) Add(method, path string, handler core.Handler, middleware ...core.Handler) {
+	g.group.Add(method, path, handlers(handler, chain(g.middleware, middleware))...)
+}
+
+// chain copies the middleware lists so groups and routes never share a backing array.
+func chain(lists ...[]core.Handler) []core.Handler {
+	var all []core.Handler
+	for _, l := range lists {
+		all = append(all, l...)
+	}
+	return all
+}
+
+func handlers(handler core.Handler, middleware []core.Handler) []fiber.Handler {
+	chain := make([]fiber.Handler, 0, len(middleware)+1)
 	for _, m := range middleware {
-		g.group.Use(path, transfer(m))
+		chain = append(chain, transferMiddleware(m))
 	}
-	g.group.Add(method, path, transfer(handler))
+	return append(chain, transfer(handler))
 }
 
 func transfer(h core.Handler) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		h(&fiberContext{ctx: c})
+		// Handler cuối chuỗi: Next() không làm gì, giống gin
+		h(&fiberContext{ctx: c, nexted: true})
 		return nil
 	}
 }
+
+func transferMiddleware(h core.Handler) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := &fiberContext{ctx: c}
+		h(ctx)
+
+		// Middleware không gọi Next() và không Abort() thì tiếp tục chuỗi xử lý
+		if !ctx.nexted && !ctx.isAborted() {
+			return c.Next()
+		}
+		return ctx.err
+	}
+}
@@ -1,9 +1,16 @@
 package gin
 
 import (
+	"bufio"
 	"context"
+	"crypto/x509"
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 	"github.com/kimxuanhong/go-server/core"
+	"io"
+	"mime/multipart"
+	"net"
+	"net/http"
 )
 
 type ginContext struct {
@@ -14,6 +21,10 @@ func (g *ginContext) Context() context.Context {
 	return g.ctx.Request.Context()
 }
 
+func (g *ginContext) SetContext(ctx context.Context) {
+	g.ctx.Request = g.ctx.Request.WithContext(ctx)
+}
+
 func (g *ginContext) Param(name string) string {
 	return g.ctx.Param(name)
 }
@@ -26,8 +37,56 @@ func (g *ginContext) Header(name string) string {
 	return g.ctx.GetHeader(name)
 }
 
+// Bind decodes the request by Content-Type, then validates obj with core.Validate.
+// Forms are mapped with gin's form tags but not through ShouldBind, which
+// would also run gin's validator.
 func (g *ginContext) Bind(obj interface{}) error {
-	return g.ctx.ShouldBind(obj)
+	if handled, err := core.BindBody(g, obj); handled {
+		return err
+	}
+	var values map[string][]string
+	if g.ctx.ContentType() == core.MIMEMultipartForm {
+		form, err := core.ParseMultipartForm(g)
+		if err != nil {
+			return err
+		}
+		values = form.Value
+	} else {
+		core.LimitRequestBody(g, g.ctx.Writer, g.ctx.Request)
+		if err := g.ctx.Request.ParseForm(); err != nil {
+			return err
+		}
+		values = g.ctx.Request.Form
+	}
+	if err := binding.MapFormWithTag(obj, values, "form"); err != nil {
+		return err
+	}
+	return core.Validate(obj)
+}
+
+func (g *ginContext) Body() io.Reader {
+	core.LimitRequestBody(g, g.ctx.Writer, g.ctx.Request)
+	return g.ctx.Request.Body
+}
+
+func (g *ginContext) BodyBytes() ([]byte, error) {
+	return io.ReadAll(g.Body())
+}
+
+func (g *ginContext) FormValue(name string) string {
+	return core.FormValue(g, name)
+}
+
+func (g *ginContext) FormFile(name string) (*multipart.FileHeader, error) {
+	return core.FormFile(g, name)
+}
+
+func (g *ginContext) MultipartForm() (*multipart.Form, error) {
+	return core.ParseMultipartForm(g)
+}
+
+func (g *ginContext) SaveUploadedFile(file *multipart.FileHeader, dst string) error {
+	return core.SaveUploadedFile(g, file, dst)
 }
 
 func (g *ginContext) JSON(code int, obj interface{}) {
@@ -52,16 +111,142 @@ func (g *ginContext) Status(code int) core.Context {
 	return g
 }
 
+func (g *ginContext) Written() bool {
+	return g.ctx.Writer.Written()
+}
+
 func (g *ginContext) SetHeader(key, value string) {
 	g.ctx.Header(key, value)
 }
 
+func (g *ginContext) AddHeader(key, value string) {
+	g.ctx.Writer.Header().Add(key, value)
+}
+
+func (g *ginContext) XML(code int, obj interface{}) error {
+	return core.XML(g, code, obj)
+}
+
+func (g *ginContext) HTML(code int, name string, data interface{}) error {
+	return core.HTML(g, code, name, data)
+}
+
+func (g *ginContext) Blob(code int, contentType string, data []byte) error {
+	if contentType != "" {
+		g.ctx.Header(core.HeaderContentType, contentType)
+	}
+	g.ctx.Status(code)
+	g.ctx.Writer.WriteHeaderNow()
+	_, err := g.ctx.Writer.Write(data)
+	return err
+}
+
+func (g *ginContext) Stream(code int, contentType string, r io.Reader) error {
+	if contentType != "" {
+		g.ctx.Header(core.HeaderContentType, contentType)
+	}
+	g.ctx.Status(code)
+	g.ctx.Writer.WriteHeaderNow()
+	return core.CopyFlush(g.ctx.Writer, r)
+}
+
+func (g *ginContext) SSE(fn func(core.EventStream) error) error {
+	return core.SSE(g, g.ctx.Writer, fn)
+}
+
+func (g *ginContext) Request() (*http.Request, error) {
+	return g.ctx.Request, nil
+}
+
+func (g *ginContext) Hijack(fn func(net.Conn, *bufio.Reader)) error {
+	return core.HijackHTTP(g.ctx.Writer, fn)
+}
+
+// CompressResponse puts a core.CompressWriter in front of gin's writer
+// until finish.
+func (g *ginContext) CompressResponse(z *core.Compression) func() {
+	orig := g.ctx.Writer
+	w := &compressWriter{ResponseWriter: orig, cw: core.NewCompressWriter(orig, z)}
+	g.ctx.Writer = w
+	return func() {
+		_ = w.cw.Close()
+		g.ctx.Writer = orig
+	}
+}
+
+// compressWriter gửi body qua CompressWriter; status vẫn do writer của gin
+// giữ, và chỉ được chuyển cho CompressWriter khi gin ghi header
+type compressWriter struct {
+	gin.ResponseWriter
+	cw *core.CompressWriter
+}
+
+func (w *compressWriter) WriteHeaderNow() {
+	if !w.cw.Written() {
+		w.cw.WriteHeader(w.ResponseWriter.Status())
+	}
+}
+
+func (w *compressWriter) Write(b []byte) (int, error) {
+	w.WriteHeaderNow()
+	return w.cw.Write(b)
+}
+
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *compressWriter) Written() bool {
+	return w.cw.Written() || w.ResponseWriter.Written()
+}
+
+func (w *compressWriter) Flush() {
+	w.WriteHeaderNow()
+	w.cw.Flush()
+}
+
+func (g *ginContext) File(path string) error {
+	return core.ServeFile(g.ctx.Writer, g.ctx.Request, path)
+}
+
+func (g *ginContext) Attachment(path, filename string) error {
+	return core.Attachment(g, path, filename)
+}
+
+func (g *ginContext) Redirect(code int, location string) error {
+	return core.Redirect(g, code, location)
+}
+
+func (g *ginContext) NoContent(code int) error {
+	return g.Blob(code, "", nil)
+}
+
+func (g *ginContext) Negotiate(code int, offers map[string]interface{}) error {
+	return core.Negotiate(g, code, offers)
+}
+
+func (g *ginContext) Error(err error) {
+	core.HandleError(g, err)
+}
+
 // Method returns the HTTP method of the request.
 func (g *ginContext) Method() string {
 	return g.ctx.Request.Method
 }
 
-// Path returns the request path.
+func (g *ginContext) ClientCertificate() *x509.Certificate {
+	return core.VerifiedClientCertificate(g.ctx.Request.TLS)
+}
+
+func (g *ginContext) Protocol() string {
+	return g.ctx.Request.Proto
+}
+
+func (g *ginContext) RemoteAddr() string {
+	return g.ctx.Request.RemoteAddr
+}
+
+// Path returns the matched route pattern.
 func (g *ginContext) Path() string {
 	return g.ctx.FullPath()
 }
@@ -2,64 +2,170 @@ package gin
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/kimxuanhong/go-server/core"
+	"html/template"
 	"log"
+	"net"
 	"net/http"
-	"time"
+	"sync"
 )
 
 // Server implements core.Server for Gin.
 type Server struct {
 	*core.DynamicRouter
 	*core.ProviderRouter
-	engine     *gin.Engine
-	rootGroup  *gin.RouterGroup
-	config     *core.Config
-	httpServer *http.Server
+	*core.HealthChecks
+	*core.Lifecycle
+	engine       *gin.Engine
+	rootGroup    *gin.RouterGroup
+	config       *core.Config
+	httpServer   *http.Server
+	mountOnce    sync.Once
+	errorHandler core.ErrorHandler
+	htmlTemplate *template.Template
+	adminAuth    []core.Handler
+	admin        *Server
+	adminOnce    sync.Once
+	// corsErr là lỗi của Config.CORS, Start trả về thay vì panic trong NewServer
+	corsErr error
 }
 
 func NewServer(configs ...*core.Config) core.Server {
 	cfg := core.GetConfig(configs...)
 	gin.SetMode(cfg.Mode)
 	engine := gin.New()
-	rootGroup := engine.Group(cfg.RootPath)
-	engine.Use(gin.Logger())
-	engine.Use(gin.Recovery())
 
-	return &Server{
-		DynamicRouter:  &core.DynamicRouter{},
+	s := &Server{
+		DynamicRouter:  &core.DynamicRouter{DevMode: cfg.Mode == "debug", RateLimit: cfg.RateLimit},
 		ProviderRouter: &core.ProviderRouter{},
+		HealthChecks:   &core.HealthChecks{},
+		Lifecycle:      &core.Lifecycle{},
 		engine:         engine,
-		rootGroup:      rootGroup,
 		config:         cfg,
+		// Tạo sẵn để Shutdown gọi trước khi Start listen vẫn dừng được server
+		httpServer: &http.Server{Addr: cfg.GetAddr()},
+	}
+	engine.Use(s.prepareRequest)
+	engine.Use(gin.Logger())
+	engine.Use(gin.CustomRecovery(func(c *gin.Context, err any) {
+		(&ginContext{ctx: c}).Error(fmt.Errorf("panic: %v", err))
+	}))
+	// Middleware của engine chạy cả cho NoRoute, nên preflight của mọi path được trả lời
+	if cfg.CORS.Enabled() {
+		if cors, err := core.NewCORS(cfg.CORS); err != nil {
+			log.Printf("CORS disabled: %v", err)
+			s.corsErr = err
+		} else {
+			engine.Use(transfer(cors))
+		}
 	}
+	engine.NoRoute(transfer(func(c core.Context) {
+		c.Error(core.NewHTTPError(core.StatusNotFound, ""))
+	}))
+	engine.NoMethod(transfer(func(c core.Context) {
+		c.Error(core.NewHTTPError(core.StatusMethodNotAllowed, ""))
+	}))
+	// Group phải tạo sau engine.Use, gin chép middleware của engine lúc tạo group
+	s.rootGroup = engine.Group(cfg.RootPath)
+	return s
 }
 
 func (s *Server) Start() error {
-	addr := s.config.GetAddr()
-	s.httpServer = &http.Server{
-		Addr:    addr,
-		Handler: s.engine,
+	listeners, err := s.config.Listen()
+	if err != nil {
+		return err
+	}
+	return s.serve(listeners)
+}
+
+// Serve serves on ln instead of the configured listeners, e.g. a socket
+// inherited from a parent process.
+func (s *Server) Serve(ln net.Listener) error {
+	return s.serve([]net.Listener{ln})
+}
+
+func (s *Server) serve(listeners []net.Listener) error {
+	if err := s.prepare(); err != nil {
+		core.CloseListeners(listeners)
+		return err
+	}
+	listeners = core.LimitListeners(listeners, s.config.Limits.MaxConnections)
+	scheme, serve := "http", s.httpServer.Serve
+	if s.config.TLS.Enabled() {
+		// Certificate lấy từ TLSConfig.GetCertificate nên không truyền file
+		scheme, serve = "https", func(ln net.Listener) error {
+			return s.httpServer.ServeTLS(ln, "", "")
+		}
+	}
+	return core.ServeListeners(scheme, listeners, serve)
+}
+
+// prepare runs the start hooks, mounts the routes, applies TLS and the
+// protocols and starts the admin listener.
+func (s *Server) prepare() error {
+	if s.corsErr != nil {
+		return s.corsErr
+	}
+	protocols := s.config.GetProtocols()
+	if err := core.CheckProtocols("gin", protocols, core.NetHTTPProtocols...); err != nil {
+		return err
+	}
+	if err := s.RunStartHooks(context.Background()); err != nil {
+		return err
+	}
+	s.httpServer.Handler = s.Handler()
+	core.ApplyLimits(s.httpServer, s.config)
+
+	if s.config.TLS.Enabled() {
+		tlsConfig, err := core.NewTLSConfig(s.config.TLS)
+		if err != nil {
+			return err
+		}
+		s.httpServer.TLSConfig = tlsConfig
 	}
+	if err := core.ConfigureProtocols(s.httpServer, protocols); err != nil {
+		return err
+	}
+
+	if s.config.Admin.Enabled() {
+		s.AdminHandler()
+		go func() {
+			if err := s.admin.Start(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Printf("Admin server error: %v", err)
+			}
+		}()
+	}
+	return nil
+}
 
-	//add api from @Api tag
-	s.LoadRouter()
-	s.Routes(s.DynamicRouter.Routes)
+// Handler loads the registered routes once and returns the gin engine.
+func (s *Server) Handler() http.Handler {
+	s.mountOnce.Do(func() {
+		//add api from @Api tag
+		s.LoadRouter()
+		s.Routes(s.DynamicRouter.Routes)
 
-	//add api from provider route
-	s.Routes(s.ProviderRouter.Routes)
+		//add api from provider route
+		s.Routes(s.ProviderRouter.Routes)
 
-	log.Printf("Server is running at %s", addr)
-	return s.httpServer.ListenAndServe()
+		// Trả 405 qua NoMethod; gin 1.10 panic khi engine chưa có route nào
+		s.engine.HandleMethodNotAllowed = len(s.engine.Routes()) > 0
+	})
+	return s.engine
 }
 
+// Shutdown drains the in-flight requests within ctx, stops the admin
+// listener and runs the OnShutdown hooks.
 func (s *Server) Shutdown(ctx context.Context) error {
 	log.Println("Shutting down server...")
-	if s.httpServer == nil {
-		return nil
+	err := s.httpServer.Shutdown(ctx)
+	if s.admin != nil {
+		err = errors.Join(err, s.admin.Shutdown(ctx))
 	}
-	return s.httpServer.Shutdown(ctx)
+	return errors.Join(err, s.RunShutdownHooks(ctx))
 }
 
 func (s *Server) Use(middleware ...core.Handler) {
@@ -77,47 +183,120 @@ func (s *Server) AddGroup(relativePath string, register func(rg core.RouterGroup
 }
 
 func (s *Server) Add(method, relativePath string, handler core.Handler, middleware ...core.Handler) {
-	handlers := make([]gin.HandlerFunc, 0, len(middleware)+1)
-	for _, m := range middleware {
-		handlers = append(handlers, transfer(m))
-	}
-	handlers = append(handlers, transfer(handler))
-	s.rootGroup.Handle(method, relativePath, handlers...)
+	s.rootGroup.Handle(method, relativePath, handlers(handler, middleware)...)
 }
 
 func (s *Server) Routes(routes []core.RouteConfig) {
 	for _, r := range routes {
+		r = core.WebSocketRoute(r, s.OnShutdown)
 		s.Add(r.Method, r.Path, r.Handler, r.Middleware...)
 	}
 }
 
+func (s *Server) WebSocket(relativePath string, handler core.WSHandler, opts ...core.WSOptions) {
+	route := core.RouteConfig{Method: core.MethodWS, Path: relativePath, WebSocket: handler}
+	if len(opts) > 0 {
+		route.WSOptions = opts[0]
+	}
+	s.Routes([]core.RouteConfig{route})
+}
+
 func (s *Server) Static(relativePath, root string) {
 	s.engine.Static(relativePath, root)
 }
 
 func (s *Server) HealthCheck() {
-	s.engine.GET("/ping", func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{
-			"message": "pong",
-		})
-	})
+	if s.config.Admin.DisablePublic {
+		return
+	}
+	admin := s.adminEndpoints()
+	s.rootRoutes(admin.ProbeRoutes())
+	if !s.config.Admin.Enabled() {
+		s.rootRoutes(admin.PublicControlRoutes())
+	}
+}
 
-	s.engine.GET("/liveness", func(c *gin.Context) {
-		c.JSON(core.StatusOK, gin.H{"status": "alive"})
-	})
+// UseAdmin adds auth middleware to the admin control routes. Call it before
+// HealthCheck and Start.
+func (s *Server) UseAdmin(middleware ...core.Handler) {
+	s.adminAuth = append(s.adminAuth, middleware...)
+}
 
-	s.engine.GET("/readiness", func(c *gin.Context) {
-		// Bạn có thể kiểm tra kết nối DB, Redis, etc. tại đây
-		c.JSON(core.StatusOK, gin.H{"status": "ready"})
+// AdminHandler builds the admin listener once, as a separate gin engine
+// serving the probes and control routes.
+func (s *Server) AdminHandler() http.Handler {
+	if !s.config.Admin.Enabled() {
+		return nil
+	}
+	s.adminOnce.Do(func() {
+		s.admin = NewServer(&core.Config{
+			Host: s.config.Admin.Host,
+			Port: s.config.Admin.Port,
+			Mode: s.config.Mode,
+		}).(*Server)
+		s.admin.errorHandler = s.errorHandler
+		admin := s.adminEndpoints()
+		s.admin.rootRoutes(admin.ProbeRoutes())
+		s.admin.rootRoutes(admin.ControlRoutes())
 	})
+	return s.admin.Handler()
+}
 
-	s.engine.POST("/terminate", func(c *gin.Context) {
-		go func() {
-			time.Sleep(1 * time.Second)
-			_ = s.Shutdown(context.Background())
-		}()
-		c.JSON(core.StatusOK, gin.H{"status": "terminating"})
-	})
+func (s *Server) adminEndpoints() *core.AdminEndpoints {
+	return &core.AdminEndpoints{
+		Health:      s.HealthChecks,
+		Routes:      s.routeInfos,
+		Terminate:   func() { _ = s.Shutdown(context.Background()) },
+		Auth:        core.AdminAuth(s.config.Admin, s.adminAuth...),
+		Credentials: s.config.Admin.Token != "" || len(s.adminAuth) > 0,
+	}
+}
+
+// rootRoutes registers routes on the engine, outside the root path.
+func (s *Server) rootRoutes(routes []core.RouteConfig) {
+	for _, r := range routes {
+		s.engine.Handle(r.Method, r.Path, handlers(r.Handler, r.Middleware)...)
+	}
+}
+
+func (s *Server) routeInfos() []core.RouteInfo {
+	var routes []core.RouteInfo
+	for _, r := range s.engine.Routes() {
+		routes = append(routes, core.RouteInfo{Method: r.Method, Path: r.Path})
+	}
+	return routes
+}
+
+func (s *Server) OpenAPI(info core.OpenAPIInfo) {
+	s.engine.GET(core.OpenAPIPath, transfer(s.OpenAPIHandler(info, s.config.RootPath)))
+}
+
+func (s *Server) SetErrorHandler(h core.ErrorHandler) {
+	s.errorHandler = h
+}
+
+func (s *Server) SetHTMLTemplate(t *template.Template) {
+	s.htmlTemplate = t
+}
+
+// prepareRequest stores the server's ErrorHandler, its HTML templates and the
+// global body and multipart limits, and removes the temporary files of the
+// multipart form once the request is served.
+func (s *Server) prepareRequest(c *gin.Context) {
+	if s.errorHandler != nil {
+		c.Set(core.ErrorHandlerKey, s.errorHandler)
+	}
+	if s.htmlTemplate != nil {
+		c.Set(core.HTMLTemplateKey, s.htmlTemplate)
+	}
+	if n := s.config.Limits.MaxBodyBytes; n > 0 {
+		c.Set(core.BodyLimitKey, n)
+	}
+	c.Set(core.MultipartKey, s.config.Multipart)
+	c.Next()
+	if form, ok := c.Get(core.MultipartFormKey); ok {
+		core.RemoveMultipartForm(form)
+	}
 }
 
 type RouterGroup struct {
@@ -125,10 +304,17 @@ type RouterGroup struct {
 }
 
 func (g *RouterGroup) Add(method, path string, handler core.Handler, middleware ...core.Handler) {
+	g.group.Handle(method, path, handlers(handler, middleware)...)
+}
+
+// handlers chains the route middleware before the handler, so the
+// middleware only applies to this route instead of the whole group.
+func handlers(handler core.Handler, middleware []core.Handler) []gin.HandlerFunc {
+	chain := make([]gin.HandlerFunc, 0, len(middleware)+1)
 	for _, m := range middleware {
-		g.group.Use(transfer(m))
+		chain = append(chain, transfer(m))
 	}
-	g.group.Handle(method, path, transfer(handler))
+	return append(chain, transfer(handler))
 }
 
 func transfer(h core.Handler) gin.HandlerFunc {
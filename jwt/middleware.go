@@ -14,14 +14,16 @@ func AuthMiddleware(jwtComp *Jwt) core.Handler {
 	return func(c core.Context) {
 		authHeader := c.Header("Authorization")
 		if !strings.HasPrefix(authHeader, "Bearer ") {
-			c.AbortWithStatusJSON(core.StatusUnauthorized, map[string]interface{}{"error": "missing token"})
+			c.SetHeader(core.HeaderWWWAuthenticate, "Bearer")
+			c.Error(core.NewHTTPError(core.StatusUnauthorized, "missing token"))
 			return
 		}
 
 		tokenStr := strings.TrimPrefix(authHeader, "Bearer ")
 		user, err := jwtComp.Validate(tokenStr)
 		if err != nil {
-			c.AbortWithStatusJSON(core.StatusUnauthorized, map[string]interface{}{"error": "invalid token"})
+			c.SetHeader(core.HeaderWWWAuthenticate, `Bearer error="invalid_token"`)
+			c.Error(core.NewHTTPError(core.StatusUnauthorized, "invalid token").Wrap(err))
 			return
 		}
 
@@ -29,3 +31,16 @@ func AuthMiddleware(jwtComp *Jwt) core.Handler {
 		c.Next()
 	}
 }
+
+// RateLimitByUser keys core.RateLimit by the subject of the token
+// (UserInfo.ID); it must run after AuthMiddleware. Anonymous requests fall
+// back to the client IP. For @RateLimit routes set it as Config.RateLimit.Key;
+// their limiter runs after the middleware of the route.
+// Example
+// server.AddGroup("/api", register, jwt.AuthMiddleware(j), core.RateLimit(core.RateLimitOptions{Limit: 100, Window: time.Minute, Key: jwt.RateLimitByUser}))
+func RateLimitByUser(c core.Context) string {
+	if user, ok := c.Get(UserInfoKey).(*UserInfo); ok && user.ID != "" {
+		return "user:" + user.ID
+	}
+	return ""
+}
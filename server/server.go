@@ -5,20 +5,34 @@ import (
 	"github.com/kimxuanhong/go-server/echo"
 	"github.com/kimxuanhong/go-server/fiber"
 	"github.com/kimxuanhong/go-server/gin"
+	"github.com/kimxuanhong/go-server/std"
 	"log"
+	"sort"
 )
 
+// engines maps core.Config.Engine to the adapter constructor.
+var engines = map[string]func(configs ...*core.Config) core.Server{
+	"gin":   gin.NewServer,
+	"fiber": fiber.NewServer,
+	"echo":  echo.NewServer,
+	"std":   std.NewServer,
+}
+
+// Engines returns the names of the registered engines, sorted.
+func Engines() []string {
+	names := make([]string, 0, len(engines))
+	for name := range engines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 func NewServer(configs ...*core.Config) core.Server {
 	cfg := core.GetConfig(configs...)
-	switch cfg.Engine {
-	case "gin":
-		return gin.NewServer(cfg)
-	case "fiber":
-		return fiber.NewServer(cfg)
-	case "echo":
-		return echo.NewServer(cfg)
-	default:
+	newServer, ok := engines[cfg.Engine]
+	if !ok {
 		log.Fatalf("Can not init server with engine = %s", cfg.Engine)
 	}
-	return nil
+	return newServer(cfg)
 }
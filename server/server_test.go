@@ -0,0 +1,19 @@
+package server
+
+import (
+	"github.com/kimxuanhong/go-server/conformance"
+	"github.com/kimxuanhong/go-server/core"
+	"testing"
+)
+
+func TestConformance(t *testing.T) {
+	for _, engine := range Engines() {
+		engine := engine
+		t.Run(engine, func(t *testing.T) {
+			conformance.Run(t, func(cfg *core.Config) core.Server {
+				cfg.Engine = engine
+				return NewServer(cfg)
+			})
+		})
+	}
+}
@@ -0,0 +1,140 @@
+//go:build unix
+
+package server
+
+import (
+	"fmt"
+	"github.com/kimxuanhong/go-server/conformance"
+	"github.com/kimxuanhong/go-server/core"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// TestUpgradeHelper is the server process of TestUpgrade, re-executed by
+// core.Upgrade with the same arguments.
+func TestUpgradeHelper(t *testing.T) {
+	engine := os.Getenv("UPGRADE_HELPER_ENGINE")
+	if engine == "" {
+		t.Skip("only run by TestUpgrade")
+	}
+	s := NewServer(&core.Config{
+		Mode:      "test",
+		Engine:    engine,
+		Listeners: []string{"tcp://127.0.0.1:" + os.Getenv("UPGRADE_HELPER_PORT")},
+	})
+	s.Add(core.MethodGet, "/pid", func(c core.Context) {
+		c.String(core.StatusOK, strconv.Itoa(os.Getpid()))
+	})
+	s.Add(core.MethodGet, "/slow", func(c core.Context) {
+		time.Sleep(500 * time.Millisecond)
+		c.String(core.StatusOK, strconv.Itoa(os.Getpid()))
+	})
+	if err := core.Run(s, core.RunOptions{Upgrade: true, ShutdownTimeout: 5 * time.Second}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUpgrade(t *testing.T) {
+	for _, engine := range Engines() {
+		engine := engine
+		t.Run(engine, func(t *testing.T) {
+			port, err := conformance.FreePort()
+			if err != nil {
+				t.Fatal(err)
+			}
+			base := "http://127.0.0.1:" + port
+			client := &http.Client{Timeout: 5 * time.Second}
+			get := func(path string) (string, error) {
+				res, err := client.Get(base + path)
+				if err != nil {
+					return "", err
+				}
+				defer res.Body.Close()
+				body, err := io.ReadAll(res.Body)
+				if res.StatusCode != http.StatusOK {
+					return "", fmt.Errorf("status %d", res.StatusCode)
+				}
+				return string(body), err
+			}
+			waitPID := func(not string) string {
+				deadline := time.Now().Add(10 * time.Second)
+				for time.Now().Before(deadline) {
+					if pid, err := get("/pid"); err == nil && pid != not {
+						return pid
+					}
+					time.Sleep(50 * time.Millisecond)
+				}
+				t.Fatalf("no answer from a process other than %q", not)
+				return ""
+			}
+
+			cmd := exec.Command(os.Args[0], "-test.run=^TestUpgradeHelper$")
+			cmd.Env = append(os.Environ(), "UPGRADE_HELPER_ENGINE="+engine, "UPGRADE_HELPER_PORT="+port)
+			if err := cmd.Start(); err != nil {
+				t.Fatal(err)
+			}
+			exited := make(chan error, 1)
+			go func() { exited <- cmd.Wait() }()
+			var newPID int
+			t.Cleanup(func() {
+				_ = cmd.Process.Kill()
+				if newPID > 0 {
+					_ = syscall.Kill(newPID, syscall.SIGKILL)
+				}
+			})
+
+			oldPID := waitPID("")
+			if oldPID != strconv.Itoa(cmd.Process.Pid) {
+				t.Fatalf("pid = %s, want %d", oldPID, cmd.Process.Pid)
+			}
+
+			// Request đang chạy trên process cũ phải hoàn tất sau upgrade
+			slow := make(chan string, 1)
+			go func() {
+				body, err := get("/slow")
+				if err != nil {
+					body = err.Error()
+				}
+				slow <- body
+			}()
+			time.Sleep(100 * time.Millisecond)
+			if err := cmd.Process.Signal(syscall.SIGHUP); err != nil {
+				t.Fatal(err)
+			}
+
+			pid := waitPID(oldPID)
+			newPID, _ = strconv.Atoi(pid)
+			if got := <-slow; got != oldPID {
+				t.Fatalf("in-flight request = %q, want it served by %s", got, oldPID)
+			}
+			select {
+			case err := <-exited:
+				if err != nil {
+					t.Fatalf("old process exited with %v", err)
+				}
+			case <-time.After(10 * time.Second):
+				t.Fatal("old process did not exit after the upgrade")
+			}
+			if got, err := get("/pid"); err != nil || got != pid {
+				t.Fatalf("after upgrade pid = %q, %v; want %s", got, err, pid)
+			}
+
+			if err := syscall.Kill(newPID, syscall.SIGTERM); err != nil {
+				t.Fatal(err)
+			}
+			deadline := time.Now().Add(10 * time.Second)
+			for syscall.Kill(newPID, 0) == nil {
+				if time.Now().After(deadline) {
+					t.Fatal("new process did not stop on SIGTERM")
+				}
+				time.Sleep(50 * time.Millisecond)
+			}
+		})
+	}
+}
@@ -0,0 +1,367 @@
+package std
+
+import (
+	"bufio"
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/kimxuanhong/go-server/core"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// stdContext is the reference implementation of core.Context on net/http.
+// Middleware and handler run as one chain driven by index, like gin.
+type stdContext struct {
+	writer   *responseWriter
+	request  *http.Request
+	path     string
+	handlers []core.Handler
+	index    int
+	aborted  bool
+	keys     map[string]interface{}
+}
+
+func newContext(w http.ResponseWriter, r *http.Request, path string, handlers []core.Handler) *stdContext {
+	return &stdContext{
+		writer:   &responseWriter{ResponseWriter: w, status: http.StatusOK},
+		request:  r,
+		path:     path,
+		handlers: handlers,
+		index:    -1,
+	}
+}
+
+func (s *stdContext) Context() context.Context {
+	return s.request.Context()
+}
+
+func (s *stdContext) SetContext(ctx context.Context) {
+	s.request = s.request.WithContext(ctx)
+}
+
+func (s *stdContext) Param(name string) string {
+	return s.request.PathValue(name)
+}
+
+func (s *stdContext) Query(name string) string {
+	return s.request.URL.Query().Get(name)
+}
+
+func (s *stdContext) Header(name string) string {
+	return s.request.Header.Get(name)
+}
+
+// Bind decodes the request body with the codec of its Content-Type, or
+// binds forms by their form tags (core.BindForm), then validates obj with
+// core.Validate. A body without Content-Type is decoded as JSON.
+func (s *stdContext) Bind(obj interface{}) error {
+	if handled, err := core.BindBody(s, obj); handled {
+		return err
+	}
+	contentType := s.request.Header.Get(core.HeaderContentType)
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case mediaType == core.MIMEApplicationForm:
+		core.LimitRequestBody(s, s.writer, s.request)
+		if err := s.request.ParseForm(); err != nil {
+			return err
+		}
+		if err := core.BindForm(obj, s.request.Form); err != nil {
+			return err
+		}
+	case mediaType == core.MIMEMultipartForm:
+		form, err := core.ParseMultipartForm(s)
+		if err != nil {
+			return err
+		}
+		if err := core.BindForm(obj, form.Value); err != nil {
+			return err
+		}
+	case contentType != "":
+		return fmt.Errorf("unsupported content type %q", contentType)
+	default:
+		// Không có Content-Type (ví dụ GET): bind query như gin, rồi body JSON nếu có
+		if err := s.request.ParseForm(); err != nil {
+			return err
+		}
+		if err := core.BindForm(obj, s.request.Form); err != nil {
+			return err
+		}
+		if err := json.NewDecoder(s.Body()).Decode(obj); err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+	}
+	return core.Validate(obj)
+}
+
+func (s *stdContext) Body() io.Reader {
+	core.LimitRequestBody(s, s.writer, s.request)
+	return s.request.Body
+}
+
+func (s *stdContext) BodyBytes() ([]byte, error) {
+	return io.ReadAll(s.Body())
+}
+
+func (s *stdContext) FormValue(name string) string {
+	return core.FormValue(s, name)
+}
+
+func (s *stdContext) FormFile(name string) (*multipart.FileHeader, error) {
+	return core.FormFile(s, name)
+}
+
+func (s *stdContext) MultipartForm() (*multipart.Form, error) {
+	return core.ParseMultipartForm(s)
+}
+
+func (s *stdContext) SaveUploadedFile(file *multipart.FileHeader, dst string) error {
+	return core.SaveUploadedFile(s, file, dst)
+}
+
+func (s *stdContext) JSON(code int, obj interface{}) {
+	body, err := json.Marshal(obj)
+	if err != nil {
+		log.Printf("JSON() error: %v", err)
+		http.Error(s.writer, "Failed to send JSON response", http.StatusInternalServerError)
+		return
+	}
+	// Giữ content type JSON đã set trước, ví dụ application/problem+json
+	if !strings.Contains(s.writer.Header().Get(core.HeaderContentType), "json") {
+		s.writer.Header().Set(core.HeaderContentType, core.MIMEApplicationJSONCharsetUTF8)
+	}
+	s.writer.WriteHeader(code)
+	_, _ = s.writer.Write(body)
+}
+
+func (s *stdContext) Abort() {
+	s.aborted = true
+}
+
+func (s *stdContext) AbortWithStatusJSON(code int, obj interface{}) {
+	s.Abort()
+	s.JSON(code, obj)
+}
+
+func (s *stdContext) String(code int, msg string) error {
+	s.writer.Header().Set(core.HeaderContentType, core.MIMETextPlainCharsetUTF8)
+	s.writer.WriteHeader(code)
+	_, err := s.writer.Write([]byte(msg))
+	return err
+}
+
+// Status sets the response status; it is written when the chain returns
+// without a body.
+func (s *stdContext) Status(code int) core.Context {
+	s.writer.SetStatus(code)
+	return s
+}
+
+func (s *stdContext) Written() bool {
+	return s.writer.written
+}
+
+func (s *stdContext) SetHeader(key, value string) {
+	s.writer.Header().Set(key, value)
+}
+
+func (s *stdContext) AddHeader(key, value string) {
+	s.writer.Header().Add(key, value)
+}
+
+func (s *stdContext) XML(code int, obj interface{}) error {
+	return core.XML(s, code, obj)
+}
+
+func (s *stdContext) HTML(code int, name string, data interface{}) error {
+	return core.HTML(s, code, name, data)
+}
+
+func (s *stdContext) Blob(code int, contentType string, data []byte) error {
+	if contentType != "" {
+		s.writer.Header().Set(core.HeaderContentType, contentType)
+	}
+	s.writer.WriteHeader(code)
+	_, err := s.writer.Write(data)
+	return err
+}
+
+func (s *stdContext) Stream(code int, contentType string, r io.Reader) error {
+	if contentType != "" {
+		s.writer.Header().Set(core.HeaderContentType, contentType)
+	}
+	s.writer.WriteHeader(code)
+	return core.CopyFlush(s.writer, r)
+}
+
+func (s *stdContext) SSE(fn func(core.EventStream) error) error {
+	return core.SSE(s, s.writer, fn)
+}
+
+func (s *stdContext) Request() (*http.Request, error) {
+	return s.request, nil
+}
+
+func (s *stdContext) Hijack(fn func(net.Conn, *bufio.Reader)) error {
+	return core.HijackHTTP(s.writer, fn)
+}
+
+// CompressResponse puts a core.CompressWriter behind the status tracking of
+// the writer until finish.
+func (s *stdContext) CompressResponse(z *core.Compression) func() {
+	orig := s.writer.ResponseWriter
+	cw := core.NewCompressWriter(orig, z)
+	s.writer.ResponseWriter = cw
+	return func() {
+		_ = cw.Close()
+		s.writer.ResponseWriter = orig
+	}
+}
+
+func (s *stdContext) File(path string) error {
+	return core.ServeFile(s.writer, s.request, path)
+}
+
+func (s *stdContext) Attachment(path, filename string) error {
+	return core.Attachment(s, path, filename)
+}
+
+func (s *stdContext) Redirect(code int, location string) error {
+	return core.Redirect(s, code, location)
+}
+
+func (s *stdContext) NoContent(code int) error {
+	return s.Blob(code, "", nil)
+}
+
+func (s *stdContext) Negotiate(code int, offers map[string]interface{}) error {
+	return core.Negotiate(s, code, offers)
+}
+
+func (s *stdContext) Error(err error) {
+	core.HandleError(s, err)
+}
+
+// Method returns the HTTP method of the request.
+func (s *stdContext) Method() string {
+	return s.request.Method
+}
+
+func (s *stdContext) ClientCertificate() *x509.Certificate {
+	return core.VerifiedClientCertificate(s.request.TLS)
+}
+
+func (s *stdContext) Protocol() string {
+	return s.request.Proto
+}
+
+func (s *stdContext) RemoteAddr() string {
+	return s.request.RemoteAddr
+}
+
+// Path returns the matched route pattern.
+func (s *stdContext) Path() string {
+	return s.path
+}
+
+// Next runs the remaining handlers of the chain until one aborts.
+func (s *stdContext) Next() {
+	s.index++
+	for s.index < len(s.handlers) {
+		if s.aborted {
+			return
+		}
+		s.handlers[s.index](s)
+		s.index++
+	}
+}
+
+// Raw returns the underlying http.ResponseWriter and *http.Request.
+func (s *stdContext) Raw() interface{} {
+	return &Raw{Writer: s.writer, Request: s.request}
+}
+
+func (s *stdContext) Set(key string, value interface{}) {
+	if s.keys == nil {
+		s.keys = make(map[string]interface{})
+	}
+	s.keys[key] = value
+	ctx := context.WithValue(s.request.Context(), key, value)
+	s.request = s.request.WithContext(ctx)
+}
+
+func (s *stdContext) Get(key string) interface{} {
+	if v, exists := s.keys[key]; exists {
+		return v
+	}
+	return s.request.Context().Value(key)
+}
+
+func (s *stdContext) GetString(key string) string {
+	if str, ok := s.Get(key).(string); ok {
+		return str
+	}
+	return ""
+}
+
+func (s *stdContext) GetInt(key string) int {
+	if i, ok := s.Get(key).(int); ok {
+		return i
+	}
+	return 0
+}
+
+// Raw is what stdContext.Raw returns.
+type Raw struct {
+	Writer  http.ResponseWriter
+	Request *http.Request
+}
+
+// responseWriter delays WriteHeader so Status() can be changed until the
+// first write, and remembers whether the response was written.
+type responseWriter struct {
+	http.ResponseWriter
+	status  int
+	written bool
+}
+
+func (w *responseWriter) SetStatus(code int) {
+	if !w.written {
+		w.status = code
+	}
+}
+
+func (w *responseWriter) WriteHeader(code int) {
+	if w.written {
+		return
+	}
+	w.status = code
+	w.written = true
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	if !w.written {
+		w.WriteHeader(w.status)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// WriteHeaderNow writes the pending status if nothing was written yet.
+func (w *responseWriter) WriteHeaderNow() {
+	if !w.written {
+		w.WriteHeader(w.status)
+	}
+}
+
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,411 @@
+package std
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/kimxuanhong/go-server/core"
+	"html/template"
+	"log"
+	"net"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+)
+
+// Server implements core.Server with only the standard library: routes are
+// registered on an http.ServeMux using its method and wildcard patterns.
+type Server struct {
+	*core.DynamicRouter
+	*core.ProviderRouter
+	*core.HealthChecks
+	*core.Lifecycle
+	mux          *http.ServeMux
+	config       *core.Config
+	middleware   []core.Handler
+	httpServer   *http.Server
+	mountOnce    sync.Once
+	errorHandler core.ErrorHandler
+	htmlTemplate *template.Template
+	adminAuth    []core.Handler
+	admin        *Server
+	adminOnce    sync.Once
+	routes       []core.RouteInfo
+	cors         core.Handler
+	// corsErr là lỗi của Config.CORS, Start trả về thay vì panic trong NewServer
+	corsErr error
+}
+
+func NewServer(configs ...*core.Config) core.Server {
+	cfg := core.GetConfig(configs...)
+	s := &Server{
+		DynamicRouter:  &core.DynamicRouter{DevMode: cfg.Mode == "debug", RateLimit: cfg.RateLimit},
+		ProviderRouter: &core.ProviderRouter{},
+		HealthChecks:   &core.HealthChecks{},
+		Lifecycle:      &core.Lifecycle{},
+		mux:            http.NewServeMux(),
+		config:         cfg,
+		// Tạo sẵn để Shutdown gọi trước khi Start listen vẫn dừng được server
+		httpServer: &http.Server{Addr: cfg.GetAddr()},
+	}
+	if cfg.CORS.Enabled() {
+		if s.cors, s.corsErr = core.NewCORS(cfg.CORS); s.corsErr != nil {
+			log.Printf("CORS disabled: %v", s.corsErr)
+		}
+	}
+	return s
+}
+
+func (s *Server) Start() error {
+	listeners, err := s.config.Listen()
+	if err != nil {
+		return err
+	}
+	return s.serve(listeners)
+}
+
+// Serve serves on ln instead of the configured listeners, e.g. a socket
+// inherited from a parent process.
+func (s *Server) Serve(ln net.Listener) error {
+	return s.serve([]net.Listener{ln})
+}
+
+func (s *Server) serve(listeners []net.Listener) error {
+	if err := s.prepare(); err != nil {
+		core.CloseListeners(listeners)
+		return err
+	}
+	listeners = core.LimitListeners(listeners, s.config.Limits.MaxConnections)
+	scheme, serve := "http", s.httpServer.Serve
+	if s.config.TLS.Enabled() {
+		// Certificate lấy từ TLSConfig.GetCertificate nên không truyền file
+		scheme, serve = "https", func(ln net.Listener) error {
+			return s.httpServer.ServeTLS(ln, "", "")
+		}
+	}
+	return core.ServeListeners(scheme, listeners, serve)
+}
+
+// prepare runs the start hooks, mounts the routes, applies TLS and the
+// protocols and starts the admin listener.
+func (s *Server) prepare() error {
+	if s.corsErr != nil {
+		return s.corsErr
+	}
+	protocols := s.config.GetProtocols()
+	if err := core.CheckProtocols("std", protocols, core.NetHTTPProtocols...); err != nil {
+		return err
+	}
+	if err := s.RunStartHooks(context.Background()); err != nil {
+		return err
+	}
+	s.httpServer.Handler = s.Handler()
+	core.ApplyLimits(s.httpServer, s.config)
+
+	if s.config.TLS.Enabled() {
+		tlsConfig, err := core.NewTLSConfig(s.config.TLS)
+		if err != nil {
+			return err
+		}
+		s.httpServer.TLSConfig = tlsConfig
+	}
+	if err := core.ConfigureProtocols(s.httpServer, protocols); err != nil {
+		return err
+	}
+
+	if s.config.Admin.Enabled() {
+		s.AdminHandler()
+		go func() {
+			if err := s.admin.Start(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Printf("Admin server error: %v", err)
+			}
+		}()
+	}
+	return nil
+}
+
+// Handler loads the registered routes once and returns the mux, with its own
+// 404/405 responses rendered by Context.Error.
+func (s *Server) Handler() http.Handler {
+	s.mountOnce.Do(func() {
+		//add api from @Api tag
+		s.LoadRouter()
+		s.Routes(s.DynamicRouter.Routes)
+
+		//add api from provider route
+		s.Routes(s.ProviderRouter.Routes)
+	})
+	return http.HandlerFunc(s.serveHTTP)
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	// CORS chạy trước mux để preflight của mọi path được trả lời
+	if s.cors != nil {
+		c := s.newContext(w, r, "", []core.Handler{s.cors})
+		c.Next()
+		if c.aborted {
+			c.writer.WriteHeaderNow()
+			return
+		}
+	}
+	uw := &unmatchedWriter{ResponseWriter: w}
+	s.mux.ServeHTTP(uw, r)
+	if uw.status != 0 {
+		c := s.newContext(w, r, "", nil)
+		c.Error(core.NewHTTPError(uw.status, ""))
+		c.writer.WriteHeaderNow()
+	}
+}
+
+// Shutdown drains the in-flight requests within ctx, stops the admin
+// listener and runs the OnShutdown hooks.
+func (s *Server) Shutdown(ctx context.Context) error {
+	log.Println("Shutting down server...")
+	err := s.httpServer.Shutdown(ctx)
+	if s.admin != nil {
+		err = errors.Join(err, s.admin.Shutdown(ctx))
+	}
+	return errors.Join(err, s.RunShutdownHooks(ctx))
+}
+
+// Use registers global middleware for the routes added afterwards, like
+// gin's RouterGroup.Use.
+func (s *Server) Use(middleware ...core.Handler) {
+	s.middleware = append(s.middleware, middleware...)
+}
+
+func (s *Server) AddGroup(relativePath string, register func(rg core.RouterGroup), middleware ...core.Handler) {
+	register(&RouterGroup{
+		server:     s,
+		basePath:   joinPath(s.config.RootPath, relativePath),
+		middleware: chain(s.middleware, middleware),
+	})
+}
+
+func (s *Server) Add(method, relativePath string, handler core.Handler, middleware ...core.Handler) {
+	s.handle(method, joinPath(s.config.RootPath, relativePath), chain(s.middleware, middleware, []core.Handler{handler}))
+}
+
+func (s *Server) Routes(routes []core.RouteConfig) {
+	for _, r := range routes {
+		r = core.WebSocketRoute(r, s.OnShutdown)
+		s.Add(r.Method, r.Path, r.Handler, r.Middleware...)
+	}
+}
+
+func (s *Server) WebSocket(relativePath string, handler core.WSHandler, opts ...core.WSOptions) {
+	route := core.RouteConfig{Method: core.MethodWS, Path: relativePath, WebSocket: handler}
+	if len(opts) > 0 {
+		route.WSOptions = opts[0]
+	}
+	s.Routes([]core.RouteConfig{route})
+}
+
+func (s *Server) Static(relativePath, root string) {
+	prefix := strings.TrimRight(relativePath, "/")
+	s.mux.Handle(core.MethodGet+" "+prefix+"/", http.StripPrefix(prefix, http.FileServer(http.Dir(root))))
+	s.routes = append(s.routes, core.RouteInfo{Method: core.MethodGet, Path: prefix + "/*filepath"})
+}
+
+func (s *Server) HealthCheck() {
+	if s.config.Admin.DisablePublic {
+		return
+	}
+	admin := s.adminEndpoints()
+	s.rootRoutes(admin.ProbeRoutes())
+	if !s.config.Admin.Enabled() {
+		s.rootRoutes(admin.PublicControlRoutes())
+	}
+}
+
+// UseAdmin adds auth middleware to the admin control routes. Call it before
+// HealthCheck and Start.
+func (s *Server) UseAdmin(middleware ...core.Handler) {
+	s.adminAuth = append(s.adminAuth, middleware...)
+}
+
+// AdminHandler builds the admin listener once, as a separate mux
+// serving the probes and control routes.
+func (s *Server) AdminHandler() http.Handler {
+	if !s.config.Admin.Enabled() {
+		return nil
+	}
+	s.adminOnce.Do(func() {
+		s.admin = NewServer(&core.Config{
+			Host: s.config.Admin.Host,
+			Port: s.config.Admin.Port,
+			Mode: s.config.Mode,
+		}).(*Server)
+		s.admin.errorHandler = s.errorHandler
+		admin := s.adminEndpoints()
+		s.admin.rootRoutes(admin.ProbeRoutes())
+		s.admin.rootRoutes(admin.ControlRoutes())
+	})
+	return s.admin.Handler()
+}
+
+func (s *Server) adminEndpoints() *core.AdminEndpoints {
+	return &core.AdminEndpoints{
+		Health:      s.HealthChecks,
+		Routes:      s.routeInfos,
+		Terminate:   func() { _ = s.Shutdown(context.Background()) },
+		Auth:        core.AdminAuth(s.config.Admin, s.adminAuth...),
+		Credentials: s.config.Admin.Token != "" || len(s.adminAuth) > 0,
+	}
+}
+
+// rootRoutes registers routes on the mux, outside the root path.
+func (s *Server) rootRoutes(routes []core.RouteConfig) {
+	for _, r := range routes {
+		s.handle(r.Method, r.Path, chain(r.Middleware, []core.Handler{r.Handler}))
+	}
+}
+
+func (s *Server) routeInfos() []core.RouteInfo {
+	return append([]core.RouteInfo(nil), s.routes...)
+}
+
+func (s *Server) OpenAPI(info core.OpenAPIInfo) {
+	s.handle(core.MethodGet, core.OpenAPIPath, []core.Handler{s.OpenAPIHandler(info, s.config.RootPath)})
+}
+
+// handle registers the chain for method and the gin-style route pattern.
+func (s *Server) handle(method, pattern string, handlers []core.Handler) {
+	debug := s.config.Mode == "debug"
+	s.routes = append(s.routes, core.RouteInfo{Method: method, Path: pattern})
+	s.mux.HandleFunc(method+" "+muxPattern(pattern), func(w http.ResponseWriter, r *http.Request) {
+		if uw, ok := w.(*unmatchedWriter); ok {
+			uw.matched = true
+			w = uw.ResponseWriter
+		}
+		if debug {
+			log.Printf("Request: %s %s", r.Method, r.URL.Path)
+		}
+		c := s.newContext(w, r, pattern, handlers)
+		defer func() {
+			core.RemoveMultipartForm(c.keys[core.MultipartFormKey])
+		}()
+		defer func() {
+			if err := recover(); err != nil {
+				if !c.writer.written {
+					c.Error(fmt.Errorf("panic: %v", err))
+				} else {
+					log.Printf("panic recovered: %v", err)
+				}
+			}
+		}()
+		c.Next()
+		c.writer.WriteHeaderNow()
+	})
+}
+
+func (s *Server) SetErrorHandler(h core.ErrorHandler) {
+	s.errorHandler = h
+}
+
+func (s *Server) SetHTMLTemplate(t *template.Template) {
+	s.htmlTemplate = t
+}
+
+// newContext creates the request context with the server's ErrorHandler,
+// its HTML templates and the global body and multipart limits.
+func (s *Server) newContext(w http.ResponseWriter, r *http.Request, pattern string, handlers []core.Handler) *stdContext {
+	c := newContext(w, r, pattern, handlers)
+	c.keys = map[string]interface{}{core.MultipartKey: s.config.Multipart}
+	if s.errorHandler != nil {
+		c.keys[core.ErrorHandlerKey] = s.errorHandler
+	}
+	if s.htmlTemplate != nil {
+		c.keys[core.HTMLTemplateKey] = s.htmlTemplate
+	}
+	if n := s.config.Limits.MaxBodyBytes; n > 0 {
+		c.keys[core.BodyLimitKey] = n
+	}
+	return c
+}
+
+// unmatchedWriter swallows the 404/405 responses the mux writes for
+// requests that match no route, so serveHTTP can render them instead.
+type unmatchedWriter struct {
+	http.ResponseWriter
+	matched bool
+	status  int
+}
+
+func (w *unmatchedWriter) WriteHeader(code int) {
+	if !w.matched && (code == http.StatusNotFound || code == http.StatusMethodNotAllowed) {
+		w.status = code
+		return
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *unmatchedWriter) Write(b []byte) (int, error) {
+	if w.status != 0 {
+		return len(b), nil
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *unmatchedWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+type RouterGroup struct {
+	server     *Server
+	basePath   string
+	middleware []core.Handler
+}
+
+func (g *RouterGroup) Add(method, path string, handler core.Handler, middleware ...core.Handler) {
+	g.server.handle(method, joinPath(g.basePath, path), chain(g.middleware, middleware, []core.Handler{handler}))
+}
+
+// chain copies the handler lists so groups and routes never share a backing array.
+func chain(lists ...[]core.Handler) []core.Handler {
+	var all []core.Handler
+	for _, l := range lists {
+		all = append(all, l...)
+	}
+	return all
+}
+
+// joinPath joins route paths the way gin does, keeping a trailing slash of relativePath.
+func joinPath(basePath, relativePath string) string {
+	if relativePath == "" {
+		if basePath == "" {
+			return "/"
+		}
+		return basePath
+	}
+	joined := path.Join("/", basePath, relativePath)
+	if strings.HasSuffix(relativePath, "/") && !strings.HasSuffix(joined, "/") {
+		return joined + "/"
+	}
+	return joined
+}
+
+// muxPattern converts a gin-style pattern (/users/:id, /files/*path) to an
+// http.ServeMux pattern (/users/{id}, /files/{path...}).
+func muxPattern(pattern string) string {
+	segments := strings.Split(pattern, "/")
+	for i, seg := range segments {
+		switch {
+		case strings.HasPrefix(seg, ":"):
+			segments[i] = "{" + seg[1:] + "}"
+		case strings.HasPrefix(seg, "*"):
+			name := seg[1:]
+			if name == "" {
+				name = "path"
+			}
+			segments[i] = "{" + name + "...}"
+		}
+	}
+	result := strings.Join(segments, "/")
+	// ServeMux coi pattern kết thúc bằng / là cả cây con; {$} giữ đúng một path
+	if strings.HasSuffix(result, "/") {
+		result += "{$}"
+	}
+	return result
+}